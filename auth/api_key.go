@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	kAPI_KEY_PREFIX      = "tvs_"
+	kAPI_KEY_SIZE        = 32
+	kAPI_KEY_SHOWN_CHARS = 8
+)
+
+// NewApiKey returns a random key, the part of it that is safe to display and
+// the hash to store. The key itself is never stored.
+func NewApiKey() (key string, prefix string, hash string, err error) {
+	random := make([]byte, kAPI_KEY_SIZE)
+	if _, err = rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+
+	key = kAPI_KEY_PREFIX + hex.EncodeToString(random)
+	return key, key[:len(kAPI_KEY_PREFIX)+kAPI_KEY_SHOWN_CHARS], HashApiKey(key), nil
+}
+
+// HashApiKey uses a plain SHA-256, keys are random enough that a slow hash
+// like the password one adds nothing but latency to every request.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsApiKey tells an API key from a JWT sent in the same header.
+func IsApiKey(credential string) bool {
+	return strings.HasPrefix(credential, kAPI_KEY_PREFIX)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	kDEFAULT_TOKEN_TTL = 24 * time.Hour
+	kMIN_PASSWORD_SIZE = 8
+)
+
+var (
+	ErrInvalidToken    = errors.New("token invalid")
+	ErrExpiredToken    = errors.New("token expired")
+	ErrInvalidPassword = errors.New("password invalid, must have at least " + strconv.Itoa(kMIN_PASSWORD_SIZE) + " characters")
+)
+
+// tokenHeader is the only JWT header issued and accepted.
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func HashPassword(password string) (string, error) {
+	if len(password) < kMIN_PASSWORD_SIZE {
+		return "", ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Signer issues and verifies HS256 JWTs whose subject is a user id.
+type Signer struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	if ttl <= 0 {
+		ttl = kDEFAULT_TOKEN_TTL
+	}
+
+	return &Signer{Secret: secret, TTL: ttl}
+}
+
+// NewSignerFromEnv builds a signer from AUTH_SECRET and AUTH_TOKEN_TTL (e.g.
+// "12h"). Without AUTH_SECRET a random secret is used, so tokens stop working
+// when the server restarts.
+func NewSignerFromEnv() *Signer {
+	secret := []byte(os.Getenv("AUTH_SECRET"))
+	if len(secret) == 0 {
+		log.Println("AUTH_SECRET not set, using a random secret")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Panic("Erro ao gerar AUTH_SECRET")
+		}
+	}
+
+	var ttl time.Duration
+	if value := os.Getenv("AUTH_TOKEN_TTL"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil {
+			log.Printf("AUTH_TOKEN_TTL [%s] invalid, using [%s]", value, kDEFAULT_TOKEN_TTL)
+		}
+	}
+
+	return NewSigner(secret, ttl)
+}
+
+func (s *Signer) Sign(userId int) (token string, expiresAt time.Time, err error) {
+	now := time.Now()
+	expiresAt = now.Add(s.TTL)
+
+	payload, err := json.Marshal(claims{
+		Subject:   strconv.Itoa(userId),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", expiresAt, err
+	}
+
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.signature(unsigned), expiresAt, nil
+}
+
+// Verify checks the signature and expiration of token and returns its user id.
+func (s *Signer) Verify(token string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return 0, ErrInvalidToken
+	}
+
+	unsigned := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(unsigned))) {
+		return 0, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= c.ExpiresAt {
+		return 0, ErrExpiredToken
+	}
+
+	userId, err := strconv.Atoi(c.Subject)
+	if err != nil || userId <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return userId, nil
+}
+
+func (s *Signer) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	kCALENDAR_TOKEN_PREFIX = "tvc_"
+	kCALENDAR_TOKEN_SIZE   = 24
+)
+
+// NewCalendarToken returns a random token for the calendar feed URL and the
+// hash to store. It only gives read access to the feed of its user, so it can
+// travel in a URL where an API key should not.
+func NewCalendarToken() (token string, hash string, err error) {
+	random := make([]byte, kCALENDAR_TOKEN_SIZE)
+	if _, err = rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	token = kCALENDAR_TOKEN_PREFIX + hex.EncodeToString(random)
+	return token, HashApiKey(token), nil
+}
@@ -0,0 +1,380 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+)
+
+// Version is bumped whenever the document layout changes. Version 2 added
+// the seasons, the documents of version 1 are still restored.
+const Version = 2
+
+const (
+	ModeReplace = "replace"
+	ModeMerge   = "merge"
+)
+
+var ErrInvalidMode = errors.New("mode invalid, must be " + ModeReplace + " or " + ModeMerge)
+
+// Document is a full snapshot of the shows, their seasons and episodes. Episodes carry the
+// watch state of the user that made the backup, and a restore gives it to
+// the user running it. The watch state of the other users is not in it.
+type Document struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	TvShows   []models.TvShow  `json:"tv_shows"`
+	Seasons   []models.Season  `json:"seasons"`
+	Episodes  []models.Episode `json:"episodes"`
+}
+
+// ValidationError reports a document that cannot be restored. Nothing is
+// written when it is returned.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, a ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, a...)}
+}
+
+type Counts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted,omitempty"`
+}
+
+// Report tells what a restore wrote.
+type Report struct {
+	Mode     string `json:"mode"`
+	TvShows  Counts `json:"tv_shows"`
+	Seasons  Counts `json:"seasons"`
+	Episodes Counts `json:"episodes"`
+}
+
+// Build takes a snapshot of every show, season and episode.
+func Build(repo repository.Repository) (Document, error) {
+	doc := Document{Version: Version, CreatedAt: time.Now()}
+	var err error
+
+	if doc.TvShows, err = repo.TvShows().FindAll(); err != nil {
+		return doc, err
+	}
+	if doc.Seasons, err = repo.Seasons().FindAll(); err != nil {
+		return doc, err
+	}
+	if doc.Episodes, err = repo.Episodes().FindAll(); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+// Write encodes doc to w one row at a time. The rows are all in memory
+// already, Build loads them, but their JSON is never held all at once.
+func Write(w io.Writer, doc Document) error {
+	createdAt, err := json.Marshal(doc.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, `{"version":%d,"created_at":%s,"tv_shows":`, doc.Version, createdAt); err != nil {
+		return err
+	}
+	if err := writeArray(w, doc.TvShows); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"seasons":`); err != nil {
+		return err
+	}
+	if err := writeArray(w, doc.Seasons); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"episodes":`); err != nil {
+		return err
+	}
+	if err := writeArray(w, doc.Episodes); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+func writeArray[T any](w io.Writer, rows []T) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for index, row := range rows {
+		if index > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// Restore loads doc in one transaction. ModeMerge updates the shows matching
+// on TmdbId and the episodes matching on (TmdbId, Season, Episode), and
+// creates the others. ModeReplace does the same after deleting the shows and
+// episodes missing from doc, with every watch state of those episodes. The
+// episodes kept keep their ids, so the other users keep their watch state.
+// Seasons match on (TmdbId, Number), ModeReplace deletes them all first.
+func Restore(repo repository.Repository, doc Document, mode string) (Report, error) {
+	report := Report{Mode: mode}
+
+	if mode != ModeReplace && mode != ModeMerge {
+		return report, ErrInvalidMode
+	}
+	if doc.Version < 1 || doc.Version > Version {
+		return report, invalid("backup version %d not supported, must be 1 to %d", doc.Version, Version)
+	}
+	if err := validate(&doc); err != nil {
+		return report, err
+	}
+
+	err := repo.Transaction(func(tx repository.Repository) error {
+		if mode == ModeReplace {
+			if err := deleteMissing(tx, doc, &report); err != nil {
+				return err
+			}
+			if err := tx.Seasons().Truncate(false); err != nil {
+				return err
+			}
+		}
+
+		tvShows, err := mergeTvShows(tx, doc.TvShows, &report.TvShows)
+		if err != nil {
+			return err
+		}
+
+		for index, season := range doc.Seasons {
+			if _, ok := tvShows[season.TmdbId]; !ok {
+				return invalid("seasons[%d]: TvShow (TMDB ID %d) not found", index, season.TmdbId)
+			}
+		}
+		for index, episode := range doc.Episodes {
+			if _, ok := tvShows[episode.TmdbId]; !ok {
+				return invalid("episodes[%d]: TvShow (TMDB ID %d) not found", index, episode.TmdbId)
+			}
+		}
+
+		if err := mergeSeasons(tx, doc.Seasons, &report.Seasons); err != nil {
+			return err
+		}
+
+		return mergeEpisodes(tx, doc.Episodes, &report.Episodes)
+	})
+
+	return report, err
+}
+
+func validate(doc *Document) error {
+	tmdbIds := make(map[int]bool)
+	for index := range doc.TvShows {
+		tvShow := &doc.TvShows[index]
+		if err := models.ValidTvShow(tvShow); err != nil {
+			return invalid("tv_shows[%d]: %s", index, err.Error())
+		}
+		if tmdbIds[tvShow.TmdbId] {
+			return invalid("tv_shows[%d]: TMDB ID %d repeated", index, tvShow.TmdbId)
+		}
+		tmdbIds[tvShow.TmdbId] = true
+	}
+
+	seasonKeys := make(map[string]bool)
+	for index := range doc.Seasons {
+		season := &doc.Seasons[index]
+		if err := models.ValidSeason(season); err != nil {
+			return invalid("seasons[%d]: %s", index, err.Error())
+		}
+		key := seasonKey(*season)
+		if seasonKeys[key] {
+			return invalid("seasons[%d]: season %s repeated", index, key)
+		}
+		seasonKeys[key] = true
+	}
+
+	keys := make(map[string]bool)
+	for index := range doc.Episodes {
+		episode := &doc.Episodes[index]
+		if err := models.ValidEpisode(episode); err != nil {
+			return invalid("episodes[%d]: %s", index, err.Error())
+		}
+		key := episodeKey(*episode)
+		if keys[key] {
+			return invalid("episodes[%d]: episode %s repeated", index, key)
+		}
+		keys[key] = true
+	}
+	return nil
+}
+
+// deleteMissing deletes the episodes and shows stored but missing from doc.
+func deleteMissing(tx repository.Repository, doc Document, report *Report) error {
+	keys := make(map[string]bool, len(doc.Episodes))
+	for _, episode := range doc.Episodes {
+		keys[episodeKey(episode)] = true
+	}
+	episodes, err := tx.Episodes().FindAll()
+	if err != nil {
+		return err
+	}
+	for _, episode := range episodes {
+		if keys[episodeKey(episode)] {
+			continue
+		}
+		if err := tx.Episodes().Delete(episode.Id); err != nil {
+			return err
+		}
+		report.Episodes.Deleted++
+	}
+
+	tmdbIds := make(map[int]bool, len(doc.TvShows))
+	for _, tvShow := range doc.TvShows {
+		tmdbIds[tvShow.TmdbId] = true
+	}
+	tvShows, err := tx.TvShows().FindAll()
+	if err != nil {
+		return err
+	}
+	for _, tvShow := range tvShows {
+		if tmdbIds[tvShow.TmdbId] {
+			continue
+		}
+		if err := tx.TvShows().Delete(tvShow.Id); err != nil {
+			return err
+		}
+		report.TvShows.Deleted++
+	}
+	return nil
+}
+
+// mergeTvShows writes the shows and returns every show now stored, keyed by
+// tmdb id.
+func mergeTvShows(tx repository.Repository, tvShows []models.TvShow, counts *Counts) (map[int]models.TvShow, error) {
+	existing, err := tx.TvShows().FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[int]models.TvShow, len(existing))
+	for _, tvShow := range existing {
+		stored[tvShow.TmdbId] = tvShow
+	}
+
+	var toCreate, toSave []models.TvShow
+	for _, tvShow := range tvShows {
+		// the unwatched fields are computed per request, never stored
+		tvShow.UnwatchedSeason, tvShow.UnwatchedEpisode, tvShow.UnwatchedCount = 0, 0, 0
+
+		if current, ok := stored[tvShow.TmdbId]; ok {
+			tvShow.Id = current.Id
+			toSave = append(toSave, tvShow)
+		} else {
+			tvShow.Id = 0
+			toCreate = append(toCreate, tvShow)
+		}
+	}
+
+	if err := tx.TvShows().CreateMany(toCreate); err != nil {
+		return nil, err
+	}
+	for index := range toSave {
+		if err := tx.TvShows().Save(&toSave[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tvShow := range append(toCreate, toSave...) {
+		stored[tvShow.TmdbId] = tvShow
+	}
+	counts.Created, counts.Updated = len(toCreate), len(toSave)
+	return stored, nil
+}
+
+func mergeSeasons(tx repository.Repository, seasons []models.Season, counts *Counts) error {
+	existing, err := tx.Seasons().FindAll()
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]models.Season, len(existing))
+	for _, season := range existing {
+		stored[seasonKey(season)] = season
+	}
+
+	for _, season := range seasons {
+		if current, ok := stored[seasonKey(season)]; ok {
+			season.Id = current.Id
+			if err := tx.Seasons().Save(&season); err != nil {
+				return err
+			}
+			counts.Updated++
+		} else {
+			season.Id = 0
+			if err := tx.Seasons().Create(&season); err != nil {
+				return err
+			}
+			counts.Created++
+		}
+	}
+	return nil
+}
+
+func mergeEpisodes(tx repository.Repository, episodes []models.Episode, counts *Counts) error {
+	existing, err := tx.Episodes().FindAll()
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]models.Episode, len(existing))
+	for _, episode := range existing {
+		stored[episodeKey(episode)] = episode
+	}
+
+	var toCreate, toSave []models.Episode
+	for _, episode := range episodes {
+		if current, ok := stored[episodeKey(episode)]; ok {
+			episode.Id = current.Id
+			toSave = append(toSave, episode)
+		} else {
+			episode.Id = 0
+			toCreate = append(toCreate, episode)
+		}
+	}
+
+	if err := tx.Episodes().CreateMany(toCreate); err != nil {
+		return err
+	}
+	if err := tx.Episodes().SaveMany(toSave); err != nil {
+		return err
+	}
+
+	counts.Created, counts.Updated = len(toCreate), len(toSave)
+	return nil
+}
+
+func seasonKey(season models.Season) string {
+	return fmt.Sprintf("%d/%d", season.TmdbId, season.Number)
+}
+
+func episodeKey(episode models.Episode) string {
+	return fmt.Sprintf("%d/%dx%d", episode.TmdbId, episode.Season, episode.Episode)
+}
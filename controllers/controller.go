@@ -3,21 +3,94 @@ package controllers
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/feealc/tvshows-backend-go/database"
 	"github.com/feealc/tvshows-backend-go/generic"
 	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE = "tmdb_id, season, episode"
-	kERROR_MESSAGE_ID                       = "id invalid"
-	kERROR_MESSAGE_TMDBID                   = "tmdbId invalid"
-	kERROR_MESSAGE_SEASON                   = "season invalid"
+	kCONTEXT_KEY_REPOSITORY    = "repository"
+	kCONTEXT_KEY_LEGACY_DATES  = "legacyDates"
+	kERROR_MESSAGE_ID          = "id invalid"
+	kERROR_MESSAGE_TMDBID      = "tmdbId invalid"
+	kERROR_MESSAGE_SEASON      = "season invalid"
+	kERROR_MESSAGE_GROUP       = "group invalid"
+	kERROR_MESSAGE_EPISODE     = "episode invalid"
+	kERROR_MESSAGE_KEY_URL     = "%s does not match the url"
+	kERROR_MESSAGE_TIME_ZONE   = "time zone invalid"
+	kERROR_MESSAGE_DATE_FORMAT = "date format invalid, must be iso or int"
+	kHEADER_TIME_ZONE          = "X-Time-Zone"
+	kHEADER_DATE_FORMAT        = "X-Date-Format"
 )
 
+// UseRepository injects the repository every handler reads and writes through.
+func UseRepository(repo repository.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(kCONTEXT_KEY_REPOSITORY, repo)
+		c.Next()
+	}
+}
+
+func getRepository(c *gin.Context) repository.Repository {
+	return c.MustGet(kCONTEXT_KEY_REPOSITORY).(repository.Repository)
+}
+
+// UseDateFormat reads the format of the dates in the response from the
+// date_format query param or the X-Date-Format header: iso, the default, or
+// int for the YYYYMMDD numbers of the clients not moved to ISO-8601 yet.
+func UseDateFormat(c *gin.Context) {
+	format := c.Query("date_format")
+	if format == "" {
+		format = c.GetHeader(kHEADER_DATE_FORMAT)
+	}
+
+	switch strings.ToLower(format) {
+	case "", "iso":
+	case "int":
+		c.Set(kCONTEXT_KEY_LEGACY_DATES, true)
+	default:
+		ResponseErrorBadRequest(c, errors.New(kERROR_MESSAGE_DATE_FORMAT))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// respondJSON writes obj like c.JSON, with the dates in the format
+// UseDateFormat read for the request.
+func respondJSON(c *gin.Context, code int, obj interface{}) {
+	if !c.GetBool(kCONTEXT_KEY_LEGACY_DATES) {
+		c.JSON(code, obj)
+		return
+	}
+
+	data, err := models.MarshalLegacyJSON(obj)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+	c.Data(code, "application/json; charset=utf-8", data)
+}
+
+// getToday returns the current day in the IANA time zone of the X-Time-Zone
+// header, UTC without it, so "today" is the day of the client and not the one
+// of the server.
+func getToday(c *gin.Context) (models.Date, error) {
+	location := time.UTC
+	if name := c.GetHeader(kHEADER_TIME_ZONE); name != "" {
+		var err error
+		if location, err = time.LoadLocation(name); err != nil {
+			return 0, errors.New(kERROR_MESSAGE_TIME_ZONE)
+		}
+	}
+
+	return models.Today(location), nil
+}
+
 func Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Ok",
@@ -25,6 +98,15 @@ func Health(c *gin.Context) {
 	})
 }
 
+// MetaEnums lists the values of the enums with their names, the names being
+// what the API reads and writes.
+func MetaEnums(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"group":  models.GroupTypes(),
+		"status": models.TvShowStatuses(),
+	})
+}
+
 func RouteNotFound(c *gin.Context) {
 	c.JSON(http.StatusBadRequest, gin.H{
 		"message": "Route not found",
@@ -45,6 +127,11 @@ func ResponseErrorBadRequest(c *gin.Context, err error) {
 	ResponseError(c, err, http.StatusBadRequest)
 }
 
+func ResponseErrorForbidden(c *gin.Context, err error) {
+	ResponseError(c, err, http.StatusForbidden)
+	c.Abort()
+}
+
 func ResponseErrorNotFound(c *gin.Context, model interface{}) {
 	name := generic.GetStructName(model)
 	ResponseError(c, errors.New(name+" not found"), http.StatusNotFound)
@@ -58,7 +145,11 @@ func ResponseErrorInternalServerError(c *gin.Context, err error) {
 	ResponseError(c, err, http.StatusInternalServerError)
 }
 
-func Truncate(c *gin.Context, table interface{}) (map[string]string, error) {
+func ResponseErrorBadGateway(c *gin.Context, err error) {
+	ResponseError(c, err, http.StatusBadGateway)
+}
+
+func Truncate(c *gin.Context, table interface{}, truncater repository.Truncater) (map[string]string, error) {
 	mode := c.Query("mode")
 	// mode := c.DefaultQuery("mode", "drop and create")
 
@@ -66,19 +157,12 @@ func Truncate(c *gin.Context, table interface{}) (map[string]string, error) {
 	response := make(map[string]string)
 	response["message"] = name + " truncated"
 
-	if mode == "delete" {
-		if result := database.DB.Where("id is not null").Delete(&table); result.Error != nil {
-			return nil, result.Error
-		}
-	} else {
-		if err := database.DB.Migrator().DropTable(&table); err != nil {
-			return nil, err
-		}
-
-		if err := database.DB.Migrator().CreateTable(&table); err != nil {
-			return nil, err
-		}
+	drop := mode != "delete"
+	if err := truncater.Truncate(drop); err != nil {
+		return nil, err
+	}
 
+	if drop {
 		response["mode"] = "drop and create"
 	}
 
@@ -87,13 +171,19 @@ func Truncate(c *gin.Context, table interface{}) (map[string]string, error) {
 
 func TruncateAll(c *gin.Context) {
 	var err error
+	repo := getRepository(c)
+
+	if _, err = Truncate(c, models.TvShow{}, repo.TvShows()); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
 
-	if _, err = Truncate(c, models.TvShow{}); err != nil {
+	if _, err = Truncate(c, models.Episode{}, repo.Episodes()); err != nil {
 		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	if _, err = Truncate(c, models.Episode{}); err != nil {
+	if _, err = Truncate(c, models.Season{}, repo.Seasons()); err != nil {
 		ResponseErrorInternalServerError(c, err)
 		return
 	}
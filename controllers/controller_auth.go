@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/feealc/tvshows-backend-go/auth"
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kCONTEXT_KEY_SIGNER = "signer"
+	kCONTEXT_KEY_USER   = "user"
+
+	kHEADER_AUTHORIZATION = "Authorization"
+	kHEADER_API_KEY       = "X-API-Key"
+
+	kERROR_MESSAGE_UNAUTHORIZED   = "authentication required"
+	kERROR_MESSAGE_API_KEY        = "api key invalid"
+	kERROR_MESSAGE_CALENDAR_TOKEN = "calendar token invalid"
+)
+
+// UseSigner injects the signer used to issue and verify login tokens.
+func UseSigner(signer *auth.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(kCONTEXT_KEY_SIGNER, signer)
+		c.Next()
+	}
+}
+
+func getSigner(c *gin.Context) *auth.Signer {
+	return c.MustGet(kCONTEXT_KEY_SIGNER).(*auth.Signer)
+}
+
+// SetCurrentUser marks user as the one making the request and binds the
+// request repository to the user's watch state.
+func SetCurrentUser(c *gin.Context, user models.User) {
+	c.Set(kCONTEXT_KEY_USER, user)
+	c.Set(kCONTEXT_KEY_REPOSITORY, getRepository(c).ForUser(user.Id))
+}
+
+func getCurrentUser(c *gin.Context) models.User {
+	return c.MustGet(kCONTEXT_KEY_USER).(models.User)
+}
+
+func ResponseErrorUnauthorized(c *gin.Context, err error) {
+	ResponseError(c, err, http.StatusUnauthorized)
+	c.Abort()
+}
+
+// Authenticate rejects requests without a valid credential. It accepts a JWT
+// issued by UserLogin or an API key, either as "Authorization: Bearer <value>"
+// or, for API keys, in the X-API-Key header.
+func Authenticate(c *gin.Context) {
+	repo := getRepository(c)
+
+	credential := c.GetHeader(kHEADER_API_KEY)
+	if header := c.GetHeader(kHEADER_AUTHORIZATION); credential == "" && header != "" {
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			ResponseErrorUnauthorized(c, auth.ErrInvalidToken)
+			return
+		}
+		credential = strings.TrimSpace(token)
+	}
+
+	if credential == "" {
+		ResponseErrorUnauthorized(c, errors.New(kERROR_MESSAGE_UNAUTHORIZED))
+		return
+	}
+
+	var userId int
+	if auth.IsApiKey(credential) {
+		apiKey, err := repo.ApiKeys().FindByHash(auth.HashApiKey(credential))
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
+			c.Abort()
+			return
+		}
+
+		if apiKey.Id == 0 {
+			ResponseErrorUnauthorized(c, errors.New(kERROR_MESSAGE_API_KEY))
+			return
+		}
+		userId = apiKey.UserId
+	} else {
+		var err error
+		if userId, err = getSigner(c).Verify(credential); err != nil {
+			ResponseErrorUnauthorized(c, err)
+			return
+		}
+	}
+
+	user, err := repo.Users().FindById(userId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		c.Abort()
+		return
+	}
+
+	if user.Id == 0 {
+		ResponseErrorUnauthorized(c, errors.New(kERROR_MESSAGE_UNAUTHORIZED))
+		return
+	}
+
+	SetCurrentUser(c, user)
+	c.Next()
+}
+
+// AuthenticateCalendar lets calendar apps subscribe to the feed with the
+// calendar token of the user in the token query param, since they cannot send
+// headers. Without it the request is authenticated like any other.
+func AuthenticateCalendar(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		Authenticate(c)
+		return
+	}
+
+	user, err := getRepository(c).Users().FindByCalendarTokenHash(auth.HashApiKey(token))
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		c.Abort()
+		return
+	}
+
+	if user.Id == 0 {
+		ResponseErrorUnauthorized(c, errors.New(kERROR_MESSAGE_CALENDAR_TOKEN))
+		return
+	}
+
+	SetCurrentUser(c, user)
+	c.Next()
+}
+
+// AuthenticateRegistration lets the first user register without credentials.
+// A request with credentials is authenticated like any other, so an admin can
+// register the next users.
+func AuthenticateRegistration(c *gin.Context) {
+	if c.GetHeader(kHEADER_API_KEY) == "" && c.GetHeader(kHEADER_AUTHORIZATION) == "" {
+		c.Next()
+		return
+	}
+
+	Authenticate(c)
+}
+
+// RequireRole declares the least role a route needs. It runs after
+// Authenticate, so the request always has a user.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := getCurrentUser(c)
+		if !user.HasRole(role) {
+			ResponseErrorForbidden(c, fmt.Errorf("role %s required", role))
+			return
+		}
+		c.Next()
+	}
+}
+
+func ApiKeyListAll(c *gin.Context) {
+	apiKeys, err := getRepository(c).ApiKeys().FindByUserId(getCurrentUser(c).Id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// ApiKeyCreate answers the only time the key itself is shown, later on it can
+// only be told apart by its prefix.
+func ApiKeyCreate(c *gin.Context) {
+	var apiKey models.ApiKey
+
+	if err := c.ShouldBindJSON(&apiKey); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if err := models.ValidApiKey(&apiKey); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	key, prefix, keyHash, err := auth.NewApiKey()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	apiKey.Id = 0
+	apiKey.UserId = getCurrentUser(c).Id
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = keyHash
+
+	if err := getRepository(c).ApiKeys().Create(&apiKey); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+func ApiKeyDelete(c *gin.Context) {
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	// keys of other users are reported as not found
+	deleted, err := getRepository(c).ApiKeys().Delete(id, getCurrentUser(c).Id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if deleted == 0 {
+		ResponseErrorNotFound(c, models.ApiKey{})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "ApiKey revoked",
+	})
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/backup"
+	"github.com/gin-gonic/gin"
+)
+
+func Backup(c *gin.Context) {
+	doc, err := backup.Build(getRepository(c))
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="tvshows-backup-`+time.Now().Format("20060102")+`.json"`)
+	c.Status(http.StatusOK)
+
+	// the status is already sent, a failure can only cut the document short
+	if err := backup.Write(c.Writer, doc); err != nil {
+		log.Printf("Backup failed: %s", err.Error())
+	}
+}
+
+// Restore loads a backup document. mode is merge by default, see
+// backup.Restore for what each mode does.
+func Restore(c *gin.Context) {
+	var doc backup.Document
+	mode := c.DefaultQuery("mode", backup.ModeMerge)
+
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	report, err := backup.Restore(getRepository(c), doc, mode)
+	if err != nil {
+		var validationError *backup.ValidationError
+		if errors.Is(err, backup.ErrInvalidMode) {
+			ResponseErrorBadRequest(c, err)
+		} else if errors.As(err, &validationError) {
+			ResponseErrorUnprocessableEntity(c, err)
+		} else {
+			ResponseErrorInternalServerError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kBATCH_MODE_PARTIAL = "partial"
+
+	kERROR_MESSAGE_BATCH_MODE = "mode invalid, must be " + kBATCH_MODE_PARTIAL
+
+	BatchStatusCreated = "created"
+	BatchStatusSkipped = "skipped"
+	BatchStatusError   = "error"
+)
+
+// BatchResult tells what happened to the item at Index of a partial batch.
+// Skipped items were duplicates, error items were invalid.
+type BatchResult struct {
+	Index  int         `json:"index"`
+	Status string      `json:"status"`
+	Reason string      `json:"reason,omitempty"`
+	Item   interface{} `json:"item,omitempty"`
+}
+
+type BatchReport struct {
+	Created int           `json:"created"`
+	Skipped int           `json:"skipped"`
+	Errors  int           `json:"errors"`
+	Results []BatchResult `json:"results"`
+}
+
+func newBatchReport(size int) *BatchReport {
+	return &BatchReport{Results: make([]BatchResult, size)}
+}
+
+func (r *BatchReport) created(index int, item interface{}) {
+	r.Created++
+	r.Results[index] = BatchResult{Index: index, Status: BatchStatusCreated, Item: item}
+}
+
+func (r *BatchReport) skipped(index int, err error) {
+	r.Skipped++
+	r.Results[index] = BatchResult{Index: index, Status: BatchStatusSkipped, Reason: err.Error()}
+}
+
+func (r *BatchReport) failed(index int, err error) {
+	r.Errors++
+	r.Results[index] = BatchResult{Index: index, Status: BatchStatusError, Reason: err.Error()}
+}
+
+// parseBatchMode tells if the batch runs in partial mode. Without mode the
+// batch is all or nothing.
+func parseBatchMode(c *gin.Context) (bool, error) {
+	switch c.Query("mode") {
+	case "":
+		return false, nil
+	case kBATCH_MODE_PARTIAL:
+		return true, nil
+	}
+	return false, errors.New(kERROR_MESSAGE_BATCH_MODE)
+}
+
+// tvShowCreateBatchPartial inserts every valid show that does not exist yet
+// and answers 207 with the result of each one.
+func tvShowCreateBatchPartial(c *gin.Context, repo repository.Repository, tvShows []models.TvShow) {
+	existing, err := repo.TvShows().FindAll()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	tmdbIds := make(map[int]bool, len(existing))
+	names := make(map[string]bool, len(existing))
+	for _, tvShow := range existing {
+		tmdbIds[tvShow.TmdbId] = true
+		names[tvShow.Name] = true
+	}
+
+	report := newBatchReport(len(tvShows))
+	var toCreate []models.TvShow
+	var indexes []int
+	for index, tvShow := range tvShows {
+		if err := models.ValidTvShow(&tvShow); err != nil {
+			report.failed(index, err)
+			continue
+		}
+
+		if tmdbIds[tvShow.TmdbId] {
+			report.skipped(index, fmt.Errorf("TvShow %s (TMDB ID %d) already exist", tvShow.Name, tvShow.TmdbId))
+			continue
+		}
+
+		if names[tvShow.Name] {
+			report.skipped(index, fmt.Errorf("TvShow name %s already exist", tvShow.Name))
+			continue
+		}
+
+		tmdbIds[tvShow.TmdbId] = true
+		names[tvShow.Name] = true
+		toCreate = append(toCreate, tvShow)
+		indexes = append(indexes, index)
+	}
+
+	// a show created since FindAll is skipped by the insert itself
+	inserted, err := repo.TvShows().CreateMissing(toCreate)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	for position, tvShow := range toCreate {
+		if !inserted[position] {
+			report.skipped(indexes[position], fmt.Errorf("TvShow %s (TMDB ID %d) already exist", tvShow.Name, tvShow.TmdbId))
+			continue
+		}
+		report.created(indexes[position], tvShow)
+	}
+
+	respondJSON(c, http.StatusMultiStatus, report)
+}
+
+// episodeCreateBatchPartial inserts every valid episode whose show exists and
+// that does not exist yet, and answers 207 with the result of each one.
+func episodeCreateBatchPartial(c *gin.Context, repo repository.Repository, episodes []models.Episode) {
+	tvShows := make(map[int]models.TvShow)
+	keys := make(map[string]bool)
+
+	report := newBatchReport(len(episodes))
+	var toCreate []models.Episode
+	var indexes []int
+	for index, episode := range episodes {
+		if err := models.ValidEpisode(&episode); err != nil {
+			report.failed(index, err)
+			continue
+		}
+
+		// one lookup per show, batches are usually a season of the same show
+		tvShow, ok := tvShows[episode.TmdbId]
+		if !ok {
+			var err error
+			if tvShow, err = loadEpisodeKeys(repo, episode.TmdbId, keys); err != nil {
+				ResponseErrorInternalServerError(c, err)
+				return
+			}
+			tvShows[episode.TmdbId] = tvShow
+		}
+
+		if tvShow.Id == 0 {
+			report.failed(index, fmt.Errorf("TvShow (TMDB ID %d) not found", episode.TmdbId))
+			continue
+		}
+
+		key := fmt.Sprintf("%d/%dx%d", episode.TmdbId, episode.Season, episode.Episode)
+		if keys[key] {
+			report.skipped(index, fmt.Errorf("episode %dx%02d already exist for %s", episode.Season, episode.Episode, tvShow.Name))
+			continue
+		}
+
+		keys[key] = true
+		toCreate = append(toCreate, episode)
+		indexes = append(indexes, index)
+	}
+
+	// an episode created since its keys were loaded is skipped by the insert
+	inserted, err := repo.Episodes().CreateMissing(toCreate)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	for position, episode := range toCreate {
+		if !inserted[position] {
+			report.skipped(indexes[position], fmt.Errorf("episode %dx%02d already exist for %s", episode.Season, episode.Episode, tvShows[episode.TmdbId].Name))
+			continue
+		}
+		report.created(indexes[position], episode)
+	}
+
+	respondJSON(c, http.StatusMultiStatus, report)
+}
+
+// loadEpisodeKeys returns the show of tmdbId and adds the keys of its
+// episodes to keys.
+func loadEpisodeKeys(repo repository.Repository, tmdbId int, keys map[string]bool) (models.TvShow, error) {
+	tvShow, err := repo.TvShows().FindByTmdbId(tmdbId)
+	if err != nil || tvShow.Id == 0 {
+		return tvShow, err
+	}
+
+	episodes, err := repo.Episodes().FindByTmdbId(tmdbId)
+	if err != nil {
+		return tvShow, err
+	}
+
+	for _, episode := range episodes {
+		keys[fmt.Sprintf("%d/%dx%d", episode.TmdbId, episode.Season, episode.Episode)] = true
+	}
+	return tvShow, nil
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/ical"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kCALENDAR_DEFAULT_DAYS = 30
+	kCALENDAR_ICS_PATH     = "/api/v1/calendar.ics"
+	kCALENDAR_ICS_PRODID   = "-//tvshows-backend-go//calendar//EN"
+
+	kERROR_MESSAGE_FROM = "from invalid, must be YYYY-MM-DD"
+	kERROR_MESSAGE_TO   = "to invalid, must be YYYY-MM-DD"
+
+	kERROR_MESSAGE_TO_BEFORE_FROM = "to invalid, must not be before from"
+)
+
+// CalendarEpisode is an episode with the name of its show.
+type CalendarEpisode struct {
+	models.Episode
+	TvShowName string `json:"tv_show_name"`
+}
+
+// CalendarDay groups the episodes airing on one date.
+type CalendarDay struct {
+	Date     models.Date       `json:"date"`
+	Episodes []CalendarEpisode `json:"episodes"`
+}
+
+var calendarSort = []repository.SortField{{Field: "air_date"}, {Field: "tmdb_id"}, {Field: "season"}, {Field: "episode"}}
+
+// parseCalendarFilter reads the air date window and the filters of the
+// calendar. from defaults to today and to to kCALENDAR_DEFAULT_DAYS after it.
+// unwatched=true keeps only the unwatched episodes, status and group keep only
+// the episodes of shows with that Status or GroupType.
+func parseCalendarFilter(c *gin.Context) (repository.ListFilter, error) {
+	var filter repository.ListFilter
+	var err error
+
+	if filter.AirDateFrom, err = generic.CheckParamDate(c.Query("from"), kERROR_MESSAGE_FROM); err != nil {
+		return filter, err
+	}
+	if filter.AirDateFrom == 0 {
+		if filter.AirDateFrom, err = getToday(c); err != nil {
+			return filter, err
+		}
+	}
+
+	if filter.AirDateTo, err = generic.CheckParamDate(c.Query("to"), kERROR_MESSAGE_TO); err != nil {
+		return filter, err
+	}
+	if filter.AirDateTo == 0 {
+		filter.AirDateTo = filter.AirDateFrom.AddDays(kCALENDAR_DEFAULT_DAYS)
+	}
+	if filter.AirDateTo < filter.AirDateFrom {
+		return filter, errors.New(kERROR_MESSAGE_TO_BEFORE_FROM)
+	}
+
+	unwatched, err := generic.CheckParamBool(c.Query("unwatched"), "unwatched invalid")
+	if err != nil {
+		return filter, err
+	}
+	if unwatched != nil && *unwatched {
+		watched := false
+		filter.Watched = &watched
+	}
+
+	if filter.Status, err = checkParamStatus(c.Query("status")); err != nil {
+		return filter, err
+	}
+	if filter.GroupType, err = checkParamGroup(c.Query("group")); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// findCalendarEpisodes returns the episodes matching filter sorted by air date,
+// each one with the name of its show.
+func findCalendarEpisodes(repo repository.Repository, filter repository.ListFilter) ([]CalendarEpisode, error) {
+	episodes, _, err := repo.Episodes().List(repository.ListOptions{Sort: calendarSort, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	tvShows, err := repo.TvShows().FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(tvShows))
+	for _, tvShow := range tvShows {
+		names[tvShow.TmdbId] = tvShow.Name
+	}
+
+	calendar := make([]CalendarEpisode, 0, len(episodes))
+	for _, episode := range episodes {
+		calendar = append(calendar, CalendarEpisode{Episode: episode, TvShowName: names[episode.TmdbId]})
+	}
+	return calendar, nil
+}
+
+// Calendar returns the episodes airing between from and to grouped by day.
+// Days without episodes are left out.
+func Calendar(c *gin.Context) {
+	repo := getRepository(c)
+
+	filter, err := parseCalendarFilter(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	episodes, err := findCalendarEpisodes(repo, filter)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	days := []CalendarDay{}
+	for _, episode := range episodes {
+		if len(days) == 0 || days[len(days)-1].Date != episode.AirDate {
+			days = append(days, CalendarDay{Date: episode.AirDate})
+		}
+		day := &days[len(days)-1]
+		day.Episodes = append(day.Episodes, episode)
+	}
+
+	respondJSON(c, http.StatusOK, days)
+}
+
+// CalendarIcs renders the episodes airing from today on as an iCalendar feed
+// of all-day events. The tmdb_id and group query params keep only one show or
+// the shows of one GroupType.
+func CalendarIcs(c *gin.Context) {
+	repo := getRepository(c)
+	filter := repository.ListFilter{AirDateFrom: models.Today(time.UTC)}
+	var err error
+
+	if filter.TmdbId, err = generic.CheckParamInt(c.Query("tmdb_id"), kERROR_MESSAGE_TMDBID); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+	if filter.GroupType, err = checkParamGroup(c.Query("group")); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	episodes, err := findCalendarEpisodes(repo, filter)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	calendar := ical.Calendar{ProdId: kCALENDAR_ICS_PRODID, Name: "TV Shows"}
+	for _, episode := range episodes {
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("episode-%d@tvshows-backend-go", episode.Id),
+			Date:        episode.AirDate.Time(),
+			Summary:     fmt.Sprintf("%s %dx%02d - %s", episode.TvShowName, episode.Season, episode.Episode.Episode, episode.Name),
+			Description: episode.Overview,
+			Stamp:       episode.UpdatedAt,
+		})
+	}
+
+	c.Header("Content-Type", ical.ContentType)
+	c.Status(http.StatusOK)
+	if err := calendar.Write(c.Writer); err != nil {
+		log.Printf("Export calendar.ics failed: %s", err.Error())
+	}
+}
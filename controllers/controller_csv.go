@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/csvio"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kCSV_FORM_FILE = "file"
+
+	kERROR_MESSAGE_CSV_FILE = "file required, send the csv as the multipart field file"
+)
+
+func setCsvHeaders(c *gin.Context, filename string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+}
+
+func TvShowExportCsv(c *gin.Context) {
+	tvShows, err := getRepository(c).TvShows().FindAll()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	setCsvHeaders(c, "tvshows.csv")
+	if err := csvio.WriteTvShows(c.Writer, tvShows); err != nil {
+		log.Printf("Export tvshows.csv failed: %s", err.Error())
+	}
+}
+
+func EpisodeExportCsv(c *gin.Context) {
+	episodes, err := getRepository(c).Episodes().FindAll()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	setCsvHeaders(c, "episodes.csv")
+	if err := csvio.WriteEpisodes(c.Writer, episodes); err != nil {
+		log.Printf("Export episodes.csv failed: %s", err.Error())
+	}
+}
+
+// importCsv reads the uploaded file and answers with the report of
+// importFn. Rows with errors do not fail the request, they are listed in the
+// report.
+func importCsv(c *gin.Context, header []string, required []string, importFn func(repo repository.Repository, records []csvio.Record) (csvio.Report, error)) {
+	fileHeader, err := c.FormFile(kCSV_FORM_FILE)
+	if err != nil {
+		ResponseErrorBadRequest(c, errors.New(kERROR_MESSAGE_CSV_FILE))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+	defer file.Close()
+
+	records, err := csvio.Read(file, header, required)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	report, err := importFn(getRepository(c), records)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func TvShowImportCsv(c *gin.Context) {
+	importCsv(c, csvio.TvShowHeader, csvio.TvShowRequired, csvio.ImportTvShows)
+}
+
+func EpisodeImportCsv(c *gin.Context) {
+	importCsv(c, csvio.EpisodeHeader, csvio.EpisodeRequired, csvio.ImportEpisodes)
+}
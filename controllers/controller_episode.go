@@ -1,28 +1,39 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"sort"
 
-	"github.com/feealc/tvshows-backend-go/database"
 	"github.com/feealc/tvshows-backend-go/generic"
 	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 func EpisodeListAll(c *gin.Context) {
-	var episodes []models.Episode
+	opts, err := parseListOptions(c, repository.EpisodeSortFields)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
 
-	if result := database.DB.Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodes, total, err := getRepository(c).Episodes().List(opts)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, episodes)
+	setListHeaders(c, opts, total)
+	respondJSON(c, http.StatusOK, episodes)
 }
 
 func EpisodeListByTmdbId(c *gin.Context) {
+	repo := getRepository(c)
 	paramTmdbId := c.Params.ByName("tmdbid")
 
 	tmdbId, err := generic.CheckParamInt(paramTmdbId, kERROR_MESSAGE_TMDBID)
@@ -31,9 +42,9 @@ func EpisodeListByTmdbId(c *gin.Context) {
 		return
 	}
 
-	var tvShowExist models.TvShow
-	if result := database.DB.Where(&models.TvShow{TmdbId: tmdbId}).Find(&tvShowExist); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShowExist, err := repo.TvShows().FindByTmdbId(tmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -42,16 +53,17 @@ func EpisodeListByTmdbId(c *gin.Context) {
 		return
 	}
 
-	var episodes []models.Episode
-	if result := database.DB.Where(&models.Episode{TmdbId: tvShowExist.TmdbId}).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodes, err := repo.Episodes().FindByTmdbId(tvShowExist.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, episodes)
+	respondJSON(c, http.StatusOK, episodes)
 }
 
 func EpisodeListByTmdbIdAndSeason(c *gin.Context) {
+	repo := getRepository(c)
 	paramTmdbId := c.Params.ByName("tmdbid")
 	paramSeason := c.Params.ByName("season")
 
@@ -67,9 +79,9 @@ func EpisodeListByTmdbIdAndSeason(c *gin.Context) {
 		return
 	}
 
-	var tvShowExist models.TvShow
-	if result := database.DB.Where(&models.TvShow{TmdbId: tmdbId}).Find(&tvShowExist); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShowExist, err := repo.TvShows().FindByTmdbId(tmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -78,16 +90,45 @@ func EpisodeListByTmdbIdAndSeason(c *gin.Context) {
 		return
 	}
 
-	var episodes []models.Episode
-	if result := database.DB.Where(&models.Episode{TmdbId: tmdbId, Season: season}).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodes, err := repo.Episodes().FindByTmdbIdAndSeason(tmdbId, season)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, episodes)
+	respondJSON(c, http.StatusOK, episodes)
+}
+
+func EpisodeListById(c *gin.Context) {
+	repo := getRepository(c)
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	episode, err := repo.Episodes().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if episode.Id == 0 {
+		ResponseErrorNotFound(c, models.Episode{})
+		return
+	}
+
+	if respondNotModified(c, episode.ETag()) {
+		return
+	}
+
+	respondJSON(c, http.StatusOK, episode)
 }
 
 func EpisodeSummaryBySeason(c *gin.Context) {
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 
 	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
@@ -96,9 +137,15 @@ func EpisodeSummaryBySeason(c *gin.Context) {
 		return
 	}
 
-	var tvShowExist models.TvShow
-	if result := database.DB.Find(&tvShowExist, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	specials, err := parseSpecials(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	tvShowExist, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -107,73 +154,74 @@ func EpisodeSummaryBySeason(c *gin.Context) {
 		return
 	}
 
-	var episodes []models.Episode
-	if result := database.DB.Where(&models.Episode{TmdbId: tvShowExist.TmdbId}).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodes, err := repo.Episodes().FindByTmdbId(tvShowExist.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	// if no episodes found, return empty slice
-	if len(episodes) == 0 {
-		c.JSON(http.StatusOK, episodes)
+	seasons, err := repo.Seasons().FindByTmdbId(tvShowExist.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	episodesBySeasons := make(map[int][]models.Episode)
+	type SeasonSummary struct {
+		Season               int    `json:"season"`
+		Name                 string `json:"name,omitempty"`
+		EpisodeCount         int    `json:"episode_count,omitempty"`
+		TotalEpisodes        int    `json:"total_episodes"`
+		TotalEpisodesWatched int    `json:"total_episodes_watched"`
+	}
+
+	// seasons announced without episodes yet are reported too, with totals 0.
+	// name and episode_count only come from the announced seasons
+	summaries := make(map[int]*SeasonSummary)
+	for _, season := range seasons {
+		if season.Number == 0 && !specials {
+			continue
+		}
+		summaries[season.Number] = &SeasonSummary{Season: season.Number, Name: season.Name, EpisodeCount: season.EpisodeCount}
+	}
 
 	for _, episode := range episodes {
-		season := episode.Season
+		if episode.Season == 0 && !specials {
+			continue
+		}
+		summary, ok := summaries[episode.Season]
+		if !ok {
+			summary = &SeasonSummary{Season: episode.Season}
+			summaries[episode.Season] = summary
+		}
 
-		aux := episodesBySeasons[season]
-		aux = append(aux, episode)
-		episodesBySeasons[season] = aux
-		// log.Printf("append %dx%d", episode.Season, episode.Episode)
+		summary.TotalEpisodes += 1
+		if episode.Watched {
+			summary.TotalEpisodesWatched += 1
+		}
 	}
 
-	// sort map by key (key = season number)
-	keys := make([]int, 0, len(episodesBySeasons))
-	for k := range episodesBySeasons {
+	// sort map by key (key = season number), specials last
+	keys := make([]int, 0, len(summaries))
+	for k := range summaries {
 		keys = append(keys, k)
 	}
-	sort.Ints(keys)
-
-	// var maxSeason int
-	// if len(keys) > 0 {
-	// 	maxSeason = keys[len(keys)-1]
-	// }
-	// fmt.Printf("max season [%d]\n", maxSeason)
-
-	// for _, season := range keys {
-	// 	log.Println("==============================")
-	// 	for _, ep := range episodesBySeasons[season] {
-	// 		log.Printf("ep %dx%d => %s \n", season, ep.Episode, ep.Name)
-	// 	}
-	// }
-
-	type SeasonSummary struct {
-		Season               int `json:"season"`
-		TotalEpisodes        int `json:"total_episodes"`
-		TotalEpisodesWatched int `json:"total_episodes_watched"`
-	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == 0) != (keys[j] == 0) {
+			return keys[j] == 0
+		}
+		return keys[i] < keys[j]
+	})
 
-	var responseSummary []SeasonSummary
-	var totalEpisodes, totalEpisodesWatched int
+	responseSummary := []SeasonSummary{}
 	for _, season := range keys {
-		totalEpisodes = 0
-		totalEpisodesWatched = 0
-		for _, ep := range episodesBySeasons[season] {
-			totalEpisodes += 1
-			if ep.Watched {
-				totalEpisodesWatched += 1
-			}
-		}
-		responseSummary = append(responseSummary, SeasonSummary{Season: season, TotalEpisodes: totalEpisodes, TotalEpisodesWatched: totalEpisodesWatched})
+		responseSummary = append(responseSummary, *summaries[season])
 	}
 
-	c.JSON(http.StatusOK, responseSummary)
+	respondJSON(c, http.StatusOK, responseSummary)
 }
 
 func EpisodeCreate(c *gin.Context) {
+	repo := getRepository(c)
 	var episode models.Episode
 
 	if err := c.ShouldBindJSON(&episode); err != nil {
@@ -186,9 +234,9 @@ func EpisodeCreate(c *gin.Context) {
 		return
 	}
 
-	var tvShowExist models.TvShow
-	if result := database.DB.Where(&models.TvShow{TmdbId: episode.TmdbId}).Find(&tvShowExist); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShowExist, err := repo.TvShows().FindByTmdbId(episode.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -197,9 +245,9 @@ func EpisodeCreate(c *gin.Context) {
 		return
 	}
 
-	var episodeExist models.Episode
-	if result := database.DB.Where(&models.Episode{TmdbId: episode.TmdbId, Season: episode.Season, Episode: episode.Episode}).Find(&episodeExist); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodeExist, err := repo.Episodes().FindByKey(episode.TmdbId, episode.Season, episode.Episode)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -208,22 +256,34 @@ func EpisodeCreate(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Create(&episode); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if err := repo.Episodes().Create(&episode); err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, episode)
+	respondJSON(c, http.StatusCreated, episode)
 }
 
 func EpisodeCreateBatch(c *gin.Context) {
+	repo := getRepository(c)
 	var episodes []models.Episode
 
+	partial, err := parseBatchMode(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
 	if err := c.ShouldBindJSON(&episodes); err != nil {
 		ResponseErrorBadRequest(c, err)
 		return
 	}
 
+	if partial {
+		episodeCreateBatchPartial(c, repo, episodes)
+		return
+	}
+
 	for index, episode := range episodes {
 		if err := models.ValidEpisode(&episode); err != nil {
 			ResponseErrorUnprocessableEntity(c, err)
@@ -231,9 +291,9 @@ func EpisodeCreateBatch(c *gin.Context) {
 		}
 		episodes[index] = episode
 
-		var tvShowExist models.TvShow
-		if result := database.DB.Where(&models.TvShow{TmdbId: episode.TmdbId}).Find(&tvShowExist); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		tvShowExist, err := repo.TvShows().FindByTmdbId(episode.TmdbId)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -242,9 +302,9 @@ func EpisodeCreateBatch(c *gin.Context) {
 			return
 		}
 
-		var episodeExist models.Episode
-		if result := database.DB.Where(&models.Episode{TmdbId: episode.TmdbId, Season: episode.Season, Episode: episode.Episode}).Find(&episodeExist); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		episodeExist, err := repo.Episodes().FindByKey(episode.TmdbId, episode.Season, episode.Episode)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -254,15 +314,131 @@ func EpisodeCreateBatch(c *gin.Context) {
 		}
 	}
 
-	if result := database.DB.Create(&episodes); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if err := repo.Episodes().CreateMany(episodes); err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, episodes)
+	respondJSON(c, http.StatusCreated, episodes)
+}
+
+// EpisodeUpsert creates the episode of the url key or updates it in place, so
+// import scripts can run again without failing on existing episodes. The
+// watched fields left out of the body keep their current value.
+func EpisodeUpsert(c *gin.Context) {
+	repo := getRepository(c)
+	var episode models.Episode
+	var fields map[string]json.RawMessage
+
+	key, err := episodeKeyParams(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if err := c.ShouldBindBodyWith(&episode, binding.JSON); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	for _, check := range []struct {
+		name        string
+		body, param int
+	}{
+		{"tmdb_id", episode.TmdbId, key.TmdbId},
+		{"season", episode.Season, key.Season},
+		{"episode", episode.Episode, key.Episode},
+	} {
+		if check.body != 0 && check.body != check.param {
+			ResponseErrorBadRequest(c, fmt.Errorf(kERROR_MESSAGE_KEY_URL, check.name))
+			return
+		}
+	}
+	episode.TmdbId, episode.Season, episode.Episode = key.TmdbId, key.Season, key.Episode
+
+	if err := models.ValidEpisode(&episode); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	tvShowExist, err := repo.TvShows().FindByTmdbId(episode.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if tvShowExist.Id == 0 {
+		ResponseErrorNotFound(c, models.TvShow{})
+		return
+	}
+
+	episodeExist, err := repo.Episodes().FindByKey(episode.TmdbId, episode.Season, episode.Episode)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	etag := ""
+	if episodeExist.Id > 0 {
+		etag = episodeExist.ETag()
+	}
+	if !checkIfMatch(c, models.Episode{}, etag) {
+		return
+	}
+
+	if _, ok := fields["watched"]; !ok {
+		episode.Watched = episodeExist.Watched
+	}
+	if _, ok := fields["watched_date"]; !ok {
+		episode.WatchedDate = episodeExist.WatchedDate
+	}
+
+	var created bool
+	if writeIfMatch(c) {
+		err = repo.Episodes().UpsertIfUnchanged(&episode, episodeExist.UpdatedAt)
+	} else {
+		created, err = repo.Episodes().Upsert(&episode)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.Episode{})
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.Header(kHEADER_ETAG, episode.ETag())
+	respondJSON(c, status, episode)
+}
+
+// episodeKeyParams reads the tmdbid, season and episode url params.
+func episodeKeyParams(c *gin.Context) (models.Episode, error) {
+	var key models.Episode
+	var err error
+
+	if key.TmdbId, err = generic.CheckParamInt(c.Params.ByName("tmdbid"), kERROR_MESSAGE_TMDBID); err != nil {
+		return key, err
+	}
+	if key.Season, err = generic.CheckParamInt(c.Params.ByName("season"), kERROR_MESSAGE_SEASON); err != nil {
+		return key, err
+	}
+	if key.Episode, err = generic.CheckParamInt(c.Params.ByName("episode"), kERROR_MESSAGE_EPISODE); err != nil {
+		return key, err
+	}
+	return key, nil
 }
 
 func EpisodeEdit(c *gin.Context) {
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 
 	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
@@ -271,9 +447,9 @@ func EpisodeEdit(c *gin.Context) {
 		return
 	}
 
-	var episodeUpdate models.Episode
-	if result := database.DB.Find(&episodeUpdate, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	episodeUpdate, err := repo.Episodes().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -282,6 +458,11 @@ func EpisodeEdit(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, models.Episode{}, episodeUpdate.ETag()) {
+		return
+	}
+	updatedAt := episodeUpdate.UpdatedAt
+
 	if err := c.ShouldBindJSON(&episodeUpdate); err != nil {
 		ResponseErrorBadRequest(c, err)
 		return
@@ -292,15 +473,85 @@ func EpisodeEdit(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Save(&episodeUpdate); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if writeIfMatch(c) {
+		err = repo.Episodes().SaveIfUnchanged(&episodeUpdate, updatedAt)
+	} else {
+		err = repo.Episodes().Save(&episodeUpdate)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.Episode{})
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, episodeUpdate)
+	c.Header(kHEADER_ETAG, episodeUpdate.ETag())
+	respondJSON(c, http.StatusOK, episodeUpdate)
+}
+
+// EpisodePatch edits the episode with a JSON Merge Patch, so fields left out
+// of the body keep their value and fields set to null are cleared.
+func EpisodePatch(c *gin.Context) {
+	repo := getRepository(c)
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	episode, err := repo.Episodes().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if episode.Id == 0 {
+		ResponseErrorNotFound(c, models.Episode{})
+		return
+	}
+
+	if !checkIfMatch(c, models.Episode{}, episode.ETag()) {
+		return
+	}
+	updatedAt := episode.UpdatedAt
+
+	if !applyMergePatch(c, &episode, episodeImmutableFields) {
+		return
+	}
+
+	if err := models.ValidEpisode(&episode); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	if writeIfMatch(c) {
+		err = repo.Episodes().SaveIfUnchanged(&episode, updatedAt)
+	} else {
+		err = repo.Episodes().Save(&episode)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.Episode{})
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicatedKey) {
+		ResponseErrorBadRequest(c, fmt.Errorf("episode %dx%02d already exist", episode.Season, episode.Episode))
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.Header(kHEADER_ETAG, episode.ETag())
+	respondJSON(c, http.StatusOK, episode)
 }
 
 func EpisodeEditMarkWatched(c *gin.Context) {
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 	paramTmdbId := c.Params.ByName("tmdbid")
 	paramSeason := c.Params.ByName("season")
@@ -323,10 +574,16 @@ func EpisodeEditMarkWatched(c *gin.Context) {
 		return
 	}
 
+	today, err := getToday(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
 	if paramId != "" {
-		var episodeUpdate models.Episode
-		if result := database.DB.Find(&episodeUpdate, id); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		episodeUpdate, err := repo.Episodes().FindById(id)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -335,23 +592,33 @@ func EpisodeEditMarkWatched(c *gin.Context) {
 			return
 		}
 
+		if !checkIfMatch(c, models.Episode{}, episodeUpdate.ETag()) {
+			return
+		}
+
+		// unmarking keeps the date of the last watch, the history keeps them all
 		episodeUpdate.Watched = !episodeUpdate.Watched
 		if episodeUpdate.Watched {
-			episodeUpdate.WatchedDate = generic.GetCurrentDate()
-		} else {
-			episodeUpdate.WatchedDate = 0
+			episodeUpdate.WatchedDate = today
 		}
 
-		if result := database.DB.Save(&episodeUpdate); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		if err := repo.Episodes().SaveWatchState(&episodeUpdate); err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, episodeUpdate)
+		c.Header(kHEADER_ETAG, episodeUpdate.ETag())
+		respondJSON(c, http.StatusOK, episodeUpdate)
 	} else {
-		var tvShowExist models.TvShow
-		if result := database.DB.Where(&models.TvShow{TmdbId: tmdbId}).Find(&tvShowExist); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		watched, err := parseWatched(c)
+		if err != nil {
+			ResponseErrorBadRequest(c, err)
+			return
+		}
+
+		tvShowExist, err := repo.TvShows().FindByTmdbId(tmdbId)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -361,33 +628,81 @@ func EpisodeEditMarkWatched(c *gin.Context) {
 		}
 
 		var episodesToUpdate []models.Episode
-		if result := database.DB.Where(&models.Episode{TmdbId: tmdbId, Season: season}).Find(&episodesToUpdate); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		if paramSeason == "" {
+			episodesToUpdate, err = repo.Episodes().FindByTmdbId(tmdbId)
+		} else {
+			episodesToUpdate, err = repo.Episodes().FindByTmdbIdAndSeason(tmdbId, season)
+		}
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
 		if len(episodesToUpdate) == 0 {
-			ResponseError(c, fmt.Errorf("episodes not found for season %d", season), http.StatusNotFound)
+			err := fmt.Errorf("episodes not found for season %d", season)
+			if paramSeason == "" {
+				err = fmt.Errorf("episodes not found for %s", tvShowExist.Name)
+			}
+			ResponseError(c, err, http.StatusNotFound)
 			return
 		}
 
+		// episodes already in that state are left alone, so they keep their
+		// date and no rewatch is recorded for them
+		var changed []models.Episode
 		for index, episode := range episodesToUpdate {
-			// fmt.Println(episode)
-			episode.Watched = true
-			episode.WatchedDate = generic.GetCurrentDate()
+			if episode.Watched == watched {
+				continue
+			}
+			episode.Watched = watched
+			if watched {
+				episode.WatchedDate = today
+			}
 			episodesToUpdate[index] = episode
+			changed = append(changed, episode)
 		}
 
-		if result := database.DB.Save(&episodesToUpdate); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		if err := repo.Episodes().SaveWatchStates(changed); err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, episodesToUpdate)
+		respondJSON(c, http.StatusOK, episodesToUpdate)
+	}
+}
+
+// parseWatched reads the watched value of the bulk watch routes from the
+// query or the body, true when neither sets it. Unwatched episodes keep the
+// date of their last watch, like unmarking a single episode.
+func parseWatched(c *gin.Context) (bool, error) {
+	var body struct {
+		Watched *bool `json:"watched"`
+	}
+
+	query, err := generic.CheckParamBool(c.Query("watched"), kERROR_MESSAGE_WATCHED)
+	if err != nil {
+		return false, err
+	}
+
+	if c.Request.Body != nil {
+		if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+	}
+
+	switch {
+	case query != nil && body.Watched != nil && *query != *body.Watched:
+		return false, errors.New(kERROR_MESSAGE_WATCHED + ", query and body disagree")
+	case query != nil:
+		return *query, nil
+	case body.Watched != nil:
+		return *body.Watched, nil
 	}
+	return true, nil
 }
 
 func EpisodeDelete(c *gin.Context) {
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 	paramTmdbId := c.Params.ByName("tmdbid")
 	paramSeason := c.Params.ByName("season")
@@ -403,8 +718,9 @@ func EpisodeDelete(c *gin.Context) {
 		}
 
 		var episode models.Episode
-		if result := database.DB.Find(&episode, id); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		episode, err = repo.Episodes().FindById(id)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -413,17 +729,31 @@ func EpisodeDelete(c *gin.Context) {
 			return
 		}
 
-		if result := database.DB.Delete(&episode, id); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		if !checkIfMatch(c, models.Episode{}, episode.ETag()) {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		if writeIfMatch(c) {
+			err = repo.Episodes().DeleteIfUnchanged(id, episode.UpdatedAt)
+		} else {
+			err = repo.Episodes().Delete(id)
+		}
+		if errors.Is(err, repository.ErrStale) {
+			respondPreconditionFailed(c, models.Episode{})
+			return
+		}
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
+			return
+		}
+
+		respondJSON(c, http.StatusOK, gin.H{
 			"message": "Episode deleted",
 		})
 		return
 	} else {
 		var tmdbId, season int
+		var rowsAffected int64
 
 		tmdbId, err = generic.CheckParamInt(paramTmdbId, kERROR_MESSAGE_TMDBID)
 		if err != nil {
@@ -437,32 +767,35 @@ func EpisodeDelete(c *gin.Context) {
 			return
 		}
 
-		var episodes []models.Episode
-		result := database.DB.Where(&models.Episode{TmdbId: tmdbId, Season: season}).Delete(&episodes)
+		if paramSeason == "" {
+			rowsAffected, err = repo.Episodes().DeleteByTmdbId(tmdbId)
+		} else {
+			rowsAffected, err = repo.Episodes().DeleteByTmdbIdAndSeason(tmdbId, season)
+		}
 
-		if result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
-		if result.RowsAffected == 0 {
+		if rowsAffected == 0 {
 			ResponseErrorNotFound(c, models.Episode{})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		respondJSON(c, http.StatusOK, gin.H{
 			"message": "Episodes deleted",
-			"rows":    result.RowsAffected,
+			"rows":    rowsAffected,
 		})
 		return
 	}
 }
 
 func EpisodeTruncate(c *gin.Context) {
-	response, err := Truncate(c, models.Episode{})
+	response, err := Truncate(c, models.Episode{}, getRepository(c).Episodes())
 	if err != nil {
 		ResponseErrorInternalServerError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	respondJSON(c, http.StatusOK, response)
 }
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kHEADER_ETAG          = "ETag"
+	kHEADER_IF_MATCH      = "If-Match"
+	kHEADER_IF_NONE_MATCH = "If-None-Match"
+)
+
+// respondNotModified sets the ETag of the representation and answers 304 when
+// the client already has it, returning true when the handler must stop.
+func respondNotModified(c *gin.Context, etag string) bool {
+	c.Header(kHEADER_ETAG, etag)
+
+	if header := c.GetHeader(kHEADER_IF_NONE_MATCH); header != "" && etagMatches(header, etag, true) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// checkIfMatch answers 412 when the request carries an If-Match that is not
+// the current ETag of model, returning false when the handler must stop. An
+// empty etag means the resource does not exist yet.
+func checkIfMatch(c *gin.Context, model interface{}, etag string) bool {
+	header := c.GetHeader(kHEADER_IF_MATCH)
+	if header == "" {
+		return true
+	}
+
+	if etag == "" || !etagMatches(header, etag, false) {
+		respondPreconditionFailed(c, model)
+		return false
+	}
+	return true
+}
+
+// writeIfMatch tells whether the write must still find the row checkIfMatch
+// read, because another request may change it in between. "*" only asks for
+// the row to exist.
+func writeIfMatch(c *gin.Context) bool {
+	header := strings.TrimSpace(c.GetHeader(kHEADER_IF_MATCH))
+	return header != "" && header != "*"
+}
+
+func respondPreconditionFailed(c *gin.Context, model interface{}) {
+	name := generic.GetStructName(model)
+	ResponseError(c, errors.New("precondition failed, If-Match does not match the current "+name), http.StatusPreconditionFailed)
+}
+
+// etagMatches compares a comma separated list of entity tags, or "*", with
+// etag. If-None-Match uses the weak comparison, If-Match the strong one.
+func etagMatches(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kLIST_MAX_LIMIT = 1000
+
+	kHEADER_TOTAL_COUNT = "X-Total-Count"
+	kHEADER_LIMIT       = "X-Limit"
+	kHEADER_OFFSET      = "X-Offset"
+
+	kERROR_MESSAGE_LIMIT    = "limit invalid"
+	kERROR_MESSAGE_OFFSET   = "offset invalid"
+	kERROR_MESSAGE_SORT     = "sort invalid"
+	kERROR_MESSAGE_STATUS   = "status invalid"
+	kERROR_MESSAGE_WATCHED  = "watched invalid"
+	kERROR_MESSAGE_SPECIALS = "specials invalid"
+	kERROR_MESSAGE_AIR_DATE = "air date invalid, must be YYYY-MM-DD"
+)
+
+// checkParamGroup reads a GroupType query param, its name or number. An empty
+// param is 0.
+func checkParamGroup(param string) (models.GroupType, error) {
+	if param == "" {
+		return 0, nil
+	}
+	group, err := models.ParseGroupType(param)
+	if err != nil {
+		return 0, errors.New(kERROR_MESSAGE_GROUP)
+	}
+	return group, nil
+}
+
+// checkParamStatus reads a TvShowStatus query param, its name or number. An
+// empty param is 0.
+func checkParamStatus(param string) (models.TvShowStatus, error) {
+	if param == "" {
+		return 0, nil
+	}
+	status, err := models.ParseTvShowStatus(param)
+	if err != nil {
+		return 0, errors.New(kERROR_MESSAGE_STATUS)
+	}
+	return status, nil
+}
+
+// parseSpecials reads the specials query param, telling whether the episodes
+// of season 0 are included. They are unless specials=false.
+func parseSpecials(c *gin.Context) (bool, error) {
+	specials, err := generic.CheckParamBool(c.Query("specials"), kERROR_MESSAGE_SPECIALS)
+	if err != nil || specials == nil {
+		return true, err
+	}
+	return *specials, nil
+}
+
+// parseListOptions reads the pagination, sort and filter query params shared
+// by the list endpoints. sort is a comma separated list of json field names,
+// a "-" prefix sorts that field descending.
+func parseListOptions(c *gin.Context, sortFields map[string]string) (repository.ListOptions, error) {
+	var opts repository.ListOptions
+	var err error
+
+	if opts.Limit, err = generic.CheckParamInt(c.Query("limit"), kERROR_MESSAGE_LIMIT); err != nil {
+		return opts, err
+	}
+	if opts.Limit < 0 || opts.Limit > kLIST_MAX_LIMIT {
+		return opts, errors.New(kERROR_MESSAGE_LIMIT + ", must be between 0 and " + strconv.Itoa(kLIST_MAX_LIMIT))
+	}
+
+	if opts.Offset, err = generic.CheckParamInt(c.Query("offset"), kERROR_MESSAGE_OFFSET); err != nil {
+		return opts, err
+	}
+	if opts.Offset < 0 {
+		return opts, errors.New(kERROR_MESSAGE_OFFSET)
+	}
+
+	if paramSort := c.Query("sort"); paramSort != "" {
+		for _, field := range strings.Split(paramSort, ",") {
+			sortField := repository.SortField{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(sortField.Field, "-") {
+				sortField.Field = strings.TrimPrefix(sortField.Field, "-")
+				sortField.Desc = true
+			}
+
+			if _, ok := sortFields[sortField.Field]; !ok {
+				return opts, errors.New(kERROR_MESSAGE_SORT + ", field " + sortField.Field + " not allowed")
+			}
+			opts.Sort = append(opts.Sort, sortField)
+		}
+	}
+
+	filter := &opts.Filter
+	filter.Name = strings.TrimSpace(c.Query("name"))
+
+	if filter.TmdbId, err = generic.CheckParamInt(c.Query("tmdb_id"), kERROR_MESSAGE_TMDBID); err != nil {
+		return opts, err
+	}
+	if filter.Season, err = generic.CheckParamInt(c.Query("season"), kERROR_MESSAGE_SEASON); err != nil {
+		return opts, err
+	}
+	if filter.GroupType, err = checkParamGroup(c.Query("group")); err != nil {
+		return opts, err
+	}
+	if filter.Status, err = checkParamStatus(c.Query("status")); err != nil {
+		return opts, err
+	}
+	if filter.Watched, err = generic.CheckParamBool(c.Query("watched"), kERROR_MESSAGE_WATCHED); err != nil {
+		return opts, err
+	}
+	if filter.AirDateFrom, err = generic.CheckParamDate(c.Query("air_date_from"), kERROR_MESSAGE_AIR_DATE); err != nil {
+		return opts, err
+	}
+	if filter.AirDateTo, err = generic.CheckParamDate(c.Query("air_date_to"), kERROR_MESSAGE_AIR_DATE); err != nil {
+		return opts, err
+	}
+
+	specials, err := parseSpecials(c)
+	if err != nil {
+		return opts, err
+	}
+	filter.ExcludeSpecials = !specials
+
+	return opts, nil
+}
+
+// setListHeaders reports the total rows matching the filter, so the body keeps
+// being a plain array.
+func setListHeaders(c *gin.Context, opts repository.ListOptions, total int64) {
+	c.Header(kHEADER_TOTAL_COUNT, strconv.FormatInt(total, 10))
+	c.Header(kHEADER_OFFSET, strconv.Itoa(opts.Offset))
+	if opts.Limit > 0 {
+		c.Header(kHEADER_LIMIT, strconv.Itoa(opts.Limit))
+	}
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/mergepatch"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// fields the database owns, a patch may repeat them but not change them
+	tvShowImmutableFields  = []string{"id", "tmdb_id", "created_at", "updated_at"}
+	episodeImmutableFields = []string{"id", "tmdb_id", "created_at", "updated_at"}
+)
+
+// applyMergePatch merges the request body into dest and answers the error
+// itself, returning false when the handler must stop. Plain application/json
+// bodies are read as merge patches too.
+func applyMergePatch(c *gin.Context, dest interface{}, immutable []string) bool {
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergepatch.ContentType && mediaType != gin.MIMEJSON) {
+			ResponseError(c, fmt.Errorf("content type must be %s", mergepatch.ContentType), http.StatusUnsupportedMediaType)
+			return false
+		}
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return false
+	}
+
+	err = mergepatch.Apply(dest, patch, immutable...)
+	var immutableErr *mergepatch.ImmutableError
+	if errors.As(err, &immutableErr) {
+		ResponseErrorUnprocessableEntity(c, err)
+		return false
+	}
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func SeasonListByTvShow(c *gin.Context) {
+	repo := getRepository(c)
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	seasons, err := repo.Seasons().FindByTmdbId(tvShow.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, seasons)
+}
+
+func SeasonListByNumber(c *gin.Context) {
+	repo := getRepository(c)
+
+	season, ok := findSeasonByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	respondJSON(c, http.StatusOK, season)
+}
+
+// SeasonCreate adds a season to the show of the url. The tmdb_id of the body
+// may be left out, it comes from the show.
+func SeasonCreate(c *gin.Context) {
+	repo := getRepository(c)
+	var season models.Season
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&season); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if season.TmdbId != 0 && season.TmdbId != tvShow.TmdbId {
+		ResponseErrorBadRequest(c, fmt.Errorf(kERROR_MESSAGE_KEY_URL, "tmdb_id"))
+		return
+	}
+	season.Id = 0
+	season.TmdbId = tvShow.TmdbId
+
+	if err := models.ValidSeason(&season); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	seasonExist, err := repo.Seasons().FindByKey(season.TmdbId, season.Number)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if seasonExist.Id > 0 {
+		ResponseErrorBadRequest(c, fmt.Errorf("Season %d of %s already exist", season.Number, tvShow.Name))
+		return
+	}
+
+	if err := repo.Seasons().Create(&season); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusCreated, season)
+}
+
+// SeasonEdit replaces the season of the url with the body. tmdb_id and number
+// may be left out of the body, they come from the url.
+func SeasonEdit(c *gin.Context) {
+	repo := getRepository(c)
+	var seasonUpdate models.Season
+
+	season, ok := findSeasonByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&seasonUpdate); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	for _, check := range []struct {
+		name  string
+		body  int
+		param int
+	}{
+		{"tmdb_id", seasonUpdate.TmdbId, season.TmdbId},
+		{"number", seasonUpdate.Number, season.Number},
+	} {
+		if check.body != 0 && check.body != check.param {
+			ResponseErrorBadRequest(c, fmt.Errorf(kERROR_MESSAGE_KEY_URL, check.name))
+			return
+		}
+	}
+
+	seasonUpdate.Id = season.Id
+	seasonUpdate.TmdbId = season.TmdbId
+	seasonUpdate.Number = season.Number
+	seasonUpdate.CreatedAt = season.CreatedAt
+
+	if err := models.ValidSeason(&seasonUpdate); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	if err := repo.Seasons().Save(&seasonUpdate); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, seasonUpdate)
+}
+
+// SeasonDelete removes the season only, its episodes are kept.
+func SeasonDelete(c *gin.Context) {
+	repo := getRepository(c)
+
+	season, ok := findSeasonByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	if err := repo.Seasons().Delete(season.Id); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Season deleted",
+	})
+}
+
+// findSeasonByParam loads the season of the show id and season number params,
+// answering the error itself when the handler must stop.
+func findSeasonByParam(c *gin.Context, repo repository.Repository) (models.Season, bool) {
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return models.Season{}, false
+	}
+
+	number, err := generic.CheckParamInt(c.Params.ByName("season"), kERROR_MESSAGE_SEASON)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return models.Season{}, false
+	}
+
+	season, err := repo.Seasons().FindByKey(tvShow.TmdbId, number)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return season, false
+	}
+
+	if season.Id == 0 {
+		ResponseErrorNotFound(c, models.Season{})
+		return season, false
+	}
+	return season, true
+}
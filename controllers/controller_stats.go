@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Stats returns the viewing statistics of the user. The from and to query
+// params keep only the watches between them.
+func Stats(c *gin.Context) {
+	var watched repository.StatsRange
+	var err error
+
+	if watched.From, err = generic.CheckParamDate(c.Query("from"), kERROR_MESSAGE_FROM); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+	if watched.To, err = generic.CheckParamDate(c.Query("to"), kERROR_MESSAGE_TO); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+	if watched.From > 0 && watched.To > 0 && watched.To < watched.From {
+		ResponseErrorBadRequest(c, errors.New(kERROR_MESSAGE_TO_BEFORE_FROM))
+		return
+	}
+
+	stats, err := getRepository(c).Stats().Summary(watched)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	for index := range stats.ByGroup {
+		stats.ByGroup[index].Name = models.GroupType(stats.ByGroup[index].Value).String()
+	}
+	for index := range stats.ByStatus {
+		stats.ByStatus[index].Name = models.TvShowStatus(stats.ByStatus[index].Value).String()
+	}
+
+	respondJSON(c, http.StatusOK, stats)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+	"github.com/feealc/tvshows-backend-go/tvsync"
+	"github.com/gin-gonic/gin"
+)
+
+const kCONTEXT_KEY_TMDB_CLIENT = "tmdb_client"
+
+// UseTmdbClient injects the client used by the handlers that read from TMDB.
+func UseTmdbClient(client *tmdb.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(kCONTEXT_KEY_TMDB_CLIENT, client)
+		c.Next()
+	}
+}
+
+func getTmdbClient(c *gin.Context) *tmdb.Client {
+	return c.MustGet(kCONTEXT_KEY_TMDB_CLIENT).(*tmdb.Client)
+}
+
+func ResponseErrorTmdb(c *gin.Context, err error) {
+	if errors.Is(err, tmdb.ErrNotFound) {
+		ResponseError(c, fmt.Errorf("TvShow %s", tmdb.ErrNotFound.Error()), http.StatusNotFound)
+		return
+	}
+	ResponseErrorBadGateway(c, err)
+}
+
+func TvShowImport(c *gin.Context) {
+	repo := getRepository(c)
+	paramTmdbId := c.Params.ByName("tmdbid")
+	paramGroup := c.DefaultQuery("group", "1")
+
+	tmdbId, err := generic.CheckParamInt(paramTmdbId, kERROR_MESSAGE_TMDBID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	groupType, err := checkParamGroup(paramGroup)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	tvShowExist, err := repo.TvShows().FindByTmdbId(tmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if tvShowExist.Id > 0 {
+		ResponseErrorBadRequest(c, fmt.Errorf("TvShow %s (TMDB ID %d) already exist", tvShowExist.Name, tvShowExist.TmdbId))
+		return
+	}
+
+	remoteTvShow, remoteSeasons, err := getTmdbClient(c).GetTvShowWithSeasons(tmdbId)
+	if err != nil {
+		ResponseErrorTmdb(c, err)
+		return
+	}
+
+	tvShow := remoteTvShow.ToModel(groupType)
+	if err := models.ValidTvShow(&tvShow); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	seasons, err := tmdb.ToSeasons(tvShow.TmdbId, remoteTvShow.Seasons)
+	if err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	episodes, err := tmdb.ToEpisodes(tvShow.TmdbId, remoteSeasons)
+	if err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	err = repo.Transaction(func(tx repository.Repository) error {
+		if err := tx.TvShows().Create(&tvShow); err != nil {
+			return err
+		}
+		for index := range seasons {
+			if err := tx.Seasons().Create(&seasons[index]); err != nil {
+				return err
+			}
+		}
+		return tx.Episodes().CreateMany(episodes)
+	})
+	if errors.Is(err, repository.ErrDuplicatedKey) {
+		ResponseErrorBadRequest(c, fmt.Errorf("TvShow name %s already exist", tvShow.Name))
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if seasons == nil {
+		seasons = []models.Season{}
+	}
+	if episodes == nil {
+		episodes = []models.Episode{}
+	}
+
+	respondJSON(c, http.StatusCreated, gin.H{
+		"tv_show":  tvShow,
+		"seasons":  seasons,
+		"episodes": episodes,
+	})
+}
+
+func TvShowSync(c *gin.Context) {
+	repo := getRepository(c)
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if tvShow.Id == 0 {
+		ResponseErrorNotFound(c, models.TvShow{})
+		return
+	}
+
+	report, err := tvsync.SyncTvShow(repo, getTmdbClient(c), tvShow)
+	if err != nil {
+		ResponseErrorTmdb(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, report)
+}
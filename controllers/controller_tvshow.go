@@ -1,48 +1,67 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/feealc/tvshows-backend-go/database"
 	"github.com/feealc/tvshows-backend-go/generic"
 	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"github.com/gin-gonic/gin"
 )
 
 func TvShowListAll(c *gin.Context) {
-	var tvShows []models.TvShow
+	repo := getRepository(c)
 
-	if result := database.DB.Order("name").Find(&tvShows); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	opts, err := parseListOptions(c, repository.TvShowSortFields)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
 		return
 	}
 
-	for index, tvShow := range tvShows {
-		var episodes []models.Episode
+	tvShows, total, err := repo.TvShows().List(opts)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
 
-		if result := database.DB.Where("tmdb_id = ? and watched = false", tvShow.TmdbId).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
-			return
-		}
+	summaries, err := repo.Episodes().UnwatchedSummaries(!opts.Filter.ExcludeSpecials)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
 
-		if len(episodes) > 0 {
-			ep := episodes[0]
-			tvShow.UnwatchedSeason = ep.Season
-			tvShow.UnwatchedEpisode = ep.Episode
-			tvShow.UnwatchedCount = len(episodes) - 1
-		}
-		tvShows[index] = tvShow
+	for index := range tvShows {
+		setUnwatched(&tvShows[index], summaries)
 	}
 
-	c.JSON(http.StatusOK, tvShows)
+	setListHeaders(c, opts, total)
+	respondJSON(c, http.StatusOK, tvShows)
+}
+
+// setUnwatched points the show to its next unwatched episode and counts the
+// unwatched ones after it.
+func setUnwatched(tvShow *models.TvShow, summaries map[int]repository.UnwatchedSummary) {
+	if summary, ok := summaries[tvShow.TmdbId]; ok {
+		tvShow.UnwatchedSeason = summary.Season
+		tvShow.UnwatchedEpisode = summary.Episode
+		tvShow.UnwatchedCount = summary.Total - 1
+	}
 }
 
 func TvShowListAllUnwatchedEpisodes(c *gin.Context) {
-	var tvShows []models.TvShow
+	repo := getRepository(c)
 
-	if result := database.DB.Order("name").Find(&tvShows); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	specials, err := parseSpecials(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	tvShows, err := repo.TvShows().FindAll()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -52,22 +71,31 @@ func TvShowListAllUnwatchedEpisodes(c *gin.Context) {
 	}
 	var response []TvShowEpisodes
 
-	for _, tvShow := range tvShows {
-		var episodes []models.Episode
+	unwatched, err := repo.Episodes().FindUnwatched(specials)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
 
-		if result := database.DB.Where("tmdb_id = ? and watched = false", tvShow.TmdbId).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
-			return
+	episodesByTmdbId := make(map[int][]models.Episode)
+	for _, episode := range unwatched {
+		episodesByTmdbId[episode.TmdbId] = append(episodesByTmdbId[episode.TmdbId], episode)
+	}
+
+	for _, tvShow := range tvShows {
+		episodes, ok := episodesByTmdbId[tvShow.TmdbId]
+		if !ok {
+			episodes = []models.Episode{}
 		}
 
 		response = append(response, TvShowEpisodes{TvShow: tvShow, Episodes: episodes})
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondJSON(c, http.StatusOK, response)
 }
 
 func TvShowListById(c *gin.Context) {
-	var tvShow models.TvShow
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 
 	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
@@ -76,8 +104,9 @@ func TvShowListById(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Find(&tvShow, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -86,11 +115,16 @@ func TvShowListById(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, tvShow)
+	if respondNotModified(c, tvShow.ETag()) {
+		return
+	}
+
+	respondJSON(c, http.StatusOK, tvShow)
 }
 
 func TvShowCreate(c *gin.Context) {
 	var tvShow models.TvShow
+	repo := getRepository(c)
 
 	if err := c.ShouldBindJSON(&tvShow); err != nil {
 		ResponseErrorBadRequest(c, err)
@@ -102,9 +136,9 @@ func TvShowCreate(c *gin.Context) {
 		return
 	}
 
-	var tvShowExist models.TvShow
-	if result := database.DB.Where(&models.TvShow{TmdbId: tvShow.TmdbId}).Find(&tvShowExist); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShowExist, err := repo.TvShows().FindByTmdbId(tvShow.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -113,22 +147,39 @@ func TvShowCreate(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Create(&tvShow); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	err = repo.TvShows().Create(&tvShow)
+	if errors.Is(err, repository.ErrDuplicatedKey) {
+		ResponseErrorBadRequest(c, fmt.Errorf("TvShow name %s already exist", tvShow.Name))
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, tvShow)
+	respondJSON(c, http.StatusCreated, tvShow)
 }
 
 func TvShowCreateBatch(c *gin.Context) {
 	var tvShows []models.TvShow
+	repo := getRepository(c)
+
+	partial, err := parseBatchMode(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
 
 	if err := c.ShouldBindJSON(&tvShows); err != nil {
 		ResponseErrorBadRequest(c, err)
 		return
 	}
 
+	if partial {
+		tvShowCreateBatchPartial(c, repo, tvShows)
+		return
+	}
+
 	for index, tvShow := range tvShows {
 		if err := models.ValidTvShow(&tvShow); err != nil {
 			ResponseErrorUnprocessableEntity(c, err)
@@ -136,9 +187,9 @@ func TvShowCreateBatch(c *gin.Context) {
 		}
 		tvShows[index] = tvShow
 
-		var tvShowExist models.TvShow
-		if result := database.DB.Where(&models.TvShow{TmdbId: tvShow.TmdbId}).Find(&tvShowExist); result.Error != nil {
-			ResponseErrorInternalServerError(c, result.Error)
+		tvShowExist, err := repo.TvShows().FindByTmdbId(tvShow.TmdbId)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
 			return
 		}
 
@@ -148,16 +199,89 @@ func TvShowCreateBatch(c *gin.Context) {
 		}
 	}
 
-	if result := database.DB.Create(&tvShows); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if err := repo.TvShows().CreateMany(tvShows); err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, tvShows)
+	respondJSON(c, http.StatusCreated, tvShows)
 }
 
-func TvShowEdit(c *gin.Context) {
+// TvShowUpsert creates the show of the url tmdb id or updates it in place, so
+// import scripts can run again without failing on existing shows.
+func TvShowUpsert(c *gin.Context) {
 	var tvShow models.TvShow
+	repo := getRepository(c)
+	paramTmdbId := c.Params.ByName("tmdbid")
+
+	tmdbId, err := generic.CheckParamInt(paramTmdbId, kERROR_MESSAGE_TMDBID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&tvShow); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if tvShow.TmdbId != 0 && tvShow.TmdbId != tmdbId {
+		ResponseErrorBadRequest(c, fmt.Errorf(kERROR_MESSAGE_KEY_URL, "tmdb_id"))
+		return
+	}
+	tvShow.TmdbId = tmdbId
+
+	if err := models.ValidTvShow(&tvShow); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	var tvShowExist models.TvShow
+	if c.GetHeader(kHEADER_IF_MATCH) != "" {
+		tvShowExist, err = repo.TvShows().FindByTmdbId(tmdbId)
+		if err != nil {
+			ResponseErrorInternalServerError(c, err)
+			return
+		}
+
+		etag := ""
+		if tvShowExist.Id > 0 {
+			etag = tvShowExist.ETag()
+		}
+		if !checkIfMatch(c, models.TvShow{}, etag) {
+			return
+		}
+	}
+
+	var created bool
+	if writeIfMatch(c) {
+		err = repo.TvShows().UpsertIfUnchanged(&tvShow, tvShowExist.UpdatedAt)
+	} else {
+		created, err = repo.TvShows().Upsert(&tvShow)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.TvShow{})
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicatedKey) {
+		ResponseErrorBadRequest(c, fmt.Errorf("TvShow name %s already exist", tvShow.Name))
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.Header(kHEADER_ETAG, tvShow.ETag())
+	respondJSON(c, status, tvShow)
+}
+
+func TvShowEdit(c *gin.Context) {
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 
 	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
@@ -166,8 +290,9 @@ func TvShowEdit(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Find(&tvShow, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -176,6 +301,11 @@ func TvShowEdit(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, models.TvShow{}, tvShow.ETag()) {
+		return
+	}
+	updatedAt := tvShow.UpdatedAt
+
 	if err := c.ShouldBindJSON(&tvShow); err != nil {
 		ResponseErrorUnprocessableEntity(c, err)
 		return
@@ -186,16 +316,85 @@ func TvShowEdit(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Save(&tvShow); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if writeIfMatch(c) {
+		err = repo.TvShows().SaveIfUnchanged(&tvShow, updatedAt)
+	} else {
+		err = repo.TvShows().Save(&tvShow)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.TvShow{})
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.Header(kHEADER_ETAG, tvShow.ETag())
+	respondJSON(c, http.StatusOK, tvShow)
+}
+
+// TvShowPatch edits the show with a JSON Merge Patch, so fields left out of
+// the body keep their value and fields set to null are cleared.
+func TvShowPatch(c *gin.Context) {
+	repo := getRepository(c)
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if tvShow.Id == 0 {
+		ResponseErrorNotFound(c, models.TvShow{})
+		return
+	}
+
+	if !checkIfMatch(c, models.TvShow{}, tvShow.ETag()) {
+		return
+	}
+	updatedAt := tvShow.UpdatedAt
+
+	if !applyMergePatch(c, &tvShow, tvShowImmutableFields) {
+		return
+	}
+
+	if err := models.ValidTvShow(&tvShow); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	if writeIfMatch(c) {
+		err = repo.TvShows().SaveIfUnchanged(&tvShow, updatedAt)
+	} else {
+		err = repo.TvShows().Save(&tvShow)
+	}
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.TvShow{})
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicatedKey) {
+		ResponseErrorBadRequest(c, fmt.Errorf("TvShow name %s already exist", tvShow.Name))
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, tvShow)
+	c.Header(kHEADER_ETAG, tvShow.ETag())
+	respondJSON(c, http.StatusOK, tvShow)
 }
 
 func TvShowDelete(c *gin.Context) {
-	var tvShow models.TvShow
+	repo := getRepository(c)
 	paramId := c.Params.ByName("id")
 
 	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
@@ -204,8 +403,9 @@ func TvShowDelete(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Find(&tvShow, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
@@ -214,27 +414,45 @@ func TvShowDelete(c *gin.Context) {
 		return
 	}
 
-	if result := database.DB.Delete(&tvShow, id); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	if !checkIfMatch(c, models.TvShow{}, tvShow.ETag()) {
 		return
 	}
 
-	var episodesToDelete []models.Episode
-	if result := database.DB.Where(&models.Episode{TmdbId: tvShow.TmdbId}).Delete(&episodesToDelete); result.Error != nil {
-		ResponseErrorInternalServerError(c, result.Error)
+	err = repo.Transaction(func(tx repository.Repository) error {
+		if writeIfMatch(c) {
+			if err := tx.TvShows().DeleteIfUnchanged(id, tvShow.UpdatedAt); err != nil {
+				return err
+			}
+		} else if err := tx.TvShows().Delete(id); err != nil {
+			return err
+		}
+
+		if _, err := tx.Seasons().DeleteByTmdbId(tvShow.TmdbId); err != nil {
+			return err
+		}
+
+		_, err := tx.Episodes().DeleteByTmdbId(tvShow.TmdbId)
+		return err
+	})
+	if errors.Is(err, repository.ErrStale) {
+		respondPreconditionFailed(c, models.TvShow{})
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respondJSON(c, http.StatusOK, gin.H{
 		"message": "TvShow and episodes deleted successfully",
 	})
 }
 
 func TvShowTruncate(c *gin.Context) {
-	response, err := Truncate(c, models.TvShow{})
+	response, err := Truncate(c, models.TvShow{}, getRepository(c).TvShows())
 	if err != nil {
 		ResponseErrorInternalServerError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	respondJSON(c, http.StatusOK, response)
 }
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/gin-gonic/gin"
+)
+
+// UpNext is the next episode to watch of a show.
+type UpNext struct {
+	TvShow          models.TvShow  `json:"tv_show"`
+	Episode         models.Episode `json:"episode"`
+	LastWatchedDate models.Date    `json:"last_watched_date"`
+}
+
+// UpNextList returns the next aired unwatched episode of every show, the
+// shows watched most recently first. The group query param filters the shows
+// by GroupType, and specials=false leaves out the episodes of season 0.
+func UpNextList(c *gin.Context) {
+	repo := getRepository(c)
+
+	groupType, err := checkParamGroup(c.Query("group"))
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	specials, err := parseSpecials(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	today, err := getToday(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	rows, err := repo.Episodes().FindUpNext(groupType, today, specials)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	tvShows, err := repo.TvShows().FindAll()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	tvShowsByTmdbId := make(map[int]models.TvShow, len(tvShows))
+	for _, tvShow := range tvShows {
+		tvShowsByTmdbId[tvShow.TmdbId] = tvShow
+	}
+
+	response := []UpNext{}
+	for _, row := range rows {
+		response = append(response, UpNext{
+			TvShow:          tvShowsByTmdbId[row.Episode.TmdbId],
+			Episode:         row.Episode,
+			LastWatchedDate: row.LastWatchedDate,
+		})
+	}
+
+	respondJSON(c, http.StatusOK, response)
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/feealc/tvshows-backend-go/auth"
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kERROR_MESSAGE_LOGIN    = "username or password invalid"
+	kERROR_MESSAGE_OWN_ROLE = "cannot change your own role"
+)
+
+var errRegistrationClosed = errors.New("registration closed, an admin creates the accounts")
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// UserRegister creates an account. Anyone registers the first one, which
+// becomes the admin, the next ones are created by an admin.
+func UserRegister(c *gin.Context) {
+	repo := getRepository(c)
+	var credentials Credentials
+
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	user := models.User{Username: credentials.Username, Role: models.UserRoleViewer}
+	if err := models.ValidUser(&user); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(credentials.Password)
+	if err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+	user.PasswordHash = passwordHash
+
+	userExist, err := repo.Users().FindByUsername(user.Username)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if userExist.Id > 0 {
+		ResponseErrorBadRequest(c, fmt.Errorf("User %s already exist", userExist.Username))
+		return
+	}
+
+	var admin bool
+	if current, ok := c.Get(kCONTEXT_KEY_USER); ok {
+		currentUser := current.(models.User)
+		admin = currentUser.HasRole(models.UserRoleAdmin)
+	}
+
+	err = repo.Transaction(func(tx repository.Repository) error {
+		count, err := tx.Users().CountForUpdate()
+		if err != nil {
+			return err
+		}
+
+		if count > 0 && !admin {
+			return errRegistrationClosed
+		}
+
+		// the first account administers the others and keeps what was
+		// watched before accounts existed
+		if count == 0 {
+			user.Role = models.UserRoleAdmin
+		}
+
+		if err := tx.Users().Create(&user); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			_, err = tx.WatchStates().AdoptLegacy(user.Id)
+		}
+		return err
+	})
+	if errors.Is(err, errRegistrationClosed) {
+		ResponseErrorForbidden(c, err)
+		return
+	}
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func UserLogin(c *gin.Context) {
+	var credentials Credentials
+
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	user, err := getRepository(c).Users().FindByUsername(strings.ToLower(strings.TrimSpace(credentials.Username)))
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if user.Id == 0 || !auth.CheckPassword(user.PasswordHash, credentials.Password) {
+		ResponseErrorUnauthorized(c, errors.New(kERROR_MESSAGE_LOGIN))
+		return
+	}
+
+	token, expiresAt, err := getSigner(c).Sign(user.Id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expiresAt,
+		"user":       user,
+	})
+}
+
+func UserMe(c *gin.Context) {
+	c.JSON(http.StatusOK, getCurrentUser(c))
+}
+
+type UserRole struct {
+	Role string `json:"role"`
+}
+
+// UserEditRole changes the role of another user. Admins cannot change their
+// own role, so there is always an admin left.
+func UserEditRole(c *gin.Context) {
+	repo := getRepository(c)
+	paramId := c.Params.ByName("id")
+
+	id, err := generic.CheckParamInt(paramId, kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	if id == getCurrentUser(c).Id {
+		ResponseErrorForbidden(c, errors.New(kERROR_MESSAGE_OWN_ROLE))
+		return
+	}
+
+	userUpdate, err := repo.Users().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if userUpdate.Id == 0 {
+		ResponseErrorNotFound(c, models.User{})
+		return
+	}
+
+	var role UserRole
+	if err := c.ShouldBindJSON(&role); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	userUpdate.Role = role.Role
+	if err := models.ValidUser(&userUpdate); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return
+	}
+
+	if err := repo.Users().Save(&userUpdate); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userUpdate)
+}
+
+// UserCalendarTokenCreate gives the user a new calendar token, revoking the
+// previous one. Like API keys, the token is only shown in this answer.
+func UserCalendarTokenCreate(c *gin.Context) {
+	user := getCurrentUser(c)
+
+	token, tokenHash, err := auth.NewCalendarToken()
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	user.CalendarTokenHash = tokenHash
+	if err := getRepository(c).Users().Save(&user); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token": token,
+		"url":   kCALENDAR_ICS_PATH + "?token=" + token,
+	})
+}
+
+func UserCalendarTokenDelete(c *gin.Context) {
+	user := getCurrentUser(c)
+
+	if user.CalendarTokenHash == "" {
+		ResponseError(c, errors.New("calendar token not found"), http.StatusNotFound)
+		return
+	}
+
+	user.CalendarTokenHash = ""
+	if err := getRepository(c).Users().Save(&user); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "calendar token revoked",
+	})
+}
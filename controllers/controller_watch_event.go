@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/generic"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kERROR_MESSAGE_EVENT_ID     = "event id invalid"
+	kERROR_MESSAGE_EVENT_FUTURE = "WatchedAt: cannot be in the future"
+)
+
+func WatchEventListByEpisode(c *gin.Context) {
+	repo := getRepository(c)
+
+	episode, ok := findEpisodeByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	events, err := repo.WatchEvents().FindByEpisodeId(episode.Id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, events)
+}
+
+func WatchEventListByTvShow(c *gin.Context) {
+	repo := getRepository(c)
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	events, err := repo.WatchEvents().FindByTmdbId(tvShow.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, events)
+}
+
+// WatchEventCreateForEpisode records a watch of the episode, now unless the
+// body sets watched_at, and marks it watched.
+func WatchEventCreateForEpisode(c *gin.Context) {
+	repo := getRepository(c)
+
+	episode, ok := findEpisodeByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	event, ok := bindWatchEvent(c)
+	if !ok {
+		return
+	}
+
+	createWatchEvent(c, repo, episode, event)
+}
+
+// WatchEventCreateForTvShow records a watch of the show episode set by the
+// season and episode of the body.
+func WatchEventCreateForTvShow(c *gin.Context) {
+	repo := getRepository(c)
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	event, ok := bindWatchEvent(c)
+	if !ok {
+		return
+	}
+
+	episode, err := repo.Episodes().FindByKey(tvShow.TmdbId, event.Season, event.Episode)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if episode.Id == 0 {
+		ResponseErrorNotFound(c, models.Episode{})
+		return
+	}
+
+	createWatchEvent(c, repo, episode, event)
+}
+
+func WatchEventDeleteForEpisode(c *gin.Context) {
+	repo := getRepository(c)
+
+	episode, ok := findEpisodeByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	deleteWatchEvent(c, repo, func(event models.WatchEvent) bool {
+		return event.EpisodeId == episode.Id
+	})
+}
+
+func WatchEventDeleteForTvShow(c *gin.Context) {
+	repo := getRepository(c)
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	deleteWatchEvent(c, repo, func(event models.WatchEvent) bool {
+		return event.TmdbId == tvShow.TmdbId
+	})
+}
+
+// bindWatchEvent reads the optional body of a new event. Without watched_at
+// the event happens now.
+func bindWatchEvent(c *gin.Context) (models.WatchEvent, bool) {
+	var event models.WatchEvent
+
+	if err := c.ShouldBindJSON(&event); err != nil && !errors.Is(err, io.EOF) {
+		ResponseErrorBadRequest(c, err)
+		return event, false
+	}
+
+	now := time.Now()
+	if event.WatchedAt.IsZero() {
+		event.WatchedAt = now
+	}
+
+	if event.WatchedAt.After(now.Add(time.Minute)) {
+		ResponseErrorUnprocessableEntity(c, errors.New(kERROR_MESSAGE_EVENT_FUTURE))
+		return event, false
+	}
+
+	if err := models.ValidWatchEvent(&event); err != nil {
+		ResponseErrorUnprocessableEntity(c, err)
+		return event, false
+	}
+	return event, true
+}
+
+func createWatchEvent(c *gin.Context, repo repository.Repository, episode models.Episode, event models.WatchEvent) {
+	event.EpisodeId = episode.Id
+	if err := repo.WatchEvents().Create(&event); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	event.TmdbId, event.Season, event.Episode = episode.TmdbId, episode.Season, episode.Episode
+	respondJSON(c, http.StatusCreated, event)
+}
+
+// deleteWatchEvent removes the event of the eventid param when it belongs to
+// the resource of the url.
+func deleteWatchEvent(c *gin.Context, repo repository.Repository, belongs func(event models.WatchEvent) bool) {
+	eventId, err := generic.CheckParamInt(c.Params.ByName("eventid"), kERROR_MESSAGE_EVENT_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	event, err := repo.WatchEvents().FindById(eventId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if event.Id == 0 || !belongs(event) {
+		ResponseErrorNotFound(c, models.WatchEvent{})
+		return
+	}
+
+	if _, err := repo.WatchEvents().Delete(event.Id); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "WatchEvent deleted",
+	})
+}
+
+// findEpisodeByParam loads the episode of the id param, answering the error
+// itself when the handler must stop.
+func findEpisodeByParam(c *gin.Context, repo repository.Repository) (models.Episode, bool) {
+	id, err := generic.CheckParamInt(c.Params.ByName("id"), kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return models.Episode{}, false
+	}
+
+	episode, err := repo.Episodes().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return episode, false
+	}
+
+	if episode.Id == 0 {
+		ResponseErrorNotFound(c, models.Episode{})
+		return episode, false
+	}
+	return episode, true
+}
+
+// findTvShowByParam loads the show of the id param, answering the error
+// itself when the handler must stop.
+func findTvShowByParam(c *gin.Context, repo repository.Repository) (models.TvShow, bool) {
+	id, err := generic.CheckParamInt(c.Params.ByName("id"), kERROR_MESSAGE_ID)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return models.TvShow{}, false
+	}
+
+	tvShow, err := repo.TvShows().FindById(id)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return tvShow, false
+	}
+
+	if tvShow.Id == 0 {
+		ResponseErrorNotFound(c, models.TvShow{})
+		return tvShow, false
+	}
+	return tvShow, true
+}
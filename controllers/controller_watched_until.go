@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/gin-gonic/gin"
+)
+
+// WatchedUntil points to the last episode watched. With UnwatchLater the
+// episodes after it are unmarked too.
+type WatchedUntil struct {
+	Season       int  `json:"season"`
+	Episode      int  `json:"episode"`
+	UnwatchLater bool `json:"unwatch_later"`
+}
+
+// TvShowWatchedUntil marks every episode of the show up to the one of the
+// body as watched in one write, and returns the show with its unwatched
+// pointer moved. Episodes already watched keep their watched date.
+func TvShowWatchedUntil(c *gin.Context) {
+	var until WatchedUntil
+	repo := getRepository(c)
+
+	tvShow, ok := findTvShowByParam(c, repo)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&until); err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	today, err := getToday(c)
+	if err != nil {
+		ResponseErrorBadRequest(c, err)
+		return
+	}
+
+	last, err := repo.Episodes().FindByKey(tvShow.TmdbId, until.Season, until.Episode)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	if last.Id == 0 {
+		ResponseErrorNotFound(c, models.Episode{})
+		return
+	}
+
+	episodes, err := repo.Episodes().FindByTmdbId(tvShow.TmdbId)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	// episodes come in watching order, the specials of season 0 last, so the
+	// earlier ones are the ones up to the last watched
+	var changed []models.Episode
+	passed := false
+	for _, episode := range episodes {
+		earlier := !passed
+		passed = passed || episode.Id == last.Id
+		switch {
+		case earlier && !episode.Watched:
+			episode.Watched = true
+			episode.WatchedDate = today
+		case !earlier && episode.Watched && until.UnwatchLater:
+			// like unmarking one episode, the date of the last watch stays
+			episode.Watched = false
+		default:
+			continue
+		}
+		changed = append(changed, episode)
+	}
+
+	if err := repo.Episodes().SaveWatchStates(changed); err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	summaries, err := repo.Episodes().UnwatchedSummaries(true)
+	if err != nil {
+		ResponseErrorInternalServerError(c, err)
+		return
+	}
+
+	setUnwatched(&tvShow, summaries)
+	respondJSON(c, http.StatusOK, tvShow)
+}
@@ -0,0 +1,217 @@
+package csvio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+// The headers use the json field names. Columns computed per request, like
+// the unwatched ones of TvShow, are left out.
+var (
+	TvShowHeader  = []string{"id", "tmdb_id", "name", "overview", "group", "status", "created_at", "updated_at"}
+	EpisodeHeader = []string{"id", "tmdb_id", "season", "episode", "name", "overview", "air_date", "watched", "watched_date", "created_at", "updated_at"}
+)
+
+// columns written on export but never read back, the database owns them
+var ignoredColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+// Record is one data row of an uploaded file, keyed by column name. Err is
+// set when the row cannot be read.
+type Record struct {
+	Line   int
+	Values map[string]string
+	Err    error
+}
+
+func WriteTvShows(w io.Writer, tvShows []models.TvShow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(TvShowHeader); err != nil {
+		return err
+	}
+	for _, t := range tvShows {
+		err := writer.Write([]string{
+			strconv.Itoa(t.Id),
+			strconv.Itoa(t.TmdbId),
+			t.Name,
+			t.Overview,
+			t.GroupType.String(),
+			t.Status.String(),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteEpisodes(w io.Writer, episodes []models.Episode) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(EpisodeHeader); err != nil {
+		return err
+	}
+	for _, e := range episodes {
+		err := writer.Write([]string{
+			strconv.Itoa(e.Id),
+			strconv.Itoa(e.TmdbId),
+			strconv.Itoa(e.Season),
+			strconv.Itoa(e.Episode),
+			e.Name,
+			e.Overview,
+			e.AirDate.String(),
+			strconv.FormatBool(e.Watched),
+			e.WatchedDate.String(),
+			formatTime(e.CreatedAt),
+			formatTime(e.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Read parses a file whose header holds columns of header in any order and
+// must include every required column. Problems with the file itself, not
+// with one of its rows, are returned as an error.
+func Read(r io.Reader, header []string, required []string) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(header))
+	for _, column := range header {
+		known[column] = true
+	}
+	present := make(map[string]bool, len(columns))
+	for index, column := range columns {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if !known[column] {
+			return nil, fmt.Errorf("column %s unknown", column)
+		}
+		columns[index] = column
+		present[column] = true
+	}
+	for _, column := range required {
+		if !present[column] {
+			return nil, fmt.Errorf("column %s required", column)
+		}
+	}
+
+	records := []Record{}
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		record := Record{Line: line, Values: make(map[string]string, len(columns))}
+		if len(values) != len(columns) {
+			record.Err = fmt.Errorf("expected %d columns, got %d", len(columns), len(values))
+		}
+		for index, column := range columns {
+			if index < len(values) && !ignoredColumns[column] {
+				record.Values[column] = strings.TrimSpace(values[index])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Int reads an integer column, an empty value counts as 0.
+func (r Record) Int(column string) (int, error) {
+	value := r.Values[column]
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s invalid", column)
+	}
+	return number, nil
+}
+
+// Date reads a date column, ISO-8601 or the YYYYMMDD of older files. An
+// empty value is no date.
+func (r Record) Date(column string) (models.Date, error) {
+	date, err := models.ParseDate(r.Values[column])
+	if err != nil {
+		return 0, fmt.Errorf("%s invalid", column)
+	}
+	return date, nil
+}
+
+// GroupType reads a GroupType column, its name or number. An empty value
+// counts as 0.
+func (r Record) GroupType(column string) (models.GroupType, error) {
+	value := r.Values[column]
+	if value == "" {
+		return 0, nil
+	}
+	group, err := models.ParseGroupType(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s invalid", column)
+	}
+	return group, nil
+}
+
+// TvShowStatus reads a TvShowStatus column, its name or number. An empty
+// value counts as 0.
+func (r Record) TvShowStatus(column string) (models.TvShowStatus, error) {
+	value := r.Values[column]
+	if value == "" {
+		return 0, nil
+	}
+	status, err := models.ParseTvShowStatus(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s invalid", column)
+	}
+	return status, nil
+}
+
+// Bool reads a boolean column, an empty value counts as false.
+func (r Record) Bool(column string) (bool, error) {
+	value := r.Values[column]
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s invalid", column)
+	}
+	return b, nil
+}
+
+// Has tells if the file has the column, so an update only changes the
+// fields the file carries.
+func (r Record) Has(column string) bool {
+	_, ok := r.Values[column]
+	return ok
+}
@@ -0,0 +1,242 @@
+package csvio
+
+import (
+	"fmt"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+)
+
+var (
+	TvShowRequired  = []string{"tmdb_id", "name"}
+	EpisodeRequired = []string{"tmdb_id", "season", "episode", "name"}
+)
+
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report tells what an import wrote and why the other rows were skipped.
+type Report struct {
+	Total   int        `json:"total"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Errors  []RowError `json:"errors"`
+}
+
+func (r *Report) skip(record Record, err error) {
+	r.Errors = append(r.Errors, RowError{Line: record.Line, Error: err.Error()})
+}
+
+// ImportTvShows creates the shows of records, or updates the ones already
+// stored with the same tmdb_id. Invalid rows are reported and skipped, the
+// valid ones are written in one transaction.
+func ImportTvShows(repo repository.Repository, records []Record) (Report, error) {
+	report := Report{Total: len(records), Errors: []RowError{}}
+
+	existing, err := repo.TvShows().FindAll()
+	if err != nil {
+		return report, err
+	}
+
+	byTmdbId := make(map[int]models.TvShow, len(existing))
+	byName := make(map[string]int, len(existing))
+	for _, tvShow := range existing {
+		byTmdbId[tvShow.TmdbId] = tvShow
+		byName[tvShow.Name] = tvShow.TmdbId
+	}
+
+	var toCreate, toSave []models.TvShow
+	imported := make(map[int]bool)
+	for _, record := range records {
+		tvShow, err := tvShowFromRecord(record, byTmdbId)
+		if err == nil && imported[tvShow.TmdbId] {
+			err = fmt.Errorf("TMDB ID %d repeated", tvShow.TmdbId)
+		}
+		if tmdbId, ok := byName[tvShow.Name]; err == nil && ok && tmdbId != tvShow.TmdbId {
+			err = fmt.Errorf("TvShow name %s already exist", tvShow.Name)
+		}
+		if err != nil {
+			report.skip(record, err)
+			continue
+		}
+
+		imported[tvShow.TmdbId] = true
+		byName[tvShow.Name] = tvShow.TmdbId
+		if tvShow.Id > 0 {
+			toSave = append(toSave, tvShow)
+		} else {
+			toCreate = append(toCreate, tvShow)
+		}
+	}
+
+	err = repo.Transaction(func(tx repository.Repository) error {
+		if err := tx.TvShows().CreateMany(toCreate); err != nil {
+			return err
+		}
+		for index := range toSave {
+			if err := tx.TvShows().Save(&toSave[index]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Created, report.Updated = len(toCreate), len(toSave)
+	return report, nil
+}
+
+func tvShowFromRecord(record Record, stored map[int]models.TvShow) (models.TvShow, error) {
+	if record.Err != nil {
+		return models.TvShow{}, record.Err
+	}
+
+	tmdbId, err := record.Int("tmdb_id")
+	if err != nil {
+		return models.TvShow{}, err
+	}
+
+	tvShow, ok := stored[tmdbId]
+	if !ok {
+		tvShow = models.TvShow{TmdbId: tmdbId}
+	}
+
+	if record.Has("name") {
+		tvShow.Name = record.Values["name"]
+	}
+	if record.Has("overview") {
+		tvShow.Overview = record.Values["overview"]
+	}
+	if record.Has("group") {
+		if tvShow.GroupType, err = record.GroupType("group"); err != nil {
+			return tvShow, err
+		}
+	}
+	if record.Has("status") {
+		if tvShow.Status, err = record.TvShowStatus("status"); err != nil {
+			return tvShow, err
+		}
+	}
+
+	return tvShow, models.ValidTvShow(&tvShow)
+}
+
+// ImportEpisodes creates the episodes of records, or updates the ones
+// already stored with the same (tmdb_id, season, episode). The watched
+// columns go to the watch state of the repository user. Invalid rows are
+// reported and skipped, the valid ones are written in one transaction.
+func ImportEpisodes(repo repository.Repository, records []Record) (Report, error) {
+	report := Report{Total: len(records), Errors: []RowError{}}
+
+	tvShows, err := repo.TvShows().FindAll()
+	if err != nil {
+		return report, err
+	}
+
+	tmdbIds := make(map[int]bool, len(tvShows))
+	for _, tvShow := range tvShows {
+		tmdbIds[tvShow.TmdbId] = true
+	}
+
+	existing, err := repo.Episodes().FindAll()
+	if err != nil {
+		return report, err
+	}
+
+	byKey := make(map[string]models.Episode, len(existing))
+	for _, episode := range existing {
+		byKey[episodeKey(episode)] = episode
+	}
+
+	var toCreate, toSave []models.Episode
+	imported := make(map[string]bool)
+	for _, record := range records {
+		episode, err := episodeFromRecord(record, byKey)
+		if err == nil && !tmdbIds[episode.TmdbId] {
+			err = fmt.Errorf("TvShow (TMDB ID %d) not found", episode.TmdbId)
+		}
+		if err == nil && imported[episodeKey(episode)] {
+			err = fmt.Errorf("episode %dx%02d repeated", episode.Season, episode.Episode)
+		}
+		if err != nil {
+			report.skip(record, err)
+			continue
+		}
+
+		imported[episodeKey(episode)] = true
+		if episode.Id > 0 {
+			toSave = append(toSave, episode)
+		} else {
+			toCreate = append(toCreate, episode)
+		}
+	}
+
+	err = repo.Transaction(func(tx repository.Repository) error {
+		if err := tx.Episodes().CreateMany(toCreate); err != nil {
+			return err
+		}
+		return tx.Episodes().SaveMany(toSave)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Created, report.Updated = len(toCreate), len(toSave)
+	return report, nil
+}
+
+func episodeFromRecord(record Record, stored map[string]models.Episode) (models.Episode, error) {
+	var key models.Episode
+	var err error
+
+	if record.Err != nil {
+		return key, record.Err
+	}
+
+	if key.TmdbId, err = record.Int("tmdb_id"); err != nil {
+		return key, err
+	}
+	if key.Season, err = record.Int("season"); err != nil {
+		return key, err
+	}
+	if key.Episode, err = record.Int("episode"); err != nil {
+		return key, err
+	}
+
+	episode, ok := stored[episodeKey(key)]
+	if !ok {
+		episode = key
+	}
+
+	if record.Has("name") {
+		episode.Name = record.Values["name"]
+	}
+	if record.Has("overview") {
+		episode.Overview = record.Values["overview"]
+	}
+	if record.Has("air_date") {
+		if episode.AirDate, err = record.Date("air_date"); err != nil {
+			return episode, err
+		}
+	}
+	if record.Has("watched") {
+		if episode.Watched, err = record.Bool("watched"); err != nil {
+			return episode, err
+		}
+	}
+	if record.Has("watched_date") {
+		if episode.WatchedDate, err = record.Date("watched_date"); err != nil {
+			return episode, err
+		}
+	}
+
+	return episode, models.ValidEpisode(&episode)
+}
+
+func episodeKey(episode models.Episode) string {
+	return fmt.Sprintf("%d/%dx%d", episode.TmdbId, episode.Season, episode.Episode)
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
 	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -49,8 +51,89 @@ func ConnectDataBase() {
 	sqlDB = nil
 	log.Println("Conectado com sucesso usando GORM")
 
+	keepDates()
+
 	DB.AutoMigrate(&models.TvShow{})
 	DB.AutoMigrate(&models.Episode{})
+	DB.AutoMigrate(&models.Season{})
+	DB.AutoMigrate(&models.User{})
+	DB.AutoMigrate(&models.ApiKey{})
+	DB.AutoMigrate(&models.WatchState{})
+	DB.AutoMigrate(&models.WatchEvent{})
+
+	keepLegacyWatchColumns()
+	keepAnAdmin()
+	keepWatchHistory()
+}
+
+// keepDates converts the YYYYMMDD integer columns of the dates to DATE, 0
+// becoming NULL. It runs before AutoMigrate, which can not cast them.
+func keepDates() {
+	migrator := DB.Migrator()
+	for table, column := range map[string]string{"episodes": "air_date", "seasons": "air_date", "watch_states": "watched_date"} {
+		if !migrator.HasColumn(table, column) {
+			continue
+		}
+		columnTypes, err := migrator.ColumnTypes(table)
+		if err != nil {
+			log.Println(err.Error())
+			log.Panic("Erro ao migrar datas de " + table)
+		}
+		for _, columnType := range columnTypes {
+			if columnType.Name() != column || !strings.Contains(strings.ToLower(columnType.DatabaseTypeName()), "int") {
+				continue
+			}
+			sql := fmt.Sprintf("alter table %s alter column %s type date using case when %s > 0 then to_date(%s::text, 'YYYYMMDD') end", table, column, column, column)
+			if err := DB.Exec(sql).Error; err != nil {
+				log.Println(err.Error())
+				log.Panic("Erro ao migrar datas de " + table)
+			}
+		}
+	}
+}
+
+// keepWatchHistory gives the watch states saved before the watch history
+// existed their first watch event.
+func keepWatchHistory() {
+	recorded, err := repository.NewGormRepository(DB).WatchStates().BackfillEvents()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	if recorded > 0 {
+		log.Printf("%d watch events recorded from the watch states", recorded)
+	}
+}
+
+// keepAnAdmin promotes the oldest user when there is no admin, which is the
+// case for the users registered before roles existed.
+func keepAnAdmin() {
+	var admins int64
+	if err := DB.Model(&models.User{}).Where("role = ?", models.UserRoleAdmin).Count(&admins).Error; err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	if admins == 0 {
+		DB.Model(&models.User{}).Where("id = (select min(id) from users)").Update("role", models.UserRoleAdmin)
+	}
+}
+
+// keepLegacyWatchColumns renames the watched columns episodes had before
+// accounts existed, so they stop shadowing the watch state of each user. The
+// first user to register adopts them (see WatchStateRepository.AdoptLegacy).
+func keepLegacyWatchColumns() {
+	migrator := DB.Migrator()
+	for _, column := range []string{"watched", "watched_date"} {
+		if !migrator.HasColumn(&models.Episode{}, column) {
+			continue
+		}
+		if err := migrator.RenameColumn(&models.Episode{}, column, "legacy_"+column); err != nil {
+			log.Println(err.Error())
+			log.Panic("Erro ao migrar colunas de episodes")
+		}
+	}
 }
 
 func buildConnectionString() string {
@@ -5,7 +5,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
-	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
 )
 
 func CheckParamInt(param, message string) (valueConverted int, err error) {
@@ -19,13 +20,32 @@ func CheckParamInt(param, message string) (valueConverted int, err error) {
 	return valueConverted, nil
 }
 
-func GetCurrentDate() int {
-	dateInt, _ := strconv.Atoi(time.Now().Format("20060102"))
-	return dateInt
-}
-
 func GetStructName(st interface{}) string {
 	name := reflect.TypeOf(st).String()
 
 	return strings.Replace(name, "models.", "", 1)
 }
+
+func CheckParamBool(param, message string) (*bool, error) {
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseBool(param)
+	if err != nil {
+		return nil, errors.New(message)
+	}
+
+	return &value, nil
+}
+
+// CheckParamDate converts a YYYY-MM-DD param, or the YYYYMMDD of the int
+// API, returning 0 when it is empty.
+func CheckParamDate(param, message string) (models.Date, error) {
+	value, err := models.ParseDate(param)
+	if err != nil {
+		return 0, errors.New(message)
+	}
+
+	return value, nil
+}
@@ -52,6 +52,7 @@ exec_tests()
 
     # Go
     clear
+    export TEST_DATABASE="postgres"
     go test -v ./tests -count=1
 }
 
@@ -0,0 +1,96 @@
+// Package ical writes iCalendar (RFC 5545) feeds of all-day events.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	kLINE_MAX_OCTETS = 75
+	kDATE_FORMAT     = "20060102"
+	kSTAMP_FORMAT    = "20060102T150405Z"
+)
+
+// Event is an all-day event on Date. UID must stay the same every time the
+// feed is rendered, calendar apps use it to update the event in place.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Stamp       time.Time
+}
+
+// Calendar is a feed of events. ProdId names the application producing it.
+type Calendar struct {
+	ProdId string
+	Name   string
+	Events []Event
+}
+
+// Write renders the calendar with CRLF line endings, escaping the text values
+// and folding the lines longer than 75 octets.
+func (c Calendar) Write(w io.Writer) error {
+	var lines []string
+	lines = append(lines,
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:"+escape(c.ProdId),
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	)
+	if c.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escape(c.Name))
+	}
+
+	for _, event := range c.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escape(event.UID),
+			"DTSTAMP:"+event.Stamp.UTC().Format(kSTAMP_FORMAT),
+			"DTSTART;VALUE=DATE:"+event.Date.Format(kDATE_FORMAT),
+			"DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format(kDATE_FORMAT),
+			"SUMMARY:"+escape(event.Summary),
+		)
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(event.Description))
+		}
+		lines = append(lines, "TRANSP:TRANSPARENT", "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := fmt.Fprint(w, fold(line), "\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// fold splits line every 75 octets, never inside a UTF-8 character. The
+// continuation lines start with a space.
+func fold(line string) string {
+	var folded strings.Builder
+	size := 0
+	for _, r := range line {
+		length := len(string(r))
+		if size+length > kLINE_MAX_OCTETS {
+			folded.WriteString("\r\n ")
+			size = 1
+		}
+		folded.WriteRune(r)
+		size += length
+	}
+	return folded.String()
+}
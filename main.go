@@ -1,12 +1,26 @@
 package main
 
 import (
+	// the time zones of the X-Time-Zone header, the image has no tzdata
+	_ "time/tzdata"
+
+	"github.com/feealc/tvshows-backend-go/auth"
 	"github.com/feealc/tvshows-backend-go/database"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"github.com/feealc/tvshows-backend-go/routes"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+	"github.com/feealc/tvshows-backend-go/tvsync"
 )
 
 func main() {
 	database.ConnectDataBase()
 
-	routes.HandleRequests()
+	repo := repository.NewGormRepository(database.DB)
+	tmdbClient := tmdb.NewClientFromEnv()
+	signer := auth.NewSignerFromEnv()
+
+	stopSync := tvsync.StartWorker(repo, tmdbClient, tvsync.IntervalFromEnv())
+	defer stopSync()
+
+	routes.HandleRequests(repo, tmdbClient, signer)
 }
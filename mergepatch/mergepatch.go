@@ -0,0 +1,93 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7396), where a
+// member set to null removes the field and an omitted member keeps it.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const ContentType = "application/merge-patch+json"
+
+var ErrNotObject = errors.New("merge patch must be a json object")
+
+// ImmutableError reports a patch changing a field that cannot be edited.
+type ImmutableError struct {
+	Field string
+}
+
+func (e *ImmutableError) Error() string {
+	return fmt.Sprintf("%s cannot be changed", e.Field)
+}
+
+// Merge applies patch to target following RFC 7396. Objects merge member by
+// member, any other patch value replaces the target one.
+func Merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = Merge(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// Apply merges the patch document into the json form of the struct pointed
+// to by dest, then decodes the result back into dest. Fields removed by the
+// patch end up with their zero value. Fields named in immutable may be sent
+// only with their current value, and unknown fields are rejected.
+func Apply(dest interface{}, patch []byte, immutable ...string) error {
+	var patchDocument interface{}
+	if err := json.Unmarshal(patch, &patchDocument); err != nil {
+		return err
+	}
+	if _, ok := patchDocument.(map[string]interface{}); !ok {
+		return ErrNotObject
+	}
+
+	current, err := json.Marshal(dest)
+	if err != nil {
+		return err
+	}
+
+	var original, document map[string]interface{}
+	if err := json.Unmarshal(current, &original); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(current, &document); err != nil {
+		return err
+	}
+
+	merged := Merge(document, patchDocument).(map[string]interface{})
+	for _, field := range immutable {
+		if !reflect.DeepEqual(original[field], merged[field]) {
+			return &ImmutableError{Field: field}
+		}
+	}
+
+	result, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(dest).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dest)
+}
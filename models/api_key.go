@@ -0,0 +1,34 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+// ApiKey is a long-lived credential of one user. Only the hash of the key is
+// stored, Prefix keeps its first characters so the owner can tell keys apart.
+type ApiKey struct {
+	Id        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId    int       `json:"user_id" gorm:"index"`
+	Name      string    `json:"name" validate:"nonzero,max=80"`
+	Prefix    string    `json:"prefix"`
+	KeyHash   string    `json:"-" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (k *ApiKey) TrimSpace() {
+	k.Name = strings.TrimSpace(k.Name)
+}
+
+// Validator
+
+func ValidApiKey(apiKey *ApiKey) error {
+	apiKey.TrimSpace()
+	if err := validator.Validate(apiKey); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, without time of day or zone. It is kept as
+// YYYYMMDD so days compare in order and 0 is no date, and stored in a DATE
+// column, NULL for no date. JSON reads and writes it as an ISO-8601 date,
+// null for no date, and still reads the YYYYMMDD numbers of the int API.
+type Date int
+
+func NewDate(year int, month time.Month, day int) Date {
+	return Date(year*10000 + int(month)*100 + day)
+}
+
+// DateOf returns the day of t in the location of t.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return NewDate(year, month, day)
+}
+
+// Today returns the current day in loc.
+func Today(loc *time.Location) Date {
+	return DateOf(time.Now().In(loc))
+}
+
+// ParseDate reads an ISO-8601 date or a YYYYMMDD number. An empty string,
+// or the 0 of the int API, is no date.
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+
+	layout := dateLayout
+	if !strings.Contains(s, "-") {
+		layout = "20060102"
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return 0, errors.New("date invalid, must be YYYY-MM-DD")
+	}
+	return DateOf(t), nil
+}
+
+func (d Date) IsZero() bool {
+	return d == 0
+}
+
+// Time returns the midnight UTC of d.
+func (d Date) Time() time.Time {
+	return time.Date(int(d)/10000, time.Month(int(d)/100%100), int(d)%100, 0, 0, 0, 0, time.UTC)
+}
+
+// AddDays returns the day days after d, or before it when days is negative.
+func (d Date) AddDays(days int) Date {
+	return DateOf(d.Time().AddDate(0, 0, days))
+}
+
+// String is the ISO-8601 date, empty for no date.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Time().Format(dateLayout)
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Time(), nil
+}
+
+func (d *Date) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*d = 0
+	case time.Time:
+		*d = DateOf(value)
+	case int64:
+		// the YYYYMMDD integer columns before the migration to DATE
+		*d = Date(value)
+	case string:
+		return d.scanText(value)
+	case []byte:
+		return d.scanText(string(value))
+	default:
+		return fmt.Errorf("can not scan %T into a date", src)
+	}
+	return nil
+}
+
+func (d *Date) scanText(text string) error {
+	date, err := ParseDate(text)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads an ISO-8601 date or, for clients of the int API, a
+// YYYYMMDD number, checked by the validator like before. null is no date, the
+// way MarshalJSON writes it.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = 0
+		return nil
+	}
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*d = Date(number)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return errors.New("date invalid, must be YYYY-MM-DD")
+	}
+	return d.scanText(text)
+}
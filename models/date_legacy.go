@@ -0,0 +1,180 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MarshalLegacyJSON marshals v like json.Marshal, but writes its dates as the
+// YYYYMMDD numbers of the int API, 0 for no date, for the clients not moved
+// to ISO-8601 yet.
+func MarshalLegacyJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	tree, err := decodeJSONTree(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(legacyDates(reflect.ValueOf(v), tree))
+}
+
+// jsonObject is a decoded JSON object that keeps the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for index, member := range o {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(member.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// replace sets the value of key, when o has it, to the one replace returns.
+func (o jsonObject) replace(key string, replace func(value interface{}) interface{}) {
+	for index := range o {
+		if o[index].Key == key {
+			o[index].Value = replace(o[index].Value)
+			return
+		}
+	}
+}
+
+// decodeJSONTree reads the next value of decoder, its objects as jsonObject.
+func decodeJSONTree(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := jsonObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONTree(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonMember{Key: key.(string), Value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSONTree(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return token, nil
+}
+
+var dateType = reflect.TypeOf(Date(0))
+
+// legacyDates walks value along tree, its decoded JSON, and puts the numbers
+// in place of the dates it finds.
+func legacyDates(value reflect.Value, tree interface{}) interface{} {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return tree
+		}
+		value = value.Elem()
+	}
+
+	if value.Type() == dateType {
+		return json.Number(strconv.Itoa(int(value.Int())))
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if object, ok := tree.(jsonObject); ok {
+			legacyStructDates(value, object)
+		}
+	case reflect.Slice, reflect.Array:
+		if list, ok := tree.([]interface{}); ok {
+			for index := range list {
+				if index < value.Len() {
+					list[index] = legacyDates(value.Index(index), list[index])
+				}
+			}
+		}
+	case reflect.Map:
+		if object, ok := tree.(jsonObject); ok && value.Type().Key().Kind() == reflect.String {
+			for _, key := range value.MapKeys() {
+				object.replace(key.String(), func(item interface{}) interface{} {
+					return legacyDates(value.MapIndex(key), item)
+				})
+			}
+		}
+	}
+	return tree
+}
+
+// legacyStructDates replaces the dates of the fields of value in object, the
+// fields of embedded structs included, like json.Marshal flattens them.
+func legacyStructDates(value reflect.Value, object jsonObject) {
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(index)
+		if field.Anonymous && name == "" {
+			for fieldValue.Kind() == reflect.Pointer && !fieldValue.IsNil() {
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				legacyStructDates(fieldValue, object)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		object.replace(name, func(item interface{}) interface{} {
+			return legacyDates(fieldValue, item)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// GroupType is the list a show is kept in.
+type GroupType int
+
+const (
+	GroupTypeWatching GroupType = 1
+	GroupTypePlanned  GroupType = 2
+	GroupTypeArchived GroupType = 3
+)
+
+// TvShowStatus is the production status of a show.
+type TvShowStatus int
+
+const (
+	TvShowStatusReturning    TvShowStatus = 1
+	TvShowStatusEnded        TvShowStatus = 2
+	TvShowStatusCanceled     TvShowStatus = 3
+	TvShowStatusInProduction TvShowStatus = 4
+	TvShowStatusPilot        TvShowStatus = 5
+)
+
+// EnumValue is one value of an enum with its name. The enums are stored as
+// their Value and read and written as their Name in JSON.
+type EnumValue struct {
+	Value int    `json:"value"`
+	Name  string `json:"name"`
+}
+
+type enum []EnumValue
+
+var groupTypes = enum{
+	{int(GroupTypeWatching), "watching"},
+	{int(GroupTypePlanned), "planned"},
+	{int(GroupTypeArchived), "archived"},
+}
+
+var tvShowStatuses = enum{
+	{int(TvShowStatusReturning), "returning"},
+	{int(TvShowStatusEnded), "ended"},
+	{int(TvShowStatusCanceled), "canceled"},
+	{int(TvShowStatusInProduction), "in_production"},
+	{int(TvShowStatusPilot), "pilot"},
+}
+
+// GroupTypes lists every GroupType in order.
+func GroupTypes() []EnumValue {
+	return append([]EnumValue(nil), groupTypes...)
+}
+
+// TvShowStatuses lists every TvShowStatus in order.
+func TvShowStatuses() []EnumValue {
+	return append([]EnumValue(nil), tvShowStatuses...)
+}
+
+func (e enum) name(value int) (string, bool) {
+	for _, v := range e {
+		if v.Value == value {
+			return v.Name, true
+		}
+	}
+	return "", false
+}
+
+// parse reads a name, in any case, or a number. Numbers are not checked, the
+// validator does it like it does for the legacy int input.
+func (e enum) parse(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	for _, v := range e {
+		if strings.EqualFold(v.Name, s) {
+			return v.Value, nil
+		}
+	}
+	if number, err := strconv.Atoi(s); err == nil {
+		return number, nil
+	}
+	return 0, errors.New("value must be " + e.names())
+}
+
+// names is the list of names for error messages, "a, b or c".
+func (e enum) names() string {
+	names := make([]string, len(e))
+	for index, v := range e {
+		names[index] = v.Name
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// marshal writes the name of value, or the number when it has none so an
+// invalid value is not lost.
+func (e enum) marshal(value int) ([]byte, error) {
+	if name, ok := e.name(value); ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(value)
+}
+
+// unmarshal reads a name or, for clients of the int API, a number. null
+// keeps current, like it does for the other fields.
+func (e enum) unmarshal(data []byte, current int) (int, error) {
+	if string(data) == "null" {
+		return current, nil
+	}
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		return number, nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return 0, errors.New("value must be " + e.names())
+	}
+	return e.parse(name)
+}
+
+func (g GroupType) String() string {
+	if name, ok := groupTypes.name(int(g)); ok {
+		return name
+	}
+	return strconv.Itoa(int(g))
+}
+
+// Value stores the number, the drivers would otherwise write the String of
+// a fmt.Stringer.
+func (g GroupType) Value() (driver.Value, error) {
+	return int64(g), nil
+}
+
+func (g GroupType) MarshalJSON() ([]byte, error) {
+	return groupTypes.marshal(int(g))
+}
+
+func (g *GroupType) UnmarshalJSON(data []byte) error {
+	value, err := groupTypes.unmarshal(data, int(*g))
+	if err != nil {
+		return errors.New("group invalid, " + err.Error())
+	}
+	*g = GroupType(value)
+	return nil
+}
+
+// ParseGroupType reads a GroupType name or number.
+func ParseGroupType(s string) (GroupType, error) {
+	value, err := groupTypes.parse(s)
+	return GroupType(value), err
+}
+
+func (s TvShowStatus) String() string {
+	if name, ok := tvShowStatuses.name(int(s)); ok {
+		return name
+	}
+	return strconv.Itoa(int(s))
+}
+
+// Value stores the number, the drivers would otherwise write the String of
+// a fmt.Stringer.
+func (s TvShowStatus) Value() (driver.Value, error) {
+	return int64(s), nil
+}
+
+func (s TvShowStatus) MarshalJSON() ([]byte, error) {
+	return tvShowStatuses.marshal(int(s))
+}
+
+func (s *TvShowStatus) UnmarshalJSON(data []byte) error {
+	value, err := tvShowStatuses.unmarshal(data, int(*s))
+	if err != nil {
+		return errors.New("status invalid, " + err.Error())
+	}
+	*s = TvShowStatus(value)
+	return nil
+}
+
+// ParseTvShowStatus reads a TvShowStatus name or number.
+func ParseTvShowStatus(s string) (TvShowStatus, error) {
+	value, err := tvShowStatuses.parse(s)
+	return TvShowStatus(value), err
+}
@@ -11,16 +11,19 @@ import (
 	"gopkg.in/validator.v2"
 )
 
+// Episode is shared catalog data. Watched and WatchedDate are not stored on
+// the row, they come from the WatchState of the user making the request.
+// Specials live in season 0, and some seasons number an episode 0.
 type Episode struct {
 	Id          int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	TmdbId      int       `json:"tmdb_id" gorm:"index:idx_episode,unique" validate:"nonzero"`
-	Season      int       `json:"season" gorm:"index:idx_episode,unique" validate:"nonzero"`
-	Episode     int       `json:"episode" gorm:"index:idx_episode,unique" validate:"nonzero"`
+	Season      int       `json:"season" gorm:"index:idx_episode,unique" validate:"min=0"`
+	Episode     int       `json:"episode" gorm:"index:idx_episode,unique" validate:"min=0"`
 	Name        string    `json:"name" validate:"min=2,max=80"`
 	Overview    string    `json:"overview"`
-	AirDate     int       `json:"air_date" validate:"checkDate"`
-	Watched     bool      `json:"watched"`
-	WatchedDate int       `json:"watched_date" validate:"checkDate"`
+	AirDate     Date      `json:"air_date" gorm:"type:date" validate:"checkDate"`
+	Watched     bool      `json:"watched" gorm:"->;-:migration"`
+	WatchedDate Date      `json:"watched_date" gorm:"->;-:migration" validate:"checkDate"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -30,8 +33,13 @@ func (e *Episode) TrimSpace() {
 	e.Overview = strings.TrimSpace(e.Overview)
 }
 
+// ETag changes whenever the episode is saved or its watch state changes.
+func (e *Episode) ETag() string {
+	return entityTag(e.Id, e.UpdatedAt.UnixMicro(), e.Watched, e.WatchedDate)
+}
+
 func (e *Episode) Dump() {
-	fmt.Printf("Id=%d TmdbId=%d T%dE%d Name=[%s] Overview=[%s] AirDate=%d Watched=%t WatchedDate=%d Cr=%s Up=%s \n",
+	fmt.Printf("Id=%d TmdbId=%d T%dE%d Name=[%s] Overview=[%s] AirDate=%s Watched=%t WatchedDate=%s Cr=%s Up=%s \n",
 		e.Id,
 		e.TmdbId,
 		e.Season,
@@ -47,7 +55,7 @@ func (e *Episode) Dump() {
 }
 
 func (e *Episode) DumpShort() {
-	fmt.Printf("Id=%d TmdbId=%d T%dE%d Name=[%s] AirDate=%d Watched=%t WatchedDate=%d \n",
+	fmt.Printf("Id=%d TmdbId=%d T%dE%d Name=[%s] AirDate=%s Watched=%t WatchedDate=%s \n",
 		e.Id,
 		e.TmdbId,
 		e.Season,
@@ -79,7 +87,7 @@ func checkDate(v interface{}, _ string) error {
 	}
 
 	if len(strconv.Itoa(value)) != 8 || value < 0 {
-		return errors.New("date must be YYYYMMDD")
+		return errors.New("date must be YYYY-MM-DD")
 	}
 
 	if _, err := time.Parse("20060102", strconv.Itoa(value)); err != nil {
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// entityTag hashes the values a representation depends on into a quoted
+// strong entity tag.
+func entityTag(values ...interface{}) string {
+	sum := sha1.Sum([]byte(fmt.Sprint(values...)))
+	return fmt.Sprintf(`"%x"`, sum[:12])
+}
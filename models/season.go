@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+// Season describes one season of a show as announced on TMDB, so it is known
+// before any of its episodes is. Number 0 holds the specials. EpisodeCount is
+// the number of episodes announced, not the ones stored.
+type Season struct {
+	Id           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TmdbId       int       `json:"tmdb_id" gorm:"index:idx_season,unique" validate:"nonzero"`
+	Number       int       `json:"number" gorm:"index:idx_season,unique" validate:"min=0"`
+	Name         string    `json:"name" validate:"max=80"`
+	Overview     string    `json:"overview"`
+	AirDate      Date      `json:"air_date" gorm:"type:date" validate:"checkDate"`
+	EpisodeCount int       `json:"episode_count" validate:"min=0"`
+	PosterPath   string    `json:"poster_path" validate:"max=200"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (s *Season) TrimSpace() {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Overview = strings.TrimSpace(s.Overview)
+	s.PosterPath = strings.TrimSpace(s.PosterPath)
+}
+
+func (s *Season) DumpShort() {
+	fmt.Printf("Id=%d TmdbId=%d Number=%d Name=[%s] AirDate=%s EpisodeCount=%d \n",
+		s.Id,
+		s.TmdbId,
+		s.Number,
+		s.Name,
+		s.AirDate,
+		s.EpisodeCount,
+	)
+}
+
+// Validator
+
+func ValidSeason(season *Season) error {
+	season.TrimSpace()
+	validator.SetValidationFunc("checkDate", checkDate)
+	if err := validator.Validate(season); err != nil {
+		return err
+	}
+	return nil
+}
@@ -11,17 +11,17 @@ import (
 )
 
 type TvShow struct {
-	Id               int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	TmdbId           int       `json:"tmdb_id" gorm:"uniqueIndex" validate:"nonzero"`
-	Name             string    `json:"name" gorm:"uniqueIndex" validate:"min=2,max=80"`
-	Overview         string    `json:"overview"`
-	GroupType        int       `json:"group" validate:"checkGroup"`
-	Status           int       `json:"status" validate:"checkStatus"`
-	UnwatchedSeason  int       `json:"unwatched_season"`
-	UnwatchedEpisode int       `json:"unwatched_episode"`
-	UnwatchedCount   int       `json:"unwatched_count"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	Id               int          `json:"id" gorm:"primaryKey;autoIncrement"`
+	TmdbId           int          `json:"tmdb_id" gorm:"uniqueIndex" validate:"nonzero"`
+	Name             string       `json:"name" gorm:"uniqueIndex" validate:"min=2,max=80"`
+	Overview         string       `json:"overview"`
+	GroupType        GroupType    `json:"group" validate:"checkGroup"`
+	Status           TvShowStatus `json:"status" validate:"checkStatus"`
+	UnwatchedSeason  int          `json:"unwatched_season"`
+	UnwatchedEpisode int          `json:"unwatched_episode"`
+	UnwatchedCount   int          `json:"unwatched_count"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
 }
 
 func (t *TvShow) TrimSpace() {
@@ -29,8 +29,14 @@ func (t *TvShow) TrimSpace() {
 	t.Overview = strings.TrimSpace(t.Overview)
 }
 
+// ETag changes whenever the show is saved. UpdatedAt is cut to microseconds,
+// the precision postgres keeps.
+func (t *TvShow) ETag() string {
+	return entityTag(t.Id, t.UpdatedAt.UnixMicro())
+}
+
 func (t *TvShow) Dump() {
-	fmt.Printf("Id=%d TmdbId=%d Name=[%s] Overview=[%s] Group=%d Status=%d UnwSeason=%d UnwEp=%d UnwCount=%d Cr=%s Up=%s \n",
+	fmt.Printf("Id=%d TmdbId=%d Name=[%s] Overview=[%s] Group=%s Status=%s UnwSeason=%d UnwEp=%d UnwCount=%d Cr=%s Up=%s \n",
 		t.Id,
 		t.TmdbId,
 		t.Name,
@@ -46,7 +52,7 @@ func (t *TvShow) Dump() {
 }
 
 func (t *TvShow) DumpShort() {
-	fmt.Printf("Id=%d TmdbId=%d Name=[%s] Group=%d Status=%d \n",
+	fmt.Printf("Id=%d TmdbId=%d Name=[%s] Group=%s Status=%s \n",
 		t.Id,
 		t.TmdbId,
 		t.Name,
@@ -69,16 +75,16 @@ func ValidTvShow(tvShow *TvShow) error {
 
 func checkGroup(v interface{}, _ string) error {
 	st := reflect.ValueOf(v)
-	if st.Int() != 1 && st.Int() != 2 && st.Int() != 3 {
-		return errors.New("value must be 1, 2 or 3")
+	if _, ok := groupTypes.name(int(st.Int())); !ok {
+		return errors.New("value must be " + groupTypes.names())
 	}
 	return nil
 }
 
 func checkStatus(v interface{}, _ string) error {
 	st := reflect.ValueOf(v)
-	if st.Int() != 1 && st.Int() != 2 && st.Int() != 3 && st.Int() != 4 && st.Int() != 5 {
-		return errors.New("value must be 1, 2, 3, 4 or 5")
+	if _, ok := tvShowStatuses.name(int(st.Int())); !ok {
+		return errors.New("value must be " + tvShowStatuses.names())
 	}
 	return nil
 }
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+// Roles are ordered, each one can do everything the ones below it can.
+const (
+	UserRoleViewer = "viewer"
+	UserRoleEditor = "editor"
+	UserRoleAdmin  = "admin"
+)
+
+var userRoleRanks = map[string]int{
+	UserRoleViewer: 1,
+	UserRoleEditor: 2,
+	UserRoleAdmin:  3,
+}
+
+type User struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username     string `json:"username" gorm:"uniqueIndex" validate:"min=3,max=40"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role" gorm:"default:viewer" validate:"checkRole"`
+	// CalendarTokenHash is the hash of the token of the calendar feed URL,
+	// empty when the user has none.
+	CalendarTokenHash string    `json:"-" gorm:"index"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// HasRole tells if the user has role or a role above it.
+func (u *User) HasRole(role string) bool {
+	return userRoleRanks[u.Role] >= userRoleRanks[role]
+}
+
+func (u *User) TrimSpace() {
+	u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+}
+
+func (u *User) DumpShort() {
+	fmt.Printf("Id=%d Username=[%s] Role=[%s] \n",
+		u.Id,
+		u.Username,
+		u.Role,
+	)
+}
+
+// Validator
+
+func ValidUser(user *User) error {
+	user.TrimSpace()
+	validator.SetValidationFunc("checkRole", checkRole)
+	if err := validator.Validate(user); err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkRole(v interface{}, _ string) error {
+	st := reflect.ValueOf(v)
+	if _, ok := userRoleRanks[st.String()]; !ok {
+		return errors.New("value must be admin, editor or viewer")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+// WatchEvent records one time a user watched an episode, so rewatches keep
+// their history. The WatchState of the episode follows the latest event.
+// TmdbId, Season and Episode are read from the episode.
+type WatchEvent struct {
+	Id        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId    int       `json:"user_id" gorm:"index:idx_watch_event"`
+	EpisodeId int       `json:"episode_id" gorm:"index:idx_watch_event"`
+	TmdbId    int       `json:"tmdb_id" gorm:"->;-:migration"`
+	Season    int       `json:"season" gorm:"->;-:migration"`
+	Episode   int       `json:"episode" gorm:"->;-:migration"`
+	WatchedAt time.Time `json:"watched_at"`
+	Note      string    `json:"note" validate:"max=500"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validator
+
+func ValidWatchEvent(event *WatchEvent) error {
+	return validator.Validate(event)
+}
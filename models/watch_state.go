@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// WatchState keeps what one user watched. Episode.Watched and
+// Episode.WatchedDate are filled from it for the user making the request.
+type WatchState struct {
+	Id          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserId      int       `json:"user_id" gorm:"index:idx_watch_state,unique"`
+	EpisodeId   int       `json:"episode_id" gorm:"index:idx_watch_state,unique"`
+	Watched     bool      `json:"watched"`
+	WatchedDate Date      `json:"watched_date" gorm:"type:date"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
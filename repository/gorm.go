@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The episode and season orders put the specials of season 0 after the other
+// seasons.
+const (
+	kTVSHOW_ORDER_BY_NAME                   = "name"
+	kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE = "tmdb_id, season = 0, season, episode"
+	kEPISODE_ORDER_BY_SEASON_EPISODE        = "season = 0, season, episode"
+	kSEASON_ORDER_BY_NUMBER                 = "number = 0, number"
+)
+
+type gormRepository struct {
+	db     *gorm.DB
+	userId int
+}
+
+func NewGormRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) TvShows() TvShowRepository {
+	return &gormTvShowRepository{db: r.db, userId: r.userId}
+}
+
+func (r *gormRepository) Episodes() EpisodeRepository {
+	return &gormEpisodeRepository{db: r.db, userId: r.userId}
+}
+
+func (r *gormRepository) Seasons() SeasonRepository {
+	return &gormSeasonRepository{db: r.db}
+}
+
+func (r *gormRepository) Users() UserRepository {
+	return &gormUserRepository{db: r.db}
+}
+
+func (r *gormRepository) ApiKeys() ApiKeyRepository {
+	return &gormApiKeyRepository{db: r.db}
+}
+
+func (r *gormRepository) WatchStates() WatchStateRepository {
+	return &gormWatchStateRepository{db: r.db}
+}
+
+func (r *gormRepository) WatchEvents() WatchEventRepository {
+	return &gormWatchEventRepository{db: r.db, userId: r.userId}
+}
+
+func (r *gormRepository) Stats() StatsRepository {
+	return &gormStatsRepository{db: r.db, userId: r.userId}
+}
+
+func (r *gormRepository) ForUser(userId int) Repository {
+	return &gormRepository{db: r.db, userId: userId}
+}
+
+func (r *gormRepository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormRepository{db: tx, userId: r.userId})
+	})
+}
+
+func gormTruncate(db *gorm.DB, table interface{}, drop bool) error {
+	if !drop {
+		return db.Where("id is not null").Delete(table).Error
+	}
+
+	if err := db.Migrator().DropTable(table); err != nil {
+		return err
+	}
+
+	return db.Migrator().CreateTable(table)
+}
+
+// gormCheckUnchanged turns a conditional write that matched no row into
+// ErrStale.
+func gormCheckUnchanged(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+// gormDuplicatedKey turns a unique index violation into ErrDuplicatedKey, the
+// error the memory repository returns for it.
+func gormDuplicatedKey(db *gorm.DB, err error) error {
+	translator, ok := db.Dialector.(gorm.ErrorTranslator)
+	if err != nil && ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return ErrDuplicatedKey
+	}
+	return err
+}
+
+func gormOrder(db *gorm.DB, sort []SortField, columns map[string]string) *gorm.DB {
+	for _, field := range sort {
+		if column, ok := columns[field.Field]; ok {
+			if column == "season" {
+				// the specials of season 0 go after the other seasons
+				db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: "season = 0", Raw: true}, Desc: field.Desc})
+			}
+			if column == "air_date" || column == "watched_date" {
+				// no date goes first, like the 0 of the memory repository
+				db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: column + " is not null", Raw: true}, Desc: field.Desc})
+			}
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: field.Desc})
+		}
+	}
+	// keep pages stable when the sort fields tie
+	return db.Order("id")
+}
+
+func gormPage(db *gorm.DB, opts ListOptions) *gorm.DB {
+	if opts.Limit > 0 {
+		db = db.Limit(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		db = db.Offset(opts.Offset)
+	}
+	return db
+}
+
+func gormNameLike(name string) string {
+	return "%" + strings.ToLower(name) + "%"
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+)
+
+type gormApiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormApiKeyRepository) FindByUserId(userId int) ([]models.ApiKey, error) {
+	var apiKeys []models.ApiKey
+	result := r.db.Where("user_id = ?", userId).Order("id").Find(&apiKeys)
+	return apiKeys, result.Error
+}
+
+func (r *gormApiKeyRepository) FindByHash(keyHash string) (models.ApiKey, error) {
+	var apiKey models.ApiKey
+	result := r.db.Where("key_hash = ?", keyHash).Find(&apiKey)
+	return apiKey, result.Error
+}
+
+func (r *gormApiKeyRepository) Create(apiKey *models.ApiKey) error {
+	return r.db.Create(apiKey).Error
+}
+
+func (r *gormApiKeyRepository) Delete(id, userId int) (int64, error) {
+	result := r.db.Where("id = ? and user_id = ?", id, userId).Delete(&models.ApiKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormApiKeyRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.ApiKey{}, drop)
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kEPISODE_UPSERT_COLUMNS are updated when an upsert hits an existing
+// (tmdb_id, season, episode).
+var kEPISODE_UPSERT_COLUMNS = []string{"name", "overview", "air_date", "updated_at"}
+
+type gormEpisodeRepository struct {
+	db     *gorm.DB
+	userId int
+}
+
+// view selects the episodes joined with the watch state of the repository
+// user under the episodes name, so every read can filter and sort on watched
+// like a regular column.
+func (r *gormEpisodeRepository) view() *gorm.DB {
+	episodes := r.db.Session(&gorm.Session{NewDB: true}).Table("episodes").
+		Select("episodes.*, coalesce(watch_states.watched, false) as watched, watch_states.watched_date").
+		Joins("left join watch_states on watch_states.episode_id = episodes.id and watch_states.user_id = ?", r.userId)
+	return r.db.Table("(?) as episodes", episodes)
+}
+
+func (r *gormEpisodeRepository) FindAll() ([]models.Episode, error) {
+	var episodes []models.Episode
+	result := r.view().Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes)
+	return episodes, result.Error
+}
+
+func (r *gormEpisodeRepository) List(opts ListOptions) ([]models.Episode, int64, error) {
+	var episodes []models.Episode
+	var total int64
+
+	query := r.filter(opts.Filter)
+	if result := query.Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	query = gormOrder(r.filter(opts.Filter), opts.sortOrDefault(episodeDefaultSort), EpisodeSortFields)
+	result := gormPage(query, opts).Find(&episodes)
+	return episodes, total, result.Error
+}
+
+func (r *gormEpisodeRepository) filter(filter ListFilter) *gorm.DB {
+	query := r.view()
+
+	if filter.Name != "" {
+		query = query.Where("lower(name) like ?", gormNameLike(filter.Name))
+	}
+	if filter.TmdbId > 0 {
+		query = query.Where("tmdb_id = ?", filter.TmdbId)
+	}
+	if filter.Season > 0 {
+		query = query.Where("season = ?", filter.Season)
+	}
+	if filter.ExcludeSpecials {
+		query = query.Where("season > 0")
+	}
+	if filter.Watched != nil {
+		query = query.Where("watched = ?", *filter.Watched)
+	}
+	if filter.AirDateFrom > 0 {
+		query = query.Where("air_date >= ?", filter.AirDateFrom)
+	}
+	if filter.AirDateTo > 0 {
+		query = query.Where("air_date <= ?", filter.AirDateTo)
+	}
+	if filter.GroupType > 0 || filter.Status > 0 {
+		tvShows := r.db.Session(&gorm.Session{NewDB: true}).Model(&models.TvShow{}).Select("tmdb_id")
+		if filter.GroupType > 0 {
+			tvShows = tvShows.Where("group_type = ?", filter.GroupType)
+		}
+		if filter.Status > 0 {
+			tvShows = tvShows.Where("status = ?", filter.Status)
+		}
+		query = query.Where("tmdb_id in (?)", tvShows)
+	}
+
+	return query
+}
+
+func (r *gormEpisodeRepository) FindById(id int) (models.Episode, error) {
+	var episode models.Episode
+	result := r.view().Find(&episode, id)
+	return episode, result.Error
+}
+
+func (r *gormEpisodeRepository) FindByKey(tmdbId, season, episode int) (models.Episode, error) {
+	var ep models.Episode
+	result := r.view().Where("tmdb_id = ? and season = ? and episode = ?", tmdbId, season, episode).Find(&ep)
+	return ep, result.Error
+}
+
+func (r *gormEpisodeRepository) FindByTmdbId(tmdbId int) ([]models.Episode, error) {
+	var episodes []models.Episode
+	result := r.view().Where("tmdb_id = ?", tmdbId).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes)
+	return episodes, result.Error
+}
+
+func (r *gormEpisodeRepository) FindByTmdbIdAndSeason(tmdbId, season int) ([]models.Episode, error) {
+	var episodes []models.Episode
+	result := r.view().Where("tmdb_id = ? and season = ?", tmdbId, season).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes)
+	return episodes, result.Error
+}
+
+// unwatched returns the unwatched episodes, the specials of season 0 only
+// when specials is true.
+func (r *gormEpisodeRepository) unwatched(specials bool) *gorm.DB {
+	query := r.view().Where("watched = false")
+	if !specials {
+		query = query.Where("season > 0")
+	}
+	return query
+}
+
+func (r *gormEpisodeRepository) FindUnwatched(specials bool) ([]models.Episode, error) {
+	var episodes []models.Episode
+	result := r.unwatched(specials).Order(kEPISODE_ORDER_BY_TMDBID_SEASON_EPISODE).Find(&episodes)
+	return episodes, result.Error
+}
+
+func (r *gormEpisodeRepository) UnwatchedSummaries(specials bool) (map[int]UnwatchedSummary, error) {
+	var rows []UnwatchedSummary
+
+	unwatched := r.unwatched(specials).
+		Select("tmdb_id, season, episode, count(*) over (partition by tmdb_id) as total, row_number() over (partition by tmdb_id order by " + kEPISODE_ORDER_BY_SEASON_EPISODE + ") as position")
+
+	result := r.db.Table("(?) as unwatched", unwatched).
+		Select("tmdb_id, season, episode, total").
+		Where("position = 1").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	summaries := make(map[int]UnwatchedSummary, len(rows))
+	for _, row := range rows {
+		summaries[row.TmdbId] = row
+	}
+	return summaries, nil
+}
+
+func (r *gormEpisodeRepository) FindUpNext(groupType models.GroupType, airedBy models.Date, specials bool) ([]UpNext, error) {
+	var rows []UpNext
+
+	// the last watched date looks at every episode, so it is taken before
+	// the unwatched ones are picked
+	episodes := r.view().
+		Select("episodes.*, max(case when watched then watched_date end) over (partition by tmdb_id) as last_watched_date")
+
+	unwatched := r.db.Table("(?) as episodes", episodes).
+		Select("episodes.*, row_number() over (partition by tmdb_id order by "+kEPISODE_ORDER_BY_SEASON_EPISODE+") as position").
+		Where("watched = false and air_date <= ?", airedBy)
+	if !specials {
+		unwatched = unwatched.Where("season > 0")
+	}
+
+	query := r.db.Table("(?) as episodes", unwatched).
+		Select("episodes.*").
+		Joins("join tv_shows on tv_shows.tmdb_id = episodes.tmdb_id").
+		Where("position = 1")
+	if groupType > 0 {
+		query = query.Where("tv_shows.group_type = ?", groupType)
+	}
+
+	result := query.Order("last_watched_date desc nulls last, tv_shows.name").Find(&rows)
+	return rows, result.Error
+}
+
+func (r *gormEpisodeRepository) Create(episode *models.Episode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(episode).Error; err != nil {
+			return err
+		}
+		return r.keepWatchStates(tx, []models.Episode{*episode})
+	})
+}
+
+func (r *gormEpisodeRepository) CreateMany(episodes []models.Episode) error {
+	if len(episodes) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&episodes).Error; err != nil {
+			return err
+		}
+		return r.keepWatchStates(tx, episodes)
+	})
+}
+
+func (r *gormEpisodeRepository) CreateMissing(episodes []models.Episode) ([]bool, error) {
+	inserted := make([]bool, len(episodes))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var created []models.Episode
+		for index := range episodes {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&episodes[index])
+			if result.Error != nil {
+				return result.Error
+			}
+			if inserted[index] = result.RowsAffected > 0; inserted[index] {
+				created = append(created, episodes[index])
+			}
+		}
+		return r.keepWatchStates(tx, created)
+	})
+	return inserted, err
+}
+
+func (r *gormEpisodeRepository) Upsert(episode *models.Episode) (bool, error) {
+	var created bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		key := tx.Model(&models.Episode{}).Where("tmdb_id = ? and season = ? and episode = ?", episode.TmdbId, episode.Season, episode.Episode)
+		if err := key.Count(&existing).Error; err != nil {
+			return err
+		}
+		created = existing == 0
+
+		watched, watchedDate := episode.Watched, episode.WatchedDate
+		episode.Id = 0
+		upsert := clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tmdb_id"}, {Name: "season"}, {Name: "episode"}},
+			DoUpdates: clause.AssignmentColumns(kEPISODE_UPSERT_COLUMNS),
+		}
+		if err := tx.Clauses(upsert).Create(episode).Error; err != nil {
+			return err
+		}
+
+		// read back the created_at kept by the conflicting row
+		if err := tx.Where("tmdb_id = ? and season = ? and episode = ?", episode.TmdbId, episode.Season, episode.Episode).Take(episode).Error; err != nil {
+			return err
+		}
+		episode.Watched, episode.WatchedDate = watched, watchedDate
+		return r.keepWatchStates(tx, []models.Episode{*episode})
+	})
+	return created, err
+}
+
+func (r *gormEpisodeRepository) UpsertIfUnchanged(episode *models.Episode, updatedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		watched, watchedDate := episode.Watched, episode.WatchedDate
+		episode.Id = 0
+		key := []interface{}{episode.TmdbId, episode.Season, episode.Episode}
+		result := tx.Model(&models.Episode{}).
+			Where("tmdb_id = ? and season = ? and episode = ? and updated_at = ?", append(key, updatedAt)...).
+			Select(kEPISODE_UPSERT_COLUMNS).
+			Updates(episode)
+		if err := gormCheckUnchanged(result); err != nil {
+			return err
+		}
+
+		if err := tx.Where("tmdb_id = ? and season = ? and episode = ?", key...).Take(episode).Error; err != nil {
+			return err
+		}
+		episode.Watched, episode.WatchedDate = watched, watchedDate
+		return r.keepWatchStates(tx, []models.Episode{*episode})
+	})
+}
+
+func (r *gormEpisodeRepository) Save(episode *models.Episode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(episode).Error; err != nil {
+			return err
+		}
+		return r.keepWatchStates(tx, []models.Episode{*episode})
+	})
+}
+
+func (r *gormEpisodeRepository) SaveIfUnchanged(episode *models.Episode, updatedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(episode).Where("updated_at = ?", updatedAt).Select("*").Updates(episode)
+		if err := gormCheckUnchanged(result); err != nil {
+			return err
+		}
+		return r.keepWatchStates(tx, []models.Episode{*episode})
+	})
+}
+
+func (r *gormEpisodeRepository) SaveMany(episodes []models.Episode) error {
+	if len(episodes) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&episodes).Error; err != nil {
+			return err
+		}
+		return r.keepWatchStates(tx, episodes)
+	})
+}
+
+// keepWatchStates stores the watched fields written along with the episodes,
+// when the repository has a user to store them for.
+func (r *gormEpisodeRepository) keepWatchStates(tx *gorm.DB, episodes []models.Episode) error {
+	if r.userId == 0 {
+		return nil
+	}
+	return gormSaveWatchStates(tx, r.userId, episodes)
+}
+
+func (r *gormEpisodeRepository) SaveWatchState(episode *models.Episode) error {
+	return r.SaveWatchStates([]models.Episode{*episode})
+}
+
+func (r *gormEpisodeRepository) SaveWatchStates(episodes []models.Episode) error {
+	if r.userId == 0 {
+		return ErrNoUser
+	}
+	return gormSaveWatchStates(r.db, r.userId, episodes)
+}
+
+func (r *gormEpisodeRepository) Delete(id int) error {
+	_, err := r.deleteWhere("id = ?", id)
+	return err
+}
+
+func (r *gormEpisodeRepository) DeleteIfUnchanged(id int, updatedAt time.Time) error {
+	// ErrStale rolls back the watch states deleted before the episode
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := &gormEpisodeRepository{db: tx, userId: r.userId}
+		deleted, err := repo.deleteWhere("id = ? and updated_at = ?", id, updatedAt)
+		if err == nil && deleted == 0 {
+			return ErrStale
+		}
+		return err
+	})
+}
+
+func (r *gormEpisodeRepository) DeleteByTmdbId(tmdbId int) (int64, error) {
+	return r.deleteWhere("tmdb_id = ?", tmdbId)
+}
+
+func (r *gormEpisodeRepository) DeleteByTmdbIdAndSeason(tmdbId, season int) (int64, error) {
+	return r.deleteWhere("tmdb_id = ? and season = ?", tmdbId, season)
+}
+
+// deleteWhere removes the matching episodes along with every watch state and
+// watch event pointing to them.
+func (r *gormEpisodeRepository) deleteWhere(query string, args ...interface{}) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&models.Episode{}).Select("id").Where(query, args...)
+		if err := tx.Where("episode_id in (?)", ids).Delete(&models.WatchState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("episode_id in (?)", ids).Delete(&models.WatchEvent{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where(query, args...).Delete(&models.Episode{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+func (r *gormEpisodeRepository) Truncate(drop bool) error {
+	// watch states and events point to episode ids, which may start over
+	if err := gormTruncate(r.db, &models.WatchState{}, drop); err != nil {
+		return err
+	}
+	if err := gormTruncate(r.db, &models.WatchEvent{}, drop); err != nil {
+		return err
+	}
+	return gormTruncate(r.db, &models.Episode{}, drop)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+)
+
+type gormSeasonRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSeasonRepository) FindAll() ([]models.Season, error) {
+	var seasons []models.Season
+	result := r.db.Order("tmdb_id, " + kSEASON_ORDER_BY_NUMBER).Find(&seasons)
+	return seasons, result.Error
+}
+
+func (r *gormSeasonRepository) FindByTmdbId(tmdbId int) ([]models.Season, error) {
+	var seasons []models.Season
+	result := r.db.Where("tmdb_id = ?", tmdbId).Order(kSEASON_ORDER_BY_NUMBER).Find(&seasons)
+	return seasons, result.Error
+}
+
+func (r *gormSeasonRepository) FindByKey(tmdbId, number int) (models.Season, error) {
+	var season models.Season
+	result := r.db.Where("tmdb_id = ? and number = ?", tmdbId, number).Find(&season)
+	return season, result.Error
+}
+
+func (r *gormSeasonRepository) Create(season *models.Season) error {
+	return r.db.Create(season).Error
+}
+
+func (r *gormSeasonRepository) Save(season *models.Season) error {
+	return r.db.Save(season).Error
+}
+
+func (r *gormSeasonRepository) Delete(id int) error {
+	return r.db.Delete(&models.Season{}, id).Error
+}
+
+func (r *gormSeasonRepository) DeleteByTmdbId(tmdbId int) (int64, error) {
+	result := r.db.Where("tmdb_id = ?", tmdbId).Delete(&models.Season{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormSeasonRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.Season{}, drop)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// kSTATS_EVENT_DAY is the day in UTC of a watch event, like watchedDateOf.
+const kSTATS_EVENT_DAY = "(watch_events.watched_at at time zone 'UTC')::date"
+
+type gormStatsRepository struct {
+	db     *gorm.DB
+	userId int
+}
+
+// episodes selects the episodes with the watch state of the repository user.
+func (r *gormStatsRepository) episodes() *gorm.DB {
+	return (&gormEpisodeRepository{db: r.db, userId: r.userId}).view()
+}
+
+// watches selects one row per watch in the range, with the tmdb id of the
+// show and the day. Every watch event counts, rewatches too. The episodes
+// watched before watched dates were kept have no event, they come from the
+// watch state without a day.
+func (r *gormStatsRepository) watches(watched StatsRange) *gorm.DB {
+	events := r.db.Table("watch_events").
+		Select("episodes.tmdb_id, "+kSTATS_EVENT_DAY+" as day").
+		Joins("join episodes on episodes.id = watch_events.episode_id").
+		Where("watch_events.user_id = ?", r.userId)
+	if watched.To > 0 {
+		events = events.Where(kSTATS_EVENT_DAY+" <= ?", watched.To)
+	}
+	if watched.From > 0 {
+		return events.Where(kSTATS_EVENT_DAY+" >= ?", watched.From)
+	}
+
+	undated := r.db.Table("(?) as episodes", r.episodes()).
+		Select("tmdb_id, null::date as day").
+		Where("watched and watched_date is null")
+	return r.db.Raw("? union all ?", events, undated)
+}
+
+func (r *gormStatsRepository) Summary(watched StatsRange) (Stats, error) {
+	// answer empty lists rather than null when nothing matches
+	stats := Stats{PerTvShow: []TvShowStats{}, ByGroup: []StatsCount{}, ByStatus: []StatsCount{}}
+
+	inRange := func() *gorm.DB {
+		return r.db.Table("(?) as watches", r.watches(watched))
+	}
+	dated := func() *gorm.DB {
+		return inRange().Where("day is not null")
+	}
+	perTvShow := func() *gorm.DB {
+		return inRange().Select("tmdb_id, count(*) as watch_count").Group("tmdb_id")
+	}
+
+	if err := inRange().Count(&stats.Watched).Error; err != nil {
+		return stats, err
+	}
+
+	var err error
+	if stats.PerMonth, err = gormPeriods(dated(), "YYYY-MM"); err != nil {
+		return stats, err
+	}
+	if stats.PerYear, err = gormPeriods(dated(), "YYYY"); err != nil {
+		return stats, err
+	}
+
+	result := r.db.Table("tv_shows").
+		Select("tv_shows.tmdb_id, tv_shows.name, count(episodes.id) as episodes, "+
+			"coalesce(max(watches.watch_count), 0) as watched, "+
+			"coalesce(round(100.0 * count(case when episodes.watched then 1 end) / nullif(count(episodes.id), 0), 1), 0) as completion").
+		Joins("left join (?) as episodes on episodes.tmdb_id = tv_shows.tmdb_id", r.episodes()).
+		Joins("left join (?) as watches on watches.tmdb_id = tv_shows.tmdb_id", perTvShow()).
+		Group("tv_shows.tmdb_id, tv_shows.name").
+		Order("tv_shows.name").
+		Scan(&stats.PerTvShow)
+	if result.Error != nil {
+		return stats, result.Error
+	}
+
+	if err := r.countBy("group_type", perTvShow(), &stats.ByGroup); err != nil {
+		return stats, err
+	}
+	if err := r.countBy("status", perTvShow(), &stats.ByStatus); err != nil {
+		return stats, err
+	}
+
+	// consecutive days minus their position give the same date, one per streak
+	days := dated().Select("distinct day")
+	islands := r.db.Table("(?) as days", days).
+		Select("day, day - (row_number() over (order by day))::int as island")
+	result = r.db.Table("(?) as islands", islands).
+		Select(`count(*) as days, min(day) as start, max(day) as "end"`).
+		Group("island").
+		Order("days desc, start desc").
+		Limit(1).
+		Scan(&stats.LongestStreak)
+	return stats, result.Error
+}
+
+// gormPeriods counts the watches of query per day formatted with format, the
+// month or the year of the day.
+func gormPeriods(query *gorm.DB, format string) ([]StatsPeriod, error) {
+	periods := []StatsPeriod{}
+	result := query.Select("to_char(day, ?) as period, count(*) as watched", format).Group("period").Order("period").Scan(&periods)
+	return periods, result.Error
+}
+
+// countBy counts the shows per value of column and their watches in the
+// range, read from perTvShow.
+func (r *gormStatsRepository) countBy(column string, perTvShow *gorm.DB, counts *[]StatsCount) error {
+	return r.db.Table("tv_shows").
+		Select("tv_shows."+column+" as value, count(*) as tv_shows, "+
+			"coalesce(sum(watches.watch_count), 0) as watched").
+		Joins("left join (?) as watches on watches.tmdb_id = tv_shows.tmdb_id", perTvShow).
+		Group("tv_shows." + column).
+		Order("value").
+		Scan(counts).Error
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kTVSHOW_UPSERT_COLUMNS are updated when an upsert hits an existing tmdb id.
+var kTVSHOW_UPSERT_COLUMNS = []string{"name", "overview", "group_type", "status", "updated_at"}
+
+type gormTvShowRepository struct {
+	db     *gorm.DB
+	userId int
+}
+
+func (r *gormTvShowRepository) FindAll() ([]models.TvShow, error) {
+	var tvShows []models.TvShow
+	result := r.db.Order(kTVSHOW_ORDER_BY_NAME).Find(&tvShows)
+	return tvShows, result.Error
+}
+
+func (r *gormTvShowRepository) List(opts ListOptions) ([]models.TvShow, int64, error) {
+	var tvShows []models.TvShow
+	var total int64
+
+	query := r.filter(opts.Filter)
+	if result := query.Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	query = gormOrder(r.filter(opts.Filter), opts.sortOrDefault(tvShowDefaultSort), TvShowSortFields)
+	result := gormPage(query, opts).Find(&tvShows)
+	return tvShows, total, result.Error
+}
+
+func (r *gormTvShowRepository) filter(filter ListFilter) *gorm.DB {
+	query := r.db.Model(&models.TvShow{})
+
+	if filter.Name != "" {
+		query = query.Where("lower(name) like ?", gormNameLike(filter.Name))
+	}
+	if filter.TmdbId > 0 {
+		query = query.Where("tmdb_id = ?", filter.TmdbId)
+	}
+	if filter.GroupType > 0 {
+		query = query.Where("group_type = ?", filter.GroupType)
+	}
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Watched != nil {
+		// watched shows have no unwatched episode left
+		unwatched := r.episodes().Where("not exists (?)", gormWatchedBy(r.db, r.userId))
+		if *filter.Watched {
+			query = query.Where("not exists (?)", unwatched)
+		} else {
+			query = query.Where("exists (?)", unwatched)
+		}
+	}
+	if filter.hasAirDate() {
+		airing := r.episodes()
+		if filter.AirDateFrom > 0 {
+			airing = airing.Where("air_date >= ?", filter.AirDateFrom)
+		}
+		if filter.AirDateTo > 0 {
+			airing = airing.Where("air_date <= ?", filter.AirDateTo)
+		}
+		query = query.Where("exists (?)", airing)
+	}
+
+	return query
+}
+
+// episodes starts a subquery over the episodes of the outer tv_shows row.
+func (r *gormTvShowRepository) episodes() *gorm.DB {
+	return r.db.Session(&gorm.Session{NewDB: true}).Table("episodes").Select("1").Where("episodes.tmdb_id = tv_shows.tmdb_id")
+}
+
+func (r *gormTvShowRepository) FindById(id int) (models.TvShow, error) {
+	var tvShow models.TvShow
+	result := r.db.Find(&tvShow, id)
+	return tvShow, result.Error
+}
+
+func (r *gormTvShowRepository) FindByTmdbId(tmdbId int) (models.TvShow, error) {
+	var tvShow models.TvShow
+	result := r.db.Where("tmdb_id = ?", tmdbId).Find(&tvShow)
+	return tvShow, result.Error
+}
+
+func (r *gormTvShowRepository) Create(tvShow *models.TvShow) error {
+	return gormDuplicatedKey(r.db, r.db.Create(tvShow).Error)
+}
+
+func (r *gormTvShowRepository) CreateMany(tvShows []models.TvShow) error {
+	if len(tvShows) == 0 {
+		return nil
+	}
+	return r.db.Create(&tvShows).Error
+}
+
+func (r *gormTvShowRepository) CreateMissing(tvShows []models.TvShow) ([]bool, error) {
+	inserted := make([]bool, len(tvShows))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for index := range tvShows {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tvShows[index])
+			if result.Error != nil {
+				return result.Error
+			}
+			inserted[index] = result.RowsAffected > 0
+		}
+		return nil
+	})
+	return inserted, err
+}
+
+func (r *gormTvShowRepository) Upsert(tvShow *models.TvShow) (bool, error) {
+	var created bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var taken, existing int64
+		if err := tx.Model(&models.TvShow{}).Where("name = ? and tmdb_id <> ?", tvShow.Name, tvShow.TmdbId).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrDuplicatedKey
+		}
+
+		if err := tx.Model(&models.TvShow{}).Where("tmdb_id = ?", tvShow.TmdbId).Count(&existing).Error; err != nil {
+			return err
+		}
+		created = existing == 0
+
+		tvShow.Id = 0
+		upsert := clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tmdb_id"}},
+			DoUpdates: clause.AssignmentColumns(kTVSHOW_UPSERT_COLUMNS),
+		}
+		if err := tx.Clauses(upsert).Create(tvShow).Error; err != nil {
+			return err
+		}
+
+		// read back the created_at kept by the conflicting row
+		return tx.Where("tmdb_id = ?", tvShow.TmdbId).Take(tvShow).Error
+	})
+	return created, err
+}
+
+func (r *gormTvShowRepository) UpsertIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.TvShow{}).Where("name = ? and tmdb_id <> ?", tvShow.Name, tvShow.TmdbId).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrDuplicatedKey
+		}
+
+		tvShow.Id = 0
+		result := tx.Model(&models.TvShow{}).
+			Where("tmdb_id = ? and updated_at = ?", tvShow.TmdbId, updatedAt).
+			Select(kTVSHOW_UPSERT_COLUMNS).
+			Updates(tvShow)
+		if err := gormCheckUnchanged(result); err != nil {
+			return err
+		}
+
+		return tx.Where("tmdb_id = ?", tvShow.TmdbId).Take(tvShow).Error
+	})
+}
+
+func (r *gormTvShowRepository) Save(tvShow *models.TvShow) error {
+	return gormDuplicatedKey(r.db, r.db.Save(tvShow).Error)
+}
+
+func (r *gormTvShowRepository) SaveIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error {
+	result := r.db.Model(tvShow).Where("updated_at = ?", updatedAt).Select("*").Updates(tvShow)
+	return gormDuplicatedKey(r.db, gormCheckUnchanged(result))
+}
+
+func (r *gormTvShowRepository) Delete(id int) error {
+	return r.db.Delete(&models.TvShow{}, id).Error
+}
+
+func (r *gormTvShowRepository) DeleteIfUnchanged(id int, updatedAt time.Time) error {
+	result := r.db.Where("updated_at = ?", updatedAt).Delete(&models.TvShow{}, id)
+	return gormCheckUnchanged(result)
+}
+
+func (r *gormTvShowRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.TvShow{}, drop)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Count() (int64, error) {
+	var count int64
+	result := r.db.Model(&models.User{}).Count(&count)
+	return count, result.Error
+}
+
+func (r *gormUserRepository) CountForUpdate() (int64, error) {
+	// the lock mode conflicts with itself and lasts until the transaction ends
+	if err := r.db.Exec("lock table users in share row exclusive mode").Error; err != nil {
+		return 0, err
+	}
+	return r.Count()
+}
+
+func (r *gormUserRepository) FindById(id int) (models.User, error) {
+	var user models.User
+	result := r.db.Find(&user, id)
+	return user, result.Error
+}
+
+func (r *gormUserRepository) FindByUsername(username string) (models.User, error) {
+	var user models.User
+	result := r.db.Where("username = ?", username).Find(&user)
+	return user, result.Error
+}
+
+func (r *gormUserRepository) FindByCalendarTokenHash(tokenHash string) (models.User, error) {
+	var user models.User
+	if tokenHash == "" {
+		return user, nil
+	}
+	result := r.db.Where("calendar_token_hash = ?", tokenHash).Find(&user)
+	return user, result.Error
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepository) Save(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *gormUserRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.User{}, drop)
+}
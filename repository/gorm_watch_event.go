@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+)
+
+const kWATCH_EVENT_ORDER_BY_NEWEST = "watched_at desc, id desc"
+
+type gormWatchEventRepository struct {
+	db     *gorm.DB
+	userId int
+}
+
+// view selects the events of the repository user joined with the key of
+// their episode, under the watch_events name.
+func (r *gormWatchEventRepository) view() *gorm.DB {
+	events := r.db.Session(&gorm.Session{NewDB: true}).Table("watch_events").
+		Select("watch_events.*, episodes.tmdb_id, episodes.season, episodes.episode").
+		Joins("join episodes on episodes.id = watch_events.episode_id").
+		Where("watch_events.user_id = ?", r.userId)
+	return r.db.Table("(?) as watch_events", events)
+}
+
+func (r *gormWatchEventRepository) FindById(id int) (models.WatchEvent, error) {
+	var event models.WatchEvent
+	result := r.view().Find(&event, id)
+	return event, result.Error
+}
+
+func (r *gormWatchEventRepository) FindByEpisodeId(episodeId int) ([]models.WatchEvent, error) {
+	var events []models.WatchEvent
+	result := r.view().Where("episode_id = ?", episodeId).Order(kWATCH_EVENT_ORDER_BY_NEWEST).Find(&events)
+	return events, result.Error
+}
+
+func (r *gormWatchEventRepository) FindByTmdbId(tmdbId int) ([]models.WatchEvent, error) {
+	var events []models.WatchEvent
+	result := r.view().Where("tmdb_id = ?", tmdbId).Order(kWATCH_EVENT_ORDER_BY_NEWEST).Find(&events)
+	return events, result.Error
+}
+
+func (r *gormWatchEventRepository) Create(event *models.WatchEvent) error {
+	if r.userId == 0 {
+		return ErrNoUser
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		event.Id = 0
+		event.UserId = r.userId
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		return gormFollowLatestEvent(tx, r.userId, event.EpisodeId, true)
+	})
+}
+
+func (r *gormWatchEventRepository) Delete(id int) (int64, error) {
+	if r.userId == 0 {
+		return 0, ErrNoUser
+	}
+
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var event models.WatchEvent
+		if err := tx.Where("user_id = ?", r.userId).Find(&event, id).Error; err != nil || event.Id == 0 {
+			return err
+		}
+
+		result := tx.Delete(&models.WatchEvent{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return gormFollowLatestEvent(tx, r.userId, event.EpisodeId, false)
+	})
+	return deleted, err
+}
+
+func (r *gormWatchEventRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.WatchEvent{}, drop)
+}
+
+// gormFollowLatestEvent moves the watch state of the episode to the latest
+// event of userId. An added event marks the episode watched.
+func gormFollowLatestEvent(tx *gorm.DB, userId, episodeId int, added bool) error {
+	var latest []time.Time
+	result := tx.Model(&models.WatchEvent{}).Where("user_id = ? and episode_id = ?", userId, episodeId).
+		Order("watched_at desc").Limit(1).Pluck("watched_at", &latest)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	var state models.WatchState
+	if err := tx.Where("user_id = ? and episode_id = ?", userId, episodeId).Find(&state).Error; err != nil {
+		return err
+	}
+
+	episode := models.Episode{Id: episodeId, Watched: state.Watched || added}
+	if len(latest) == 0 {
+		episode.Watched = false
+	} else {
+		episode.WatchedDate = watchedDateOf(latest[0])
+	}
+	return gormWriteWatchStates(tx, userId, []models.Episode{episode})
+}
+
+// gormRecordWatchEvents adds a watch event for the watched episodes whose
+// latest event is not on their WatchedDate.
+func gormRecordWatchEvents(tx *gorm.DB, userId int, episodes []models.Episode, now time.Time) error {
+	var ids []int
+	for _, episode := range episodes {
+		if episode.Watched && episode.WatchedDate != 0 {
+			ids = append(ids, episode.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		EpisodeId int
+		WatchedAt time.Time
+	}
+	result := tx.Model(&models.WatchEvent{}).Select("episode_id, max(watched_at) as watched_at").
+		Where("user_id = ? and episode_id in ?", userId, ids).Group("episode_id").Scan(&rows)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	latest := make(map[int]models.Date, len(rows))
+	for _, row := range rows {
+		latest[row.EpisodeId] = watchedDateOf(row.WatchedAt)
+	}
+
+	var events []models.WatchEvent
+	for _, episode := range episodes {
+		if !episode.Watched || episode.WatchedDate == 0 || latest[episode.Id] == episode.WatchedDate {
+			continue
+		}
+		latest[episode.Id] = episode.WatchedDate
+		events = append(events, models.WatchEvent{
+			UserId:    userId,
+			EpisodeId: episode.Id,
+			WatchedAt: watchedAtOf(episode.WatchedDate, now),
+			CreatedAt: now,
+		})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+// watchedAtOf turns a WatchedDate into the time of its event: now for today,
+// the midnight UTC of any other day.
+func watchedAtOf(date models.Date, now time.Time) time.Time {
+	if date == watchedDateOf(now) {
+		return now
+	}
+	return date.Time()
+}
+
+// watchedDateOf turns the time of an event into its WatchedDate, the day in
+// UTC so it does not depend on the zone of the server.
+func watchedDateOf(watchedAt time.Time) models.Date {
+	return models.DateOf(watchedAt.UTC())
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// columns the watched fields of episodes are renamed to when the
+	// database is migrated, until the first user adopts them
+	kLEGACY_WATCHED      = "legacy_watched"
+	kLEGACY_WATCHED_DATE = "legacy_watched_date"
+)
+
+type gormWatchStateRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormWatchStateRepository) AdoptLegacy(userId int) (int64, error) {
+	var adopted int64
+	migrator := r.db.Migrator()
+	if !migrator.HasColumn(&models.Episode{}, kLEGACY_WATCHED) {
+		return 0, nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("insert into watch_states (user_id, episode_id, watched, watched_date, created_at, updated_at) "+
+			"select ?, id, "+kLEGACY_WATCHED+", "+
+			"case when "+kLEGACY_WATCHED_DATE+" > 0 then to_date("+kLEGACY_WATCHED_DATE+"::text, 'YYYYMMDD') end, now(), now() from episodes "+
+			"where "+kLEGACY_WATCHED+" = true or "+kLEGACY_WATCHED_DATE+" > 0 "+
+			"on conflict do nothing", userId)
+		if result.Error != nil {
+			return result.Error
+		}
+		adopted = result.RowsAffected
+
+		if _, err := gormBackfillEvents(tx); err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(&models.Episode{}, kLEGACY_WATCHED); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Episode{}, kLEGACY_WATCHED_DATE)
+	})
+	return adopted, err
+}
+
+func (r *gormWatchStateRepository) BackfillEvents() (int64, error) {
+	return gormBackfillEvents(r.db)
+}
+
+// gormBackfillEvents dates the events it records on the watched date of the
+// state, or when the state was last saved if it has none.
+func gormBackfillEvents(db *gorm.DB) (int64, error) {
+	result := db.Exec("insert into watch_events (user_id, episode_id, watched_at, note, created_at) " +
+		"select user_id, episode_id, " +
+		"coalesce(watched_date::timestamp at time zone 'UTC', updated_at), '', now() " +
+		"from watch_states where watched and not exists (" +
+		"select 1 from watch_events where watch_events.user_id = watch_states.user_id and watch_events.episode_id = watch_states.episode_id)")
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormWatchStateRepository) Truncate(drop bool) error {
+	return gormTruncate(r.db, &models.WatchState{}, drop)
+}
+
+// gormWatchedBy starts a subquery matching when the outer episodes row was
+// watched by userId.
+func gormWatchedBy(db *gorm.DB, userId int) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Table("watch_states").Select("1").
+		Where("watch_states.episode_id = episodes.id and watch_states.user_id = ? and watch_states.watched", userId)
+}
+
+// gormSaveWatchStates stores the watched fields of episodes for userId,
+// recording a watch event for each new watched date.
+func gormSaveWatchStates(db *gorm.DB, userId int, episodes []models.Episode) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := gormRecordWatchEvents(tx, userId, episodes, time.Now()); err != nil {
+			return err
+		}
+		return gormWriteWatchStates(tx, userId, episodes)
+	})
+}
+
+// gormWriteWatchStates stores the watched fields of episodes for userId. An
+// unwatched episode without a watched date has its watch state removed.
+func gormWriteWatchStates(db *gorm.DB, userId int, episodes []models.Episode) error {
+	var states []models.WatchState
+	var cleared []int
+	now := time.Now()
+
+	for _, episode := range episodes {
+		if !episode.Watched && episode.WatchedDate == 0 {
+			cleared = append(cleared, episode.Id)
+			continue
+		}
+		states = append(states, models.WatchState{
+			UserId:      userId,
+			EpisodeId:   episode.Id,
+			Watched:     episode.Watched,
+			WatchedDate: episode.WatchedDate,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	if len(cleared) > 0 {
+		result := db.Where("user_id = ? and episode_id in ?", userId, cleared).Delete(&models.WatchState{})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	if len(states) > 0 {
+		return db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "episode_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"watched", "watched_date", "updated_at"}),
+		}).Create(&states).Error
+	}
+	return nil
+}
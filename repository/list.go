@@ -0,0 +1,84 @@
+package repository
+
+import "github.com/feealc/tvshows-backend-go/models"
+
+// SortField orders a list by one whitelisted field, using its json name.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListFilter holds the filters shared by the list endpoints. Zero values are
+// ignored. GroupType and Status filter episodes by their show, while Watched
+// and the air date range filter shows by their episodes.
+type ListFilter struct {
+	Name        string
+	TmdbId      int
+	Season      int
+	GroupType   models.GroupType
+	Status      models.TvShowStatus
+	Watched     *bool
+	AirDateFrom models.Date
+	AirDateTo   models.Date
+	// ExcludeSpecials leaves out the episodes of season 0.
+	ExcludeSpecials bool
+}
+
+// ListOptions describes one page of a list. Limit 0 returns every row.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+	Filter ListFilter
+}
+
+// TvShowSortFields maps the sortable json fields of TvShow to their columns.
+var TvShowSortFields = map[string]string{
+	"id":         "id",
+	"tmdb_id":    "tmdb_id",
+	"name":       "name",
+	"group":      "group_type",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// EpisodeSortFields maps the sortable json fields of Episode to their columns.
+var EpisodeSortFields = map[string]string{
+	"id":           "id",
+	"tmdb_id":      "tmdb_id",
+	"season":       "season",
+	"episode":      "episode",
+	"name":         "name",
+	"air_date":     "air_date",
+	"watched":      "watched",
+	"watched_date": "watched_date",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+}
+
+var (
+	tvShowDefaultSort  = []SortField{{Field: "name"}}
+	episodeDefaultSort = []SortField{{Field: "tmdb_id"}, {Field: "season"}, {Field: "episode"}}
+)
+
+func (o ListOptions) sortOrDefault(defaultSort []SortField) []SortField {
+	if len(o.Sort) == 0 {
+		return defaultSort
+	}
+	return o.Sort
+}
+
+func (f ListFilter) hasAirDate() bool {
+	return f.AirDateFrom > 0 || f.AirDateTo > 0
+}
+
+func (f ListFilter) airDateInRange(airDate models.Date) bool {
+	if f.AirDateFrom > 0 && airDate < f.AirDateFrom {
+		return false
+	}
+	if f.AirDateTo > 0 && airDate > f.AirDateTo {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+// memoryTable keeps the rows of one table indexed by primary key and mimics
+// an auto increment sequence.
+type memoryTable[T any] struct {
+	rows map[int]T
+	seq  int
+}
+
+func newMemoryTable[T any]() *memoryTable[T] {
+	return &memoryTable[T]{rows: make(map[int]T)}
+}
+
+func (t *memoryTable[T]) clone() *memoryTable[T] {
+	c := &memoryTable[T]{rows: make(map[int]T, len(t.rows)), seq: t.seq}
+	for id, row := range t.rows {
+		c.rows[id] = row
+	}
+	return c
+}
+
+// nextId returns the id to use for a new row. A non zero id is kept, like an
+// explicit value inserted into a serial column.
+func (t *memoryTable[T]) nextId(id int) int {
+	if id == 0 {
+		t.seq++
+		return t.seq
+	}
+	if id > t.seq {
+		t.seq = id
+	}
+	return id
+}
+
+// list returns the rows accepted by filter sorted by less.
+func (t *memoryTable[T]) list(filter func(row T) bool, less func(a, b T) bool) []T {
+	rows := make([]T, 0)
+	for _, row := range t.rows {
+		if filter == nil || filter(row) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+	return rows
+}
+
+func (t *memoryTable[T]) deleteWhere(filter func(row T) bool) int64 {
+	var deleted int64
+	for id, row := range t.rows {
+		if filter(row) {
+			delete(t.rows, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (t *memoryTable[T]) truncate(drop bool) {
+	t.rows = make(map[int]T)
+	if drop {
+		t.seq = 0
+	}
+}
+
+// memoryLess builds the comparison for a list sorted by the given fields,
+// reading each field through value and breaking ties by id like gormOrder.
+func memoryLess[T any](sortFields []SortField, value func(row T, field string) interface{}, id func(row T) int) func(a, b T) bool {
+	return func(a, b T) bool {
+		for _, field := range sortFields {
+			cmp := compareValues(value(a, field.Field), value(b, field.Field))
+			if cmp == 0 {
+				continue
+			}
+			if field.Desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return id(a) < id(b)
+	}
+}
+
+func compareValues(a, b interface{}) int {
+	switch va := a.(type) {
+	case int:
+		vb := b.(int)
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case string:
+		return strings.Compare(va, b.(string))
+	case bool:
+		vb := b.(bool)
+		if va != vb {
+			if vb {
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		return va.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+func memoryPage[T any](rows []T, opts ListOptions) []T {
+	if opts.Offset >= len(rows) {
+		return make([]T, 0)
+	}
+	rows = rows[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(rows) {
+		rows = rows[:opts.Limit]
+	}
+	return rows
+}
+
+func memoryNameContains(name, search string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(search))
+}
+
+type memoryData struct {
+	tvShows     *memoryTable[models.TvShow]
+	episodes    *memoryTable[models.Episode]
+	seasons     *memoryTable[models.Season]
+	users       *memoryTable[models.User]
+	apiKeys     *memoryTable[models.ApiKey]
+	watchStates *memoryTable[models.WatchState]
+	watchEvents *memoryTable[models.WatchEvent]
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		tvShows:     newMemoryTable[models.TvShow](),
+		episodes:    newMemoryTable[models.Episode](),
+		seasons:     newMemoryTable[models.Season](),
+		users:       newMemoryTable[models.User](),
+		apiKeys:     newMemoryTable[models.ApiKey](),
+		watchStates: newMemoryTable[models.WatchState](),
+		watchEvents: newMemoryTable[models.WatchEvent](),
+	}
+}
+
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		tvShows:     d.tvShows.clone(),
+		episodes:    d.episodes.clone(),
+		seasons:     d.seasons.clone(),
+		users:       d.users.clone(),
+		apiKeys:     d.apiKeys.clone(),
+		watchStates: d.watchStates.clone(),
+		watchEvents: d.watchEvents.clone(),
+	}
+}
+
+type memoryStore struct {
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// memoryRepository is a thread-safe in-memory Repository, meant for tests and
+// local runs without Postgres.
+type memoryRepository struct {
+	store  *memoryStore
+	userId int
+	// inTx is set for the repository handed to a Transaction callback, which
+	// already holds the store lock.
+	inTx bool
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{store: &memoryStore{data: newMemoryData()}}
+}
+
+func (r *memoryRepository) TvShows() TvShowRepository {
+	return &memoryTvShowRepository{r}
+}
+
+func (r *memoryRepository) Episodes() EpisodeRepository {
+	return &memoryEpisodeRepository{r}
+}
+
+func (r *memoryRepository) Seasons() SeasonRepository {
+	return &memorySeasonRepository{r}
+}
+
+func (r *memoryRepository) Users() UserRepository {
+	return &memoryUserRepository{r}
+}
+
+func (r *memoryRepository) ApiKeys() ApiKeyRepository {
+	return &memoryApiKeyRepository{r}
+}
+
+func (r *memoryRepository) WatchStates() WatchStateRepository {
+	return &memoryWatchStateRepository{r}
+}
+
+func (r *memoryRepository) WatchEvents() WatchEventRepository {
+	return &memoryWatchEventRepository{r}
+}
+
+func (r *memoryRepository) Stats() StatsRepository {
+	return &memoryStatsRepository{r}
+}
+
+func (r *memoryRepository) ForUser(userId int) Repository {
+	return &memoryRepository{store: r.store, userId: userId, inTx: r.inTx}
+}
+
+func (r *memoryRepository) Transaction(fn func(repo Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	snapshot := r.store.data.clone()
+	if err := fn(&memoryRepository{store: r.store, userId: r.userId, inTx: true}); err != nil {
+		r.store.data = snapshot
+		return err
+	}
+
+	return nil
+}
+
+func (r *memoryRepository) read(fn func(data *memoryData)) {
+	if !r.inTx {
+		r.store.mu.RLock()
+		defer r.store.mu.RUnlock()
+	}
+	fn(r.store.data)
+}
+
+func (r *memoryRepository) write(fn func(data *memoryData) error) error {
+	if !r.inTx {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+	}
+	return fn(r.store.data)
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryApiKeyRepository struct {
+	*memoryRepository
+}
+
+func (r *memoryApiKeyRepository) FindByUserId(userId int) (apiKeys []models.ApiKey, err error) {
+	r.read(func(data *memoryData) {
+		apiKeys = data.apiKeys.list(func(apiKey models.ApiKey) bool {
+			return apiKey.UserId == userId
+		}, func(a, b models.ApiKey) bool {
+			return a.Id < b.Id
+		})
+	})
+	return apiKeys, nil
+}
+
+func (r *memoryApiKeyRepository) FindByHash(keyHash string) (apiKey models.ApiKey, err error) {
+	r.read(func(data *memoryData) {
+		for _, row := range data.apiKeys.rows {
+			if row.KeyHash == keyHash {
+				apiKey = row
+				return
+			}
+		}
+	})
+	return apiKey, nil
+}
+
+func (r *memoryApiKeyRepository) Create(apiKey *models.ApiKey) error {
+	return r.write(func(data *memoryData) error {
+		if _, ok := data.apiKeys.rows[apiKey.Id]; apiKey.Id != 0 && ok {
+			return ErrDuplicatedKey
+		}
+		// mirrors the unique index on key_hash
+		for _, row := range data.apiKeys.rows {
+			if row.KeyHash == apiKey.KeyHash {
+				return ErrDuplicatedKey
+			}
+		}
+
+		now := time.Now()
+		apiKey.Id = data.apiKeys.nextId(apiKey.Id)
+		if apiKey.CreatedAt.IsZero() {
+			apiKey.CreatedAt = now
+		}
+		if apiKey.UpdatedAt.IsZero() {
+			apiKey.UpdatedAt = now
+		}
+		data.apiKeys.rows[apiKey.Id] = *apiKey
+		return nil
+	})
+}
+
+func (r *memoryApiKeyRepository) Delete(id, userId int) (deleted int64, err error) {
+	err = r.write(func(data *memoryData) error {
+		deleted = data.apiKeys.deleteWhere(func(apiKey models.ApiKey) bool {
+			return apiKey.Id == id && apiKey.UserId == userId
+		})
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *memoryApiKeyRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.apiKeys.truncate(drop)
+		return nil
+	})
+}
@@ -0,0 +1,452 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryEpisodeRepository struct {
+	*memoryRepository
+}
+
+func episodeLessByTmdbIdSeasonEpisode(a, b models.Episode) bool {
+	if a.TmdbId != b.TmdbId {
+		return a.TmdbId < b.TmdbId
+	}
+	if a.Season != b.Season {
+		return seasonLess(a.Season, b.Season)
+	}
+	return a.Episode < b.Episode
+}
+
+// seasonLess puts the specials of season 0 after the other seasons.
+func seasonLess(a, b int) bool {
+	if (a == 0) != (b == 0) {
+		return b == 0
+	}
+	return a < b
+}
+
+func (r *memoryEpisodeRepository) findWhere(filter func(episode models.Episode) bool) (episodes []models.Episode, err error) {
+	r.read(func(data *memoryData) {
+		episodes = data.episodesOf(r.userId).list(filter, episodeLessByTmdbIdSeasonEpisode)
+	})
+	return episodes, nil
+}
+
+func (r *memoryEpisodeRepository) FindAll() ([]models.Episode, error) {
+	return r.findWhere(nil)
+}
+
+func (r *memoryEpisodeRepository) List(opts ListOptions) (episodes []models.Episode, total int64, err error) {
+	r.read(func(data *memoryData) {
+		filter := episodeFilter(data, opts.Filter)
+		less := memoryLess(opts.sortOrDefault(episodeDefaultSort), episodeValue, func(e models.Episode) int { return e.Id })
+		episodes = data.episodesOf(r.userId).list(filter, less)
+	})
+	return memoryPage(episodes, opts), int64(len(episodes)), nil
+}
+
+func episodeValue(episode models.Episode, field string) interface{} {
+	switch field {
+	case "id":
+		return episode.Id
+	case "tmdb_id":
+		return episode.TmdbId
+	case "season":
+		// the specials of season 0 go after the other seasons
+		if episode.Season == 0 {
+			return math.MaxInt
+		}
+		return episode.Season
+	case "episode":
+		return episode.Episode
+	case "name":
+		return episode.Name
+	case "air_date":
+		return int(episode.AirDate)
+	case "watched":
+		return episode.Watched
+	case "watched_date":
+		return int(episode.WatchedDate)
+	case "created_at":
+		return episode.CreatedAt
+	case "updated_at":
+		return episode.UpdatedAt
+	}
+	return 0
+}
+
+func episodeFilter(data *memoryData, filter ListFilter) func(episode models.Episode) bool {
+	return func(episode models.Episode) bool {
+		if filter.Name != "" && !memoryNameContains(episode.Name, filter.Name) {
+			return false
+		}
+		if filter.TmdbId > 0 && episode.TmdbId != filter.TmdbId {
+			return false
+		}
+		if filter.Season > 0 && episode.Season != filter.Season {
+			return false
+		}
+		if filter.ExcludeSpecials && episode.Season == 0 {
+			return false
+		}
+		if filter.Watched != nil && episode.Watched != *filter.Watched {
+			return false
+		}
+		if !filter.airDateInRange(episode.AirDate) {
+			return false
+		}
+
+		if filter.GroupType > 0 || filter.Status > 0 {
+			for _, tvShow := range data.tvShows.rows {
+				if tvShow.TmdbId != episode.TmdbId {
+					continue
+				}
+				return (filter.GroupType == 0 || tvShow.GroupType == filter.GroupType) &&
+					(filter.Status == 0 || tvShow.Status == filter.Status)
+			}
+			return false
+		}
+		return true
+	}
+}
+
+func (r *memoryEpisodeRepository) FindById(id int) (episode models.Episode, err error) {
+	r.read(func(data *memoryData) {
+		episode = data.episodesOf(r.userId).rows[id]
+	})
+	return episode, nil
+}
+
+func (r *memoryEpisodeRepository) FindByKey(tmdbId, season, episode int) (models.Episode, error) {
+	episodes, err := r.findWhere(func(ep models.Episode) bool {
+		return ep.TmdbId == tmdbId && ep.Season == season && ep.Episode == episode
+	})
+	if err != nil || len(episodes) == 0 {
+		return models.Episode{}, err
+	}
+	return episodes[0], nil
+}
+
+func (r *memoryEpisodeRepository) FindByTmdbId(tmdbId int) ([]models.Episode, error) {
+	return r.findWhere(func(ep models.Episode) bool {
+		return ep.TmdbId == tmdbId
+	})
+}
+
+func (r *memoryEpisodeRepository) FindByTmdbIdAndSeason(tmdbId, season int) ([]models.Episode, error) {
+	return r.findWhere(func(ep models.Episode) bool {
+		return ep.TmdbId == tmdbId && ep.Season == season
+	})
+}
+
+func (r *memoryEpisodeRepository) FindUnwatched(specials bool) ([]models.Episode, error) {
+	return r.findWhere(func(ep models.Episode) bool {
+		return !ep.Watched && (specials || ep.Season > 0)
+	})
+}
+
+func (r *memoryEpisodeRepository) UnwatchedSummaries(specials bool) (map[int]UnwatchedSummary, error) {
+	episodes, err := r.FindUnwatched(specials)
+	if err != nil {
+		return nil, err
+	}
+
+	// episodes are sorted, so the first one of each show is the next to watch
+	summaries := make(map[int]UnwatchedSummary)
+	for _, episode := range episodes {
+		summary, ok := summaries[episode.TmdbId]
+		if !ok {
+			summary = UnwatchedSummary{TmdbId: episode.TmdbId, Season: episode.Season, Episode: episode.Episode}
+		}
+		summary.Total++
+		summaries[episode.TmdbId] = summary
+	}
+	return summaries, nil
+}
+
+func (r *memoryEpisodeRepository) FindUpNext(groupType models.GroupType, airedBy models.Date, specials bool) (rows []UpNext, err error) {
+	r.read(func(data *memoryData) {
+		names := make(map[int]string)
+		for _, tvShow := range data.tvShows.rows {
+			if groupType == 0 || tvShow.GroupType == groupType {
+				names[tvShow.TmdbId] = tvShow.Name
+			}
+		}
+
+		// episodes are sorted, so the first unwatched one of each show is the next to watch
+		lastWatched := make(map[int]models.Date)
+		next := make(map[int]models.Episode)
+		for _, episode := range data.episodesOf(r.userId).list(nil, episodeLessByTmdbIdSeasonEpisode) {
+			if episode.Watched && episode.WatchedDate > lastWatched[episode.TmdbId] {
+				lastWatched[episode.TmdbId] = episode.WatchedDate
+			}
+			if _, ok := next[episode.TmdbId]; !ok && !episode.Watched && episode.AirDate > 0 && episode.AirDate <= airedBy && (specials || episode.Season > 0) {
+				next[episode.TmdbId] = episode
+			}
+		}
+
+		for tmdbId, episode := range next {
+			if _, ok := names[tmdbId]; ok {
+				rows = append(rows, UpNext{Episode: episode, LastWatchedDate: lastWatched[tmdbId]})
+			}
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.LastWatchedDate != b.LastWatchedDate {
+				return a.LastWatchedDate > b.LastWatchedDate
+			}
+			return names[a.Episode.TmdbId] < names[b.Episode.TmdbId]
+		})
+	})
+	return rows, nil
+}
+
+func (r *memoryEpisodeRepository) Create(episode *models.Episode) error {
+	return r.write(func(data *memoryData) error {
+		return r.insert(data, episode, time.Now())
+	})
+}
+
+func (r *memoryEpisodeRepository) CreateMany(episodes []models.Episode) error {
+	return r.write(func(data *memoryData) error {
+		// restore the tables if any row fails so nothing is inserted
+		backup := data.clone()
+		now := time.Now()
+		for index := range episodes {
+			if err := r.insert(data, &episodes[index], now); err != nil {
+				data.episodes, data.watchStates, data.watchEvents = backup.episodes, backup.watchStates, backup.watchEvents
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryEpisodeRepository) CreateMissing(episodes []models.Episode) ([]bool, error) {
+	inserted := make([]bool, len(episodes))
+	err := r.write(func(data *memoryData) error {
+		now := time.Now()
+		for index := range episodes {
+			err := r.insert(data, &episodes[index], now)
+			if err != nil && !errors.Is(err, ErrDuplicatedKey) {
+				return err
+			}
+			inserted[index] = err == nil
+		}
+		return nil
+	})
+	return inserted, err
+}
+
+func (r *memoryEpisodeRepository) Upsert(episode *models.Episode) (created bool, err error) {
+	err = r.write(func(data *memoryData) error {
+		episode.Id = 0
+		for _, row := range data.episodes.rows {
+			if row.TmdbId == episode.TmdbId && row.Season == episode.Season && row.Episode == episode.Episode {
+				episode.Id, episode.CreatedAt = row.Id, row.CreatedAt
+			}
+		}
+
+		now := time.Now()
+		if episode.Id == 0 {
+			created = true
+			episode.CreatedAt, episode.UpdatedAt = time.Time{}, time.Time{}
+			return r.insert(data, episode, now)
+		}
+		return r.save(data, episode, now)
+	})
+	return created, err
+}
+
+func (r *memoryEpisodeRepository) UpsertIfUnchanged(episode *models.Episode, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		episode.Id = 0
+		for _, row := range data.episodes.rows {
+			if row.TmdbId == episode.TmdbId && row.Season == episode.Season && row.Episode == episode.Episode && row.UpdatedAt.Equal(updatedAt) {
+				episode.Id, episode.CreatedAt = row.Id, row.CreatedAt
+			}
+		}
+		if episode.Id == 0 {
+			return ErrStale
+		}
+		return r.save(data, episode, time.Now())
+	})
+}
+
+func (r *memoryEpisodeRepository) Save(episode *models.Episode) error {
+	return r.write(func(data *memoryData) error {
+		return r.save(data, episode, time.Now())
+	})
+}
+
+func (r *memoryEpisodeRepository) SaveIfUnchanged(episode *models.Episode, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		if row, ok := data.episodes.rows[episode.Id]; !ok || !row.UpdatedAt.Equal(updatedAt) {
+			return ErrStale
+		}
+		return r.save(data, episode, time.Now())
+	})
+}
+
+func (r *memoryEpisodeRepository) SaveMany(episodes []models.Episode) error {
+	return r.write(func(data *memoryData) error {
+		backup := data.clone()
+		now := time.Now()
+		for index := range episodes {
+			if err := r.save(data, &episodes[index], now); err != nil {
+				data.episodes, data.watchStates, data.watchEvents = backup.episodes, backup.watchStates, backup.watchEvents
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryEpisodeRepository) SaveWatchState(episode *models.Episode) error {
+	return r.SaveWatchStates([]models.Episode{*episode})
+}
+
+func (r *memoryEpisodeRepository) SaveWatchStates(episodes []models.Episode) error {
+	if r.userId == 0 {
+		return ErrNoUser
+	}
+	return r.write(func(data *memoryData) error {
+		now := time.Now()
+		for _, episode := range episodes {
+			data.saveWatchState(r.userId, episode, now)
+		}
+		return nil
+	})
+}
+
+func (r *memoryEpisodeRepository) Delete(id int) error {
+	return r.write(func(data *memoryData) error {
+		delete(data.episodes.rows, id)
+		data.deleteOrphanWatchStates()
+		return nil
+	})
+}
+
+func (r *memoryEpisodeRepository) DeleteIfUnchanged(id int, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		if row, ok := data.episodes.rows[id]; !ok || !row.UpdatedAt.Equal(updatedAt) {
+			return ErrStale
+		}
+		delete(data.episodes.rows, id)
+		data.deleteOrphanWatchStates()
+		return nil
+	})
+}
+
+func (r *memoryEpisodeRepository) DeleteByTmdbId(tmdbId int) (deleted int64, err error) {
+	err = r.write(func(data *memoryData) error {
+		deleted = data.episodes.deleteWhere(func(ep models.Episode) bool {
+			return ep.TmdbId == tmdbId
+		})
+		data.deleteOrphanWatchStates()
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *memoryEpisodeRepository) DeleteByTmdbIdAndSeason(tmdbId, season int) (deleted int64, err error) {
+	err = r.write(func(data *memoryData) error {
+		deleted = data.episodes.deleteWhere(func(ep models.Episode) bool {
+			return ep.TmdbId == tmdbId && ep.Season == season
+		})
+		data.deleteOrphanWatchStates()
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *memoryEpisodeRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		// watch states and events point to episode ids, which may start over
+		data.watchStates.truncate(drop)
+		data.watchEvents.truncate(drop)
+		data.episodes.truncate(drop)
+		return nil
+	})
+}
+
+// insert and save store the episode row without its watched fields, which go
+// to the watch state of the repository user when there is one.
+func (r *memoryEpisodeRepository) insert(data *memoryData, episode *models.Episode, now time.Time) error {
+	if err := insertEpisode(data, episode, now); err != nil {
+		return err
+	}
+	if r.userId != 0 {
+		data.saveWatchState(r.userId, *episode, now)
+	}
+	return nil
+}
+
+func (r *memoryEpisodeRepository) save(data *memoryData, episode *models.Episode, now time.Time) error {
+	if err := saveEpisode(data, episode, now); err != nil {
+		return err
+	}
+	if r.userId != 0 {
+		data.saveWatchState(r.userId, *episode, now)
+	}
+	return nil
+}
+
+// checkEpisodeUnique mirrors the unique index on (tmdb_id, season, episode).
+func checkEpisodeUnique(table *memoryTable[models.Episode], episode *models.Episode) error {
+	for id, row := range table.rows {
+		if id == episode.Id {
+			continue
+		}
+		if row.TmdbId == episode.TmdbId && row.Season == episode.Season && row.Episode == episode.Episode {
+			return ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func insertEpisode(data *memoryData, episode *models.Episode, now time.Time) error {
+	if _, ok := data.episodes.rows[episode.Id]; episode.Id != 0 && ok {
+		return ErrDuplicatedKey
+	}
+	if err := checkEpisodeUnique(data.episodes, episode); err != nil {
+		return err
+	}
+
+	episode.Id = data.episodes.nextId(episode.Id)
+	if episode.CreatedAt.IsZero() {
+		episode.CreatedAt = now
+	}
+	if episode.UpdatedAt.IsZero() {
+		episode.UpdatedAt = now
+	}
+	data.episodes.rows[episode.Id] = catalogEpisode(*episode)
+	return nil
+}
+
+func saveEpisode(data *memoryData, episode *models.Episode, now time.Time) error {
+	if _, ok := data.episodes.rows[episode.Id]; episode.Id == 0 || !ok {
+		return insertEpisode(data, episode, now)
+	}
+
+	if err := checkEpisodeUnique(data.episodes, episode); err != nil {
+		return err
+	}
+	episode.UpdatedAt = now
+	data.episodes.rows[episode.Id] = catalogEpisode(*episode)
+	return nil
+}
+
+// catalogEpisode drops the fields kept in the watch states, like the columns
+// the gorm repository never writes.
+func catalogEpisode(episode models.Episode) models.Episode {
+	episode.Watched = false
+	episode.WatchedDate = 0
+	return episode
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memorySeasonRepository struct {
+	*memoryRepository
+}
+
+func (r *memorySeasonRepository) FindAll() (seasons []models.Season, err error) {
+	r.read(func(data *memoryData) {
+		seasons = data.seasons.list(nil, func(a, b models.Season) bool {
+			if a.TmdbId != b.TmdbId {
+				return a.TmdbId < b.TmdbId
+			}
+			return seasonLess(a.Number, b.Number)
+		})
+	})
+	return seasons, nil
+}
+
+func (r *memorySeasonRepository) FindByTmdbId(tmdbId int) (seasons []models.Season, err error) {
+	r.read(func(data *memoryData) {
+		seasons = data.seasons.list(func(season models.Season) bool {
+			return season.TmdbId == tmdbId
+		}, func(a, b models.Season) bool {
+			return seasonLess(a.Number, b.Number)
+		})
+	})
+	return seasons, nil
+}
+
+func (r *memorySeasonRepository) FindByKey(tmdbId, number int) (season models.Season, err error) {
+	r.read(func(data *memoryData) {
+		for _, row := range data.seasons.rows {
+			if row.TmdbId == tmdbId && row.Number == number {
+				season = row
+				return
+			}
+		}
+	})
+	return season, nil
+}
+
+func (r *memorySeasonRepository) Create(season *models.Season) error {
+	return r.write(func(data *memoryData) error {
+		return insertSeason(data, season, time.Now())
+	})
+}
+
+func (r *memorySeasonRepository) Save(season *models.Season) error {
+	return r.write(func(data *memoryData) error {
+		if _, ok := data.seasons.rows[season.Id]; season.Id == 0 || !ok {
+			return insertSeason(data, season, time.Now())
+		}
+
+		if err := checkSeasonUnique(data.seasons, season); err != nil {
+			return err
+		}
+		season.UpdatedAt = time.Now()
+		data.seasons.rows[season.Id] = *season
+		return nil
+	})
+}
+
+func (r *memorySeasonRepository) Delete(id int) error {
+	return r.write(func(data *memoryData) error {
+		delete(data.seasons.rows, id)
+		return nil
+	})
+}
+
+func (r *memorySeasonRepository) DeleteByTmdbId(tmdbId int) (deleted int64, err error) {
+	err = r.write(func(data *memoryData) error {
+		deleted = data.seasons.deleteWhere(func(season models.Season) bool {
+			return season.TmdbId == tmdbId
+		})
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *memorySeasonRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.seasons.truncate(drop)
+		return nil
+	})
+}
+
+// checkSeasonUnique mirrors the unique index on (tmdb_id, number).
+func checkSeasonUnique(table *memoryTable[models.Season], season *models.Season) error {
+	for id, row := range table.rows {
+		if id == season.Id {
+			continue
+		}
+		if row.TmdbId == season.TmdbId && row.Number == season.Number {
+			return ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func insertSeason(data *memoryData, season *models.Season, now time.Time) error {
+	if _, ok := data.seasons.rows[season.Id]; season.Id != 0 && ok {
+		return ErrDuplicatedKey
+	}
+	if err := checkSeasonUnique(data.seasons, season); err != nil {
+		return err
+	}
+
+	season.Id = data.seasons.nextId(season.Id)
+	if season.CreatedAt.IsZero() {
+		season.CreatedAt = now
+	}
+	if season.UpdatedAt.IsZero() {
+		season.UpdatedAt = now
+	}
+	data.seasons.rows[season.Id] = *season
+	return nil
+}
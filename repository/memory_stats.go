@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryStatsRepository struct {
+	*memoryRepository
+}
+
+func (r *memoryStatsRepository) Summary(watched StatsRange) (stats Stats, err error) {
+	r.read(func(data *memoryData) {
+		episodes := data.episodesOf(r.userId).list(nil, episodeLessByTmdbIdSeasonEpisode)
+		tvShows := data.tvShows.list(nil, func(a, b models.TvShow) bool {
+			return a.Name < b.Name
+		})
+
+		// every watch event counts, rewatches too, on its day in UTC
+		watches := make(map[int]int64)
+		months := make(map[int]int64)
+		years := make(map[int]int64)
+		days := make(map[models.Date]bool)
+		for _, event := range data.watchEvents.rows {
+			episode, ok := data.episodes.rows[event.EpisodeId]
+			day := watchedDateOf(event.WatchedAt)
+			if event.UserId != r.userId || !ok || !watched.includes(day) {
+				continue
+			}
+			stats.Watched++
+			watches[episode.TmdbId]++
+			months[int(day)/100]++
+			years[int(day)/10000]++
+			days[day] = true
+		}
+
+		// the episodes watched before watched dates were kept have no event
+		for _, episode := range episodes {
+			if episode.Watched && episode.WatchedDate.IsZero() && watched.includes(0) {
+				stats.Watched++
+				watches[episode.TmdbId]++
+			}
+		}
+		stats.PerMonth = memoryPeriods(months, func(period int) string {
+			return fmt.Sprintf("%04d-%02d", period/100, period%100)
+		})
+		stats.PerYear = memoryPeriods(years, func(period int) string {
+			return fmt.Sprintf("%04d", period)
+		})
+
+		groups := make(map[int]*StatsCount)
+		statuses := make(map[int]*StatsCount)
+		stats.PerTvShow = make([]TvShowStats, 0, len(tvShows))
+		for _, tvShow := range tvShows {
+			show := TvShowStats{TmdbId: tvShow.TmdbId, Name: tvShow.Name, Watched: watches[tvShow.TmdbId]}
+			var watchedEver int64
+			for _, episode := range episodes {
+				if episode.TmdbId != tvShow.TmdbId {
+					continue
+				}
+				show.Episodes++
+				if episode.Watched {
+					watchedEver++
+				}
+			}
+			if show.Episodes > 0 {
+				// one decimal, like the sql round
+				show.Completion = math.Round(1000*float64(watchedEver)/float64(show.Episodes)) / 10
+			}
+			stats.PerTvShow = append(stats.PerTvShow, show)
+
+			memoryCount(groups, int(tvShow.GroupType), show.Watched)
+			memoryCount(statuses, int(tvShow.Status), show.Watched)
+		}
+		stats.ByGroup = memoryCounts(groups)
+		stats.ByStatus = memoryCounts(statuses)
+
+		stats.LongestStreak = memoryLongestStreak(days)
+	})
+	return stats, nil
+}
+
+func memoryPeriods(counts map[int]int64, format func(period int) string) []StatsPeriod {
+	periods := make([]int, 0, len(counts))
+	for period := range counts {
+		periods = append(periods, period)
+	}
+	sort.Ints(periods)
+
+	stats := make([]StatsPeriod, 0, len(periods))
+	for _, period := range periods {
+		stats = append(stats, StatsPeriod{Period: format(period), Watched: counts[period]})
+	}
+	return stats
+}
+
+func memoryCount(counts map[int]*StatsCount, value int, watched int64) {
+	count, ok := counts[value]
+	if !ok {
+		count = &StatsCount{Value: value}
+		counts[value] = count
+	}
+	count.TvShows++
+	count.Watched += watched
+}
+
+func memoryCounts(counts map[int]*StatsCount) []StatsCount {
+	stats := make([]StatsCount, 0, len(counts))
+	for _, count := range counts {
+		stats = append(stats, *count)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Value < stats[j].Value
+	})
+	return stats
+}
+
+func memoryLongestStreak(days map[models.Date]bool) Streak {
+	dates := make([]models.Date, 0, len(days))
+	for day := range days {
+		dates = append(dates, day)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i] < dates[j]
+	})
+
+	var longest, current Streak
+	for _, day := range dates {
+		if current.Days > 0 && current.End.AddDays(1) == day {
+			current.Days++
+			current.End = day
+		} else {
+			current = Streak{Days: 1, Start: day, End: day}
+		}
+		// dates go forward, so the most recent streak wins a tie
+		if current.Days >= longest.Days {
+			longest = current
+		}
+	}
+	return longest
+}
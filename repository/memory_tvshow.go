@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryTvShowRepository struct {
+	*memoryRepository
+}
+
+func tvShowLessByName(a, b models.TvShow) bool {
+	return a.Name < b.Name
+}
+
+func (r *memoryTvShowRepository) FindAll() (tvShows []models.TvShow, err error) {
+	r.read(func(data *memoryData) {
+		tvShows = data.tvShows.list(nil, tvShowLessByName)
+	})
+	return tvShows, nil
+}
+
+func (r *memoryTvShowRepository) List(opts ListOptions) (tvShows []models.TvShow, total int64, err error) {
+	r.read(func(data *memoryData) {
+		filter := tvShowFilter(data.episodesOf(r.userId), opts.Filter)
+		less := memoryLess(opts.sortOrDefault(tvShowDefaultSort), tvShowValue, func(t models.TvShow) int { return t.Id })
+		tvShows = data.tvShows.list(filter, less)
+	})
+	return memoryPage(tvShows, opts), int64(len(tvShows)), nil
+}
+
+func tvShowValue(tvShow models.TvShow, field string) interface{} {
+	switch field {
+	case "id":
+		return tvShow.Id
+	case "tmdb_id":
+		return tvShow.TmdbId
+	case "name":
+		return tvShow.Name
+	case "group":
+		return int(tvShow.GroupType)
+	case "status":
+		return int(tvShow.Status)
+	case "created_at":
+		return tvShow.CreatedAt
+	case "updated_at":
+		return tvShow.UpdatedAt
+	}
+	return 0
+}
+
+func tvShowFilter(episodes *memoryTable[models.Episode], filter ListFilter) func(tvShow models.TvShow) bool {
+	return func(tvShow models.TvShow) bool {
+		if filter.Name != "" && !memoryNameContains(tvShow.Name, filter.Name) {
+			return false
+		}
+		if filter.TmdbId > 0 && tvShow.TmdbId != filter.TmdbId {
+			return false
+		}
+		if filter.GroupType > 0 && tvShow.GroupType != filter.GroupType {
+			return false
+		}
+		if filter.Status > 0 && tvShow.Status != filter.Status {
+			return false
+		}
+
+		if filter.Watched == nil && !filter.hasAirDate() {
+			return true
+		}
+
+		hasUnwatched, hasAiring := false, false
+		for _, episode := range episodes.rows {
+			if episode.TmdbId != tvShow.TmdbId {
+				continue
+			}
+			if !episode.Watched {
+				hasUnwatched = true
+			}
+			if filter.airDateInRange(episode.AirDate) {
+				hasAiring = true
+			}
+		}
+
+		// watched shows have no unwatched episode left
+		if filter.Watched != nil && *filter.Watched == hasUnwatched {
+			return false
+		}
+		if filter.hasAirDate() && !hasAiring {
+			return false
+		}
+		return true
+	}
+}
+
+func (r *memoryTvShowRepository) FindById(id int) (tvShow models.TvShow, err error) {
+	r.read(func(data *memoryData) {
+		tvShow = data.tvShows.rows[id]
+	})
+	return tvShow, nil
+}
+
+func (r *memoryTvShowRepository) FindByTmdbId(tmdbId int) (tvShow models.TvShow, err error) {
+	r.read(func(data *memoryData) {
+		for _, row := range data.tvShows.rows {
+			if row.TmdbId == tmdbId {
+				tvShow = row
+				return
+			}
+		}
+	})
+	return tvShow, nil
+}
+
+func (r *memoryTvShowRepository) Create(tvShow *models.TvShow) error {
+	return r.write(func(data *memoryData) error {
+		return insertTvShow(data, tvShow, time.Now())
+	})
+}
+
+func (r *memoryTvShowRepository) CreateMany(tvShows []models.TvShow) error {
+	return r.write(func(data *memoryData) error {
+		// restore the table if any row fails so nothing is inserted
+		backup := data.tvShows.clone()
+		now := time.Now()
+		for index := range tvShows {
+			if err := insertTvShow(data, &tvShows[index], now); err != nil {
+				data.tvShows = backup
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTvShowRepository) CreateMissing(tvShows []models.TvShow) ([]bool, error) {
+	inserted := make([]bool, len(tvShows))
+	err := r.write(func(data *memoryData) error {
+		now := time.Now()
+		for index := range tvShows {
+			err := insertTvShow(data, &tvShows[index], now)
+			if err != nil && !errors.Is(err, ErrDuplicatedKey) {
+				return err
+			}
+			inserted[index] = err == nil
+		}
+		return nil
+	})
+	return inserted, err
+}
+
+func (r *memoryTvShowRepository) Upsert(tvShow *models.TvShow) (created bool, err error) {
+	err = r.write(func(data *memoryData) error {
+		tvShow.Id = 0
+		for _, row := range data.tvShows.rows {
+			if row.TmdbId == tvShow.TmdbId {
+				tvShow.Id, tvShow.CreatedAt = row.Id, row.CreatedAt
+			}
+		}
+
+		now := time.Now()
+		if tvShow.Id == 0 {
+			created = true
+			tvShow.CreatedAt, tvShow.UpdatedAt = time.Time{}, time.Time{}
+			return insertTvShow(data, tvShow, now)
+		}
+
+		if err := checkTvShowUnique(data.tvShows, tvShow); err != nil {
+			return err
+		}
+		tvShow.UpdatedAt = now
+		data.tvShows.rows[tvShow.Id] = *tvShow
+		return nil
+	})
+	return created, err
+}
+
+func (r *memoryTvShowRepository) UpsertIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		tvShow.Id = 0
+		for _, row := range data.tvShows.rows {
+			if row.TmdbId == tvShow.TmdbId && row.UpdatedAt.Equal(updatedAt) {
+				tvShow.Id, tvShow.CreatedAt = row.Id, row.CreatedAt
+			}
+		}
+		if tvShow.Id == 0 {
+			return ErrStale
+		}
+		return saveTvShow(data, tvShow, time.Now())
+	})
+}
+
+func (r *memoryTvShowRepository) Save(tvShow *models.TvShow) error {
+	return r.write(func(data *memoryData) error {
+		return saveTvShow(data, tvShow, time.Now())
+	})
+}
+
+func (r *memoryTvShowRepository) SaveIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		if row, ok := data.tvShows.rows[tvShow.Id]; !ok || !row.UpdatedAt.Equal(updatedAt) {
+			return ErrStale
+		}
+		return saveTvShow(data, tvShow, time.Now())
+	})
+}
+
+func (r *memoryTvShowRepository) Delete(id int) error {
+	return r.write(func(data *memoryData) error {
+		delete(data.tvShows.rows, id)
+		return nil
+	})
+}
+
+func (r *memoryTvShowRepository) DeleteIfUnchanged(id int, updatedAt time.Time) error {
+	return r.write(func(data *memoryData) error {
+		if row, ok := data.tvShows.rows[id]; !ok || !row.UpdatedAt.Equal(updatedAt) {
+			return ErrStale
+		}
+		delete(data.tvShows.rows, id)
+		return nil
+	})
+}
+
+func (r *memoryTvShowRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.tvShows.truncate(drop)
+		return nil
+	})
+}
+
+// checkTvShowUnique mirrors the unique indexes on tmdb_id and name.
+func checkTvShowUnique(table *memoryTable[models.TvShow], tvShow *models.TvShow) error {
+	for id, row := range table.rows {
+		if id == tvShow.Id {
+			continue
+		}
+		if row.TmdbId == tvShow.TmdbId || row.Name == tvShow.Name {
+			return ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func saveTvShow(data *memoryData, tvShow *models.TvShow, now time.Time) error {
+	if _, ok := data.tvShows.rows[tvShow.Id]; tvShow.Id == 0 || !ok {
+		return insertTvShow(data, tvShow, now)
+	}
+
+	if err := checkTvShowUnique(data.tvShows, tvShow); err != nil {
+		return err
+	}
+	tvShow.UpdatedAt = now
+	data.tvShows.rows[tvShow.Id] = *tvShow
+	return nil
+}
+
+func insertTvShow(data *memoryData, tvShow *models.TvShow, now time.Time) error {
+	if _, ok := data.tvShows.rows[tvShow.Id]; tvShow.Id != 0 && ok {
+		return ErrDuplicatedKey
+	}
+	if err := checkTvShowUnique(data.tvShows, tvShow); err != nil {
+		return err
+	}
+
+	tvShow.Id = data.tvShows.nextId(tvShow.Id)
+	if tvShow.CreatedAt.IsZero() {
+		tvShow.CreatedAt = now
+	}
+	if tvShow.UpdatedAt.IsZero() {
+		tvShow.UpdatedAt = now
+	}
+	data.tvShows.rows[tvShow.Id] = *tvShow
+	return nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryUserRepository struct {
+	*memoryRepository
+}
+
+func (r *memoryUserRepository) Count() (count int64, err error) {
+	r.read(func(data *memoryData) {
+		count = int64(len(data.users.rows))
+	})
+	return count, nil
+}
+
+// CountForUpdate is Count, transactions already hold the store lock.
+func (r *memoryUserRepository) CountForUpdate() (int64, error) {
+	return r.Count()
+}
+
+func (r *memoryUserRepository) FindById(id int) (user models.User, err error) {
+	r.read(func(data *memoryData) {
+		user = data.users.rows[id]
+	})
+	return user, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(username string) (user models.User, err error) {
+	r.read(func(data *memoryData) {
+		for _, row := range data.users.rows {
+			if row.Username == username {
+				user = row
+				return
+			}
+		}
+	})
+	return user, nil
+}
+
+func (r *memoryUserRepository) FindByCalendarTokenHash(tokenHash string) (user models.User, err error) {
+	if tokenHash == "" {
+		return user, nil
+	}
+	r.read(func(data *memoryData) {
+		for _, row := range data.users.rows {
+			if row.CalendarTokenHash == tokenHash {
+				user = row
+				return
+			}
+		}
+	})
+	return user, nil
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	return r.write(func(data *memoryData) error {
+		return insertUser(data, user, time.Now())
+	})
+}
+
+func (r *memoryUserRepository) Save(user *models.User) error {
+	return r.write(func(data *memoryData) error {
+		if _, ok := data.users.rows[user.Id]; user.Id == 0 || !ok {
+			return insertUser(data, user, time.Now())
+		}
+
+		if err := checkUserUnique(data.users, user); err != nil {
+			return err
+		}
+		user.UpdatedAt = time.Now()
+		data.users.rows[user.Id] = *user
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.users.truncate(drop)
+		return nil
+	})
+}
+
+// checkUserUnique mirrors the unique index on username.
+func checkUserUnique(table *memoryTable[models.User], user *models.User) error {
+	for id, row := range table.rows {
+		if id == user.Id {
+			continue
+		}
+		if row.Username == user.Username {
+			return ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func insertUser(data *memoryData, user *models.User, now time.Time) error {
+	if _, ok := data.users.rows[user.Id]; user.Id != 0 && ok {
+		return ErrDuplicatedKey
+	}
+	if err := checkUserUnique(data.users, user); err != nil {
+		return err
+	}
+
+	user.Id = data.users.nextId(user.Id)
+	if user.Role == "" {
+		// mirrors the column default
+		user.Role = models.UserRoleViewer
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	data.users.rows[user.Id] = *user
+	return nil
+}
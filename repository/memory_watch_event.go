@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryWatchEventRepository struct {
+	*memoryRepository
+}
+
+func watchEventLessByNewest(a, b models.WatchEvent) bool {
+	if !a.WatchedAt.Equal(b.WatchedAt) {
+		return a.WatchedAt.After(b.WatchedAt)
+	}
+	return a.Id > b.Id
+}
+
+// findWhere returns the events of the repository user accepted by filter,
+// with the key of their episode filled in like the gorm view.
+func (r *memoryWatchEventRepository) findWhere(filter func(event models.WatchEvent) bool) (events []models.WatchEvent, err error) {
+	r.read(func(data *memoryData) {
+		events = data.watchEvents.list(func(event models.WatchEvent) bool {
+			if event.UserId != r.userId {
+				return false
+			}
+			episode, ok := data.episodes.rows[event.EpisodeId]
+			if !ok {
+				return false
+			}
+			event.TmdbId, event.Season, event.Episode = episode.TmdbId, episode.Season, episode.Episode
+			return filter == nil || filter(event)
+		}, watchEventLessByNewest)
+
+		for index, event := range events {
+			episode := data.episodes.rows[event.EpisodeId]
+			event.TmdbId, event.Season, event.Episode = episode.TmdbId, episode.Season, episode.Episode
+			events[index] = event
+		}
+	})
+	return events, nil
+}
+
+func (r *memoryWatchEventRepository) FindById(id int) (models.WatchEvent, error) {
+	events, err := r.findWhere(func(event models.WatchEvent) bool {
+		return event.Id == id
+	})
+	if err != nil || len(events) == 0 {
+		return models.WatchEvent{}, err
+	}
+	return events[0], nil
+}
+
+func (r *memoryWatchEventRepository) FindByEpisodeId(episodeId int) ([]models.WatchEvent, error) {
+	return r.findWhere(func(event models.WatchEvent) bool {
+		return event.EpisodeId == episodeId
+	})
+}
+
+func (r *memoryWatchEventRepository) FindByTmdbId(tmdbId int) ([]models.WatchEvent, error) {
+	return r.findWhere(func(event models.WatchEvent) bool {
+		return event.TmdbId == tmdbId
+	})
+}
+
+func (r *memoryWatchEventRepository) Create(event *models.WatchEvent) error {
+	if r.userId == 0 {
+		return ErrNoUser
+	}
+	return r.write(func(data *memoryData) error {
+		now := time.Now()
+		event.Id = 0
+		event.UserId = r.userId
+		event.CreatedAt = time.Time{}
+		*event = data.insertWatchEvent(*event, now)
+		data.followLatestEvent(r.userId, event.EpisodeId, true, now)
+		return nil
+	})
+}
+
+func (r *memoryWatchEventRepository) Delete(id int) (deleted int64, err error) {
+	if r.userId == 0 {
+		return 0, ErrNoUser
+	}
+	err = r.write(func(data *memoryData) error {
+		event, ok := data.watchEvents.rows[id]
+		if !ok || event.UserId != r.userId {
+			return nil
+		}
+
+		delete(data.watchEvents.rows, id)
+		deleted = 1
+		data.followLatestEvent(r.userId, event.EpisodeId, false, time.Now())
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *memoryWatchEventRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.watchEvents.truncate(drop)
+		return nil
+	})
+}
+
+func (d *memoryData) insertWatchEvent(event models.WatchEvent, now time.Time) models.WatchEvent {
+	event.Id = d.watchEvents.nextId(event.Id)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	d.watchEvents.rows[event.Id] = event
+	return event
+}
+
+func (d *memoryData) latestWatchEvent(userId, episodeId int) (latest models.WatchEvent, found bool) {
+	for _, event := range d.watchEvents.rows {
+		if event.UserId != userId || event.EpisodeId != episodeId {
+			continue
+		}
+		if !found || event.WatchedAt.After(latest.WatchedAt) {
+			latest, found = event, true
+		}
+	}
+	return latest, found
+}
+
+// followLatestEvent mirrors gormFollowLatestEvent.
+func (d *memoryData) followLatestEvent(userId, episodeId int, added bool, now time.Time) {
+	watched := added
+	for _, state := range d.watchStates.rows {
+		if state.UserId == userId && state.EpisodeId == episodeId {
+			watched = watched || state.Watched
+		}
+	}
+
+	episode := models.Episode{Id: episodeId, Watched: watched}
+	if latest, ok := d.latestWatchEvent(userId, episodeId); ok {
+		episode.WatchedDate = watchedDateOf(latest.WatchedAt)
+	} else {
+		episode.Watched = false
+	}
+	d.writeWatchState(userId, episode, now)
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+type memoryWatchStateRepository struct {
+	*memoryRepository
+}
+
+// AdoptLegacy has nothing to adopt, the memory store never had watched
+// columns on its episodes.
+func (r *memoryWatchStateRepository) AdoptLegacy(userId int) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryWatchStateRepository) BackfillEvents() (recorded int64, err error) {
+	err = r.write(func(data *memoryData) error {
+		now := time.Now()
+		for _, state := range data.watchStates.rows {
+			if !state.Watched {
+				continue
+			}
+			if _, ok := data.latestWatchEvent(state.UserId, state.EpisodeId); ok {
+				continue
+			}
+
+			watchedAt := state.UpdatedAt
+			if state.WatchedDate > 0 {
+				watchedAt = state.WatchedDate.Time()
+			}
+			data.insertWatchEvent(models.WatchEvent{UserId: state.UserId, EpisodeId: state.EpisodeId, WatchedAt: watchedAt}, now)
+			recorded++
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+func (r *memoryWatchStateRepository) Truncate(drop bool) error {
+	return r.write(func(data *memoryData) error {
+		data.watchStates.truncate(drop)
+		return nil
+	})
+}
+
+// episodesOf returns a copy of the episodes table with the watched fields of
+// userId filled in.
+func (d *memoryData) episodesOf(userId int) *memoryTable[models.Episode] {
+	states := make(map[int]models.WatchState)
+	if userId != 0 {
+		for _, state := range d.watchStates.rows {
+			if state.UserId == userId {
+				states[state.EpisodeId] = state
+			}
+		}
+	}
+
+	episodes := d.episodes.clone()
+	for id, episode := range episodes.rows {
+		state := states[id]
+		episode.Watched = state.Watched
+		episode.WatchedDate = state.WatchedDate
+		episodes.rows[id] = episode
+	}
+	return episodes
+}
+
+// saveWatchState stores the watched fields of episode for userId, recording
+// a watch event for a new watched date like gormSaveWatchStates.
+func (d *memoryData) saveWatchState(userId int, episode models.Episode, now time.Time) {
+	if episode.Watched && episode.WatchedDate != 0 {
+		latest, ok := d.latestWatchEvent(userId, episode.Id)
+		if !ok || watchedDateOf(latest.WatchedAt) != episode.WatchedDate {
+			d.insertWatchEvent(models.WatchEvent{UserId: userId, EpisodeId: episode.Id, WatchedAt: watchedAtOf(episode.WatchedDate, now)}, now)
+		}
+	}
+	d.writeWatchState(userId, episode, now)
+}
+
+// writeWatchState mirrors the unique index on (user_id, episode_id). An
+// unwatched episode without a watched date has its watch state removed.
+func (d *memoryData) writeWatchState(userId int, episode models.Episode, now time.Time) {
+	for id, state := range d.watchStates.rows {
+		if state.UserId != userId || state.EpisodeId != episode.Id {
+			continue
+		}
+		if !episode.Watched && episode.WatchedDate == 0 {
+			delete(d.watchStates.rows, id)
+			return
+		}
+		state.Watched = episode.Watched
+		state.WatchedDate = episode.WatchedDate
+		state.UpdatedAt = now
+		d.watchStates.rows[id] = state
+		return
+	}
+
+	if !episode.Watched && episode.WatchedDate == 0 {
+		return
+	}
+	state := models.WatchState{
+		UserId:      userId,
+		EpisodeId:   episode.Id,
+		Watched:     episode.Watched,
+		WatchedDate: episode.WatchedDate,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	state.Id = d.watchStates.nextId(0)
+	d.watchStates.rows[state.Id] = state
+}
+
+// deleteOrphanWatchStates removes the watch states and events of deleted
+// episodes.
+func (d *memoryData) deleteOrphanWatchStates() {
+	d.watchStates.deleteWhere(func(state models.WatchState) bool {
+		_, ok := d.episodes.rows[state.EpisodeId]
+		return !ok
+	})
+	d.watchEvents.deleteWhere(func(event models.WatchEvent) bool {
+		_, ok := d.episodes.rows[event.EpisodeId]
+		return !ok
+	})
+}
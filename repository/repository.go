@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+var (
+	ErrDuplicatedKey = errors.New("duplicated key not allowed")
+	ErrNoUser        = errors.New("watch state needs a user")
+	ErrStale         = errors.New("row changed since it was read")
+)
+
+// Repository groups every data access used by the controllers. Lookups that
+// find nothing return a zero value (Id == 0) and a nil error, the same way
+// gorm's Find behaves.
+//
+// Episodes are shared by every user, but their Watched and WatchedDate fields
+// belong to one user. A repository returned by ForUser reads and writes them
+// from that user's watch state, any other repository sees every episode as
+// unwatched and ignores them on writes. Saving an episode as watched on a
+// date with no watch event yet records one in the user's watch history.
+type Repository interface {
+	TvShows() TvShowRepository
+	Episodes() EpisodeRepository
+	Seasons() SeasonRepository
+	Users() UserRepository
+	ApiKeys() ApiKeyRepository
+	WatchStates() WatchStateRepository
+	// WatchEvents reads and writes the watch history of the repository user.
+	WatchEvents() WatchEventRepository
+	// Stats aggregates the watch history of the repository user.
+	Stats() StatsRepository
+	// ForUser returns a repository bound to the watch state of userId.
+	ForUser(userId int) Repository
+	// Transaction runs fn against a repository bound to a single transaction.
+	// Returning an error from fn rolls back every change made inside it.
+	Transaction(fn func(repo Repository) error) error
+}
+
+// Truncater is implemented by every table repository. When drop is true the
+// table is dropped and created again, resetting its id sequence.
+type Truncater interface {
+	Truncate(drop bool) error
+}
+
+// UnwatchedSummary points to the next unwatched episode of a show and counts
+// every unwatched episode, the next one included.
+type UnwatchedSummary struct {
+	TmdbId  int
+	Season  int
+	Episode int
+	Total   int
+}
+
+// UpNext is the next episode to watch of a show. LastWatchedDate is the latest
+// date any episode of the show was watched on, zero when none was.
+type UpNext struct {
+	Episode         models.Episode `gorm:"embedded"`
+	LastWatchedDate models.Date
+}
+
+type TvShowRepository interface {
+	Truncater
+	FindAll() ([]models.TvShow, error)
+	// List returns one page of shows and the total matching the filter.
+	List(opts ListOptions) ([]models.TvShow, int64, error)
+	FindById(id int) (models.TvShow, error)
+	FindByTmdbId(tmdbId int) (models.TvShow, error)
+	Create(tvShow *models.TvShow) error
+	CreateMany(tvShows []models.TvShow) error
+	// CreateMissing inserts the shows whose tmdb id and name are both free and
+	// reports which ones it inserted. A show taken in the meantime is left out
+	// instead of failing the others.
+	CreateMissing(tvShows []models.TvShow) ([]bool, error)
+	// Upsert creates the show or updates the one with the same tmdb id in
+	// place, reporting whether it was created. A name taken by another show
+	// returns ErrDuplicatedKey.
+	Upsert(tvShow *models.TvShow) (bool, error)
+	// UpsertIfUnchanged updates the show with the same tmdb id in place like
+	// Upsert, only while it is still at updatedAt, and returns ErrStale
+	// otherwise. It never creates the show.
+	UpsertIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error
+	Save(tvShow *models.TvShow) error
+	// SaveIfUnchanged and DeleteIfUnchanged write only when the show is still
+	// at updatedAt, checked by the write itself, and return ErrStale otherwise.
+	SaveIfUnchanged(tvShow *models.TvShow, updatedAt time.Time) error
+	Delete(id int) error
+	DeleteIfUnchanged(id int, updatedAt time.Time) error
+}
+
+type EpisodeRepository interface {
+	Truncater
+	FindAll() ([]models.Episode, error)
+	// List returns one page of episodes and the total matching the filter.
+	List(opts ListOptions) ([]models.Episode, int64, error)
+	FindById(id int) (models.Episode, error)
+	FindByKey(tmdbId, season, episode int) (models.Episode, error)
+	FindByTmdbId(tmdbId int) ([]models.Episode, error)
+	FindByTmdbIdAndSeason(tmdbId, season int) ([]models.Episode, error)
+	// FindUnwatched returns the unwatched episodes of every show in one query.
+	// The specials of season 0 are left out unless specials is true.
+	FindUnwatched(specials bool) ([]models.Episode, error)
+	// UnwatchedSummaries aggregates the unwatched episodes of every show in
+	// one query, keyed by tmdb id. Shows without unwatched episodes are left out,
+	// and so are the specials of season 0 unless specials is true.
+	UnwatchedSummaries(specials bool) (map[int]UnwatchedSummary, error)
+	// FindUpNext returns the first unwatched episode of every show among the
+	// ones aired by airedBy, the most recently watched shows first and then
+	// by show name. Episodes without an air date have not aired yet.
+	// groupType 0 keeps every group, and the specials of season 0 are left
+	// out unless specials is true.
+	FindUpNext(groupType models.GroupType, airedBy models.Date, specials bool) ([]UpNext, error)
+	Create(episode *models.Episode) error
+	CreateMany(episodes []models.Episode) error
+	// CreateMissing inserts the episodes whose key is free and reports which
+	// ones it inserted. An episode taken in the meantime is left out instead of
+	// failing the others.
+	CreateMissing(episodes []models.Episode) ([]bool, error)
+	// Upsert creates the episode or updates the one with the same tmdb id,
+	// season and episode in place, reporting whether it was created. Watched
+	// and WatchedDate are written like Save does.
+	Upsert(episode *models.Episode) (bool, error)
+	// UpsertIfUnchanged updates the episode with the same key in place like
+	// Upsert, only while it is still at updatedAt, and returns ErrStale
+	// otherwise. It never creates the episode.
+	UpsertIfUnchanged(episode *models.Episode, updatedAt time.Time) error
+	Save(episode *models.Episode) error
+	// SaveIfUnchanged and DeleteIfUnchanged write only when the episode is
+	// still at updatedAt, checked by the write itself, and return ErrStale
+	// otherwise. The watch state is not part of the check.
+	SaveIfUnchanged(episode *models.Episode, updatedAt time.Time) error
+	SaveMany(episodes []models.Episode) error
+	// SaveWatchState stores only Watched and WatchedDate of the episode for
+	// the repository user, returning ErrNoUser when there is none.
+	SaveWatchState(episode *models.Episode) error
+	SaveWatchStates(episodes []models.Episode) error
+	// Delete and the DeleteBy methods remove the watch state of the deleted
+	// episodes too. Truncate does the same for every watch state.
+	Delete(id int) error
+	DeleteIfUnchanged(id int, updatedAt time.Time) error
+	DeleteByTmdbId(tmdbId int) (int64, error)
+	DeleteByTmdbIdAndSeason(tmdbId, season int) (int64, error)
+}
+
+// SeasonRepository keeps the seasons announced for each show. They are not
+// tied to the episodes stored, deleting a season keeps its episodes.
+type SeasonRepository interface {
+	Truncater
+	// FindAll returns every season ordered by tmdb id and number.
+	FindAll() ([]models.Season, error)
+	// FindByTmdbId returns the seasons of a show ordered by number.
+	FindByTmdbId(tmdbId int) ([]models.Season, error)
+	FindByKey(tmdbId, number int) (models.Season, error)
+	Create(season *models.Season) error
+	Save(season *models.Season) error
+	Delete(id int) error
+	DeleteByTmdbId(tmdbId int) (int64, error)
+}
+
+type UserRepository interface {
+	Truncater
+	Count() (int64, error)
+	// CountForUpdate counts the users in a Transaction and holds the other
+	// CountForUpdate calls back until it ends, so two registrations never
+	// both see no users.
+	CountForUpdate() (int64, error)
+	FindById(id int) (models.User, error)
+	FindByUsername(username string) (models.User, error)
+	// FindByCalendarTokenHash never matches an empty hash.
+	FindByCalendarTokenHash(tokenHash string) (models.User, error)
+	Create(user *models.User) error
+	Save(user *models.User) error
+}
+
+type ApiKeyRepository interface {
+	Truncater
+	FindByUserId(userId int) ([]models.ApiKey, error)
+	FindByHash(keyHash string) (models.ApiKey, error)
+	Create(apiKey *models.ApiKey) error
+	// Delete removes the key only when it belongs to userId, returning how
+	// many keys were removed.
+	Delete(id, userId int) (int64, error)
+}
+
+type WatchStateRepository interface {
+	Truncater
+	// AdoptLegacy gives userId the watched columns kept on the episodes from
+	// before accounts existed, then drops them. It returns how many watch
+	// states were adopted, 0 when there is nothing left to adopt.
+	AdoptLegacy(userId int) (int64, error)
+	// BackfillEvents records a watch event for every watched state without
+	// one, like the states saved before the watch history existed. It returns
+	// how many events were recorded.
+	BackfillEvents() (int64, error)
+}
+
+type StatsRepository interface {
+	// Summary computes every stat of the watches in the range. Shows
+	// are sorted by name, groups and statuses by value, and periods in order.
+	// The longest streak is the most recent one when several tie.
+	Summary(watched StatsRange) (Stats, error)
+}
+
+// WatchEventRepository keeps the watch history of one user. Creating or
+// deleting an event moves the watch state of its episode to the latest event
+// left: WatchedDate becomes its date, or 0 when there is none. Creating marks
+// the episode watched, deleting never does, and deleting the last event
+// leaves it unwatched. Writes return ErrNoUser without a user.
+type WatchEventRepository interface {
+	Truncater
+	FindById(id int) (models.WatchEvent, error)
+	// FindByEpisodeId and FindByTmdbId return the newest events first.
+	FindByEpisodeId(episodeId int) ([]models.WatchEvent, error)
+	FindByTmdbId(tmdbId int) ([]models.WatchEvent, error)
+	Create(event *models.WatchEvent) error
+	Delete(id int) (int64, error)
+}
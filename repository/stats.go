@@ -0,0 +1,67 @@
+package repository
+
+import "github.com/feealc/tvshows-backend-go/models"
+
+// StatsPeriod counts the watches of one month (YYYY-MM) or year (YYYY).
+type StatsPeriod struct {
+	Period  string `json:"period"`
+	Watched int64  `json:"watched"`
+}
+
+// TvShowStats reports one show. Watched counts its watches in the range,
+// Completion is the percentage of its episodes watched so far, no matter
+// when.
+type TvShowStats struct {
+	TmdbId     int     `json:"tmdb_id"`
+	Name       string  `json:"name"`
+	Episodes   int64   `json:"episodes"`
+	Watched    int64   `json:"watched"`
+	Completion float64 `json:"completion"`
+}
+
+// StatsCount counts the shows with one GroupType or Status and their watches
+// in the range. Name is the name of Value, set by the caller.
+type StatsCount struct {
+	Value   int    `json:"value"`
+	Name    string `json:"name" gorm:"-"`
+	TvShows int64  `json:"tv_shows"`
+	Watched int64  `json:"watched"`
+}
+
+// Streak is a run of consecutive days with at least one episode watched.
+type Streak struct {
+	Days  int64       `json:"days"`
+	Start models.Date `json:"start"`
+	End   models.Date `json:"end"`
+}
+
+// Stats aggregates the watch history of one user, where each watch event is
+// a watch and a rewatch counts again. Episodes watched before watched dates
+// were kept have no event, their watch state only counts when the range has
+// no start, and never in the periods or the streak.
+type Stats struct {
+	Watched       int64         `json:"watched"`
+	PerMonth      []StatsPeriod `json:"per_month"`
+	PerYear       []StatsPeriod `json:"per_year"`
+	PerTvShow     []TvShowStats `json:"per_tv_show"`
+	ByGroup       []StatsCount  `json:"by_group"`
+	ByStatus      []StatsCount  `json:"by_status"`
+	LongestStreak Streak        `json:"longest_streak"`
+}
+
+// StatsRange limits the stats to the episodes watched between From and To,
+// both included. Zero values are ignored.
+type StatsRange struct {
+	From models.Date
+	To   models.Date
+}
+
+func (r StatsRange) includes(watchedDate models.Date) bool {
+	if r.From > 0 && watchedDate < r.From {
+		return false
+	}
+	if r.To > 0 && watchedDate > r.To {
+		return false
+	}
+	return true
+}
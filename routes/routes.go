@@ -1,12 +1,32 @@
 package routes
 
 import (
+	"github.com/feealc/tvshows-backend-go/auth"
 	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tmdb"
 	"github.com/gin-gonic/gin"
 )
 
-func HandleRequests() {
+func HandleRequests(repo repository.Repository, tmdbClient *tmdb.Client, signer *auth.Signer) {
 	r := gin.Default()
+	SetUpRoutes(r, repo, tmdbClient, signer)
+	r.Run(":8080")
+}
+
+// SetUpRoutes registers every route on r. Each authenticated route declares
+// the least role it needs: viewers read, editors create and edit, admins
+// delete and truncate.
+func SetUpRoutes(r *gin.Engine, repo repository.Repository, tmdbClient *tmdb.Client, signer *auth.Signer) {
+	r.Use(controllers.UseRepository(repo))
+	r.Use(controllers.UseTmdbClient(tmdbClient))
+	r.Use(controllers.UseSigner(signer))
+	r.Use(controllers.UseDateFormat)
+
+	viewer := controllers.RequireRole(models.UserRoleViewer)
+	editor := controllers.RequireRole(models.UserRoleEditor)
+	admin := controllers.RequireRole(models.UserRoleAdmin)
 
 	api := r.Group("/api")
 	{
@@ -15,37 +35,105 @@ func HandleRequests() {
 			// Health
 			v1.GET("/health", controllers.Health)
 
+			// Meta, the enums clients can discover
+			v1.GET("/meta/enums", controllers.MetaEnums)
+
+			// Users
+			// the first user registers alone, an admin registers the others
+			v1.POST("/users/register", controllers.AuthenticateRegistration, controllers.UserRegister)
+			v1.POST("/users/login", controllers.UserLogin)
+
+			// Calendar feed, calendar apps send the calendar token in the url
+			v1.GET("/calendar.ics", controllers.AuthenticateCalendar, viewer, controllers.CalendarIcs)
+		}
+
+		// every other route needs a login token or an API key
+		v1 = v1.Group("", controllers.Authenticate)
+		{
+			// Users
+			v1.GET("/users/me", viewer, controllers.UserMe)
+			v1.POST("/users/me/calendar-token", viewer, controllers.UserCalendarTokenCreate)
+			v1.DELETE("/users/me/calendar-token", viewer, controllers.UserCalendarTokenDelete)
+			v1.PUT("/users/:id/role", admin, controllers.UserEditRole)
+
+			// ApiKeys
+			v1.GET("/apikeys", viewer, controllers.ApiKeyListAll)
+			v1.POST("/apikeys", viewer, controllers.ApiKeyCreate)
+			v1.DELETE("/apikeys/:id", viewer, controllers.ApiKeyDelete)
+
 			// TvShows
-			v1.GET("/tvshows", controllers.TvShowListAll)
-			v1.GET("/tvshows/episodes", controllers.TvShowListAllUnwatchedEpisodes)
-			v1.GET("/tvshows/:id", controllers.TvShowListById)
-			v1.POST("/tvshows/create", controllers.TvShowCreate)
-			v1.POST("/tvshows/create/batch", controllers.TvShowCreateBatch)
-			v1.PUT("/tvshows/:id", controllers.TvShowEdit)
-			v1.DELETE("/tvshows/:id", controllers.TvShowDelete)
-			v1.DELETE("/tvshows/truncate", controllers.TvShowTruncate)
+			v1.GET("/tvshows", viewer, controllers.TvShowListAll)
+			v1.GET("/tvshows/episodes", viewer, controllers.TvShowListAllUnwatchedEpisodes)
+			v1.GET("/tvshows/:id", viewer, controllers.TvShowListById)
+			v1.POST("/tvshows/create", editor, controllers.TvShowCreate)
+			v1.POST("/tvshows/create/batch", editor, controllers.TvShowCreateBatch)
+			v1.POST("/tvshows/import/:tmdbid", editor, controllers.TvShowImport)
+			v1.POST("/tvshows/:id/sync", editor, controllers.TvShowSync)
+			v1.POST("/tvshows/:id/watched-until", viewer, controllers.TvShowWatchedUntil)
+			v1.PUT("/tvshows/:id", editor, controllers.TvShowEdit)
+			v1.PUT("/tvshows/tmdb/:tmdbid", editor, controllers.TvShowUpsert)
+			v1.PATCH("/tvshows/:id", editor, controllers.TvShowPatch)
+			v1.DELETE("/tvshows/:id", admin, controllers.TvShowDelete)
+			v1.DELETE("/tvshows/truncate", admin, controllers.TvShowTruncate)
+
+			// Seasons
+			v1.GET("/tvshows/:id/seasons", viewer, controllers.SeasonListByTvShow)
+			v1.GET("/tvshows/:id/seasons/:season", viewer, controllers.SeasonListByNumber)
+			v1.POST("/tvshows/:id/seasons", editor, controllers.SeasonCreate)
+			v1.PUT("/tvshows/:id/seasons/:season", editor, controllers.SeasonEdit)
+			v1.DELETE("/tvshows/:id/seasons/:season", admin, controllers.SeasonDelete)
 
 			// Episodes
-			v1.GET("/episodes", controllers.EpisodeListAll)
-			v1.GET("/episodes/:tmdbid", controllers.EpisodeListByTmdbId)
-			v1.GET("/episodes/:tmdbid/:season", controllers.EpisodeListByTmdbIdAndSeason)
-			v1.GET("/episodes/summary/:id", controllers.EpisodeSummaryBySeason)
-			v1.POST("/episodes/create", controllers.EpisodeCreate)
-			v1.POST("/episodes/create/batch", controllers.EpisodeCreateBatch)
-			v1.PUT("/episodes/edit/:id", controllers.EpisodeEdit)
-			v1.PUT("/episodes/watched/:id", controllers.EpisodeEditMarkWatched)
-			v1.PUT("/episodes/watched/season/:tmdbid/:season", controllers.EpisodeEditMarkWatched)
-			v1.DELETE("/episodes/delete/:id", controllers.EpisodeDelete)
-			v1.DELETE("/episodes/delete/tvshow/:tmdbid", controllers.EpisodeDelete)
-			v1.DELETE("/episodes/delete/season/:tmdbid/:season", controllers.EpisodeDelete)
-			v1.DELETE("/episodes/truncate", controllers.EpisodeTruncate)
+			v1.GET("/episodes", viewer, controllers.EpisodeListAll)
+			v1.GET("/episodes/:tmdbid", viewer, controllers.EpisodeListByTmdbId)
+			v1.GET("/episodes/:tmdbid/:season", viewer, controllers.EpisodeListByTmdbIdAndSeason)
+			v1.GET("/episodes/summary/:id", viewer, controllers.EpisodeSummaryBySeason)
+			v1.GET("/episodes/id/:id", viewer, controllers.EpisodeListById)
+			v1.POST("/episodes/create", editor, controllers.EpisodeCreate)
+			v1.POST("/episodes/create/batch", editor, controllers.EpisodeCreateBatch)
+			v1.PUT("/episodes/edit/:id", editor, controllers.EpisodeEdit)
+			v1.PUT("/episodes/tmdb/:tmdbid/:season/:episode", editor, controllers.EpisodeUpsert)
+			v1.PATCH("/episodes/:id", editor, controllers.EpisodePatch)
+			// the watch state belongs to the user, so viewers keep their own
+			v1.PUT("/episodes/watched/:id", viewer, controllers.EpisodeEditMarkWatched)
+			v1.PUT("/episodes/watched/season/:tmdbid/:season", viewer, controllers.EpisodeEditMarkWatched)
+			v1.PUT("/episodes/watched/tvshow/:tmdbid", viewer, controllers.EpisodeEditMarkWatched)
+			v1.DELETE("/episodes/delete/:id", admin, controllers.EpisodeDelete)
+			v1.DELETE("/episodes/delete/tvshow/:tmdbid", admin, controllers.EpisodeDelete)
+			v1.DELETE("/episodes/delete/season/:tmdbid/:season", admin, controllers.EpisodeDelete)
+			v1.DELETE("/episodes/truncate", admin, controllers.EpisodeTruncate)
+
+			// UpNext
+			v1.GET("/up-next", viewer, controllers.UpNextList)
+
+			// Calendar
+			v1.GET("/calendar", viewer, controllers.Calendar)
+
+			// Stats
+			v1.GET("/stats", viewer, controllers.Stats)
+
+			// WatchEvents, the watch history of the user
+			v1.GET("/episodes/id/:id/events", viewer, controllers.WatchEventListByEpisode)
+			v1.POST("/episodes/id/:id/events", viewer, controllers.WatchEventCreateForEpisode)
+			v1.DELETE("/episodes/id/:id/events/:eventid", viewer, controllers.WatchEventDeleteForEpisode)
+			v1.GET("/tvshows/:id/events", viewer, controllers.WatchEventListByTvShow)
+			v1.POST("/tvshows/:id/events", viewer, controllers.WatchEventCreateForTvShow)
+			v1.DELETE("/tvshows/:id/events/:eventid", viewer, controllers.WatchEventDeleteForTvShow)
+
+			// Csv
+			v1.GET("/export/tvshows.csv", viewer, controllers.TvShowExportCsv)
+			v1.GET("/export/episodes.csv", viewer, controllers.EpisodeExportCsv)
+			v1.POST("/import/tvshows.csv", editor, controllers.TvShowImportCsv)
+			v1.POST("/import/episodes.csv", editor, controllers.EpisodeImportCsv)
+
+			// Backup
+			v1.GET("/backup", admin, controllers.Backup)
+			v1.POST("/restore", admin, controllers.Restore)
 
 			//
-			v1.DELETE("/truncate/all", controllers.TruncateAll)
+			v1.DELETE("/truncate/all", admin, controllers.TruncateAll)
 		}
 	}
 
 	r.NoRoute(controllers.RouteNotFound)
-
-	r.Run(":8080")
 }
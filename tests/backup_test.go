@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/backup"
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func getBackup(t *testing.T) backup.Document {
+	r := testutils.SetUpTestRoutes(true)
+	r.GET("/backup", controllers.Backup)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/backup", nil)
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	var doc backup.Document
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return doc
+}
+
+func postRestore(t *testing.T, mode string, doc interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.POST("/restore", controllers.Restore)
+	body, err := json.Marshal(doc)
+	assert.Nil(t, err)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/restore?mode="+mode, bytes.NewReader(body))
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+	// println(w.Body.String())
+	return w
+}
+
+func TestBackupAndRestoreReplace(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+	assert.Nil(t, repo.Seasons().Create(&models.Season{TmdbId: 1, Number: 1, Name: "Season 1", AirDate: 20090309, EpisodeCount: 10}))
+
+	doc := getBackup(t)
+	assert.Equal(t, backup.Version, doc.Version)
+	assert.Equal(t, 4, len(doc.TvShows))
+	assert.Equal(t, 1, len(doc.Seasons))
+	assert.Equal(t, 6, len(doc.Episodes))
+	testutils.CheckEpisode(t, doc.Episodes[0], models.Episode{Id: 1, TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", AirDate: 20090309, Watched: true, WatchedDate: 20240101})
+	assert.False(t, doc.Episodes[0].CreatedAt.IsZero())
+
+	// everything added after the backup goes away on replace
+	assert.Nil(t, repo.TvShows().Create(&models.TvShow{TmdbId: 5, Name: "Bones", GroupType: 1, Status: 2}))
+	assert.Nil(t, repo.Seasons().Create(&models.Season{TmdbId: 5, Number: 1, Name: "Season 1"}))
+	_, err := repo.Episodes().DeleteByTmdbId(1)
+	assert.Nil(t, err)
+
+	w := postRestore(t, backup.ModeReplace, doc)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"mode":"replace","tv_shows":{"created":0,"updated":4,"deleted":1},"seasons":{"created":1,"updated":0},"episodes":{"created":2,"updated":4}}`, w.Body.String())
+
+	restored := getBackup(t)
+	assert.Equal(t, tvShowNames(doc.TvShows), tvShowNames(restored.TvShows))
+	assert.Equal(t, 1, len(restored.Seasons))
+	assert.Equal(t, "Season 1", restored.Seasons[0].Name)
+	assert.Equal(t, models.Date(20090309), restored.Seasons[0].AirDate)
+	assert.Equal(t, 6, len(restored.Episodes))
+	for index, episode := range restored.Episodes {
+		expected := doc.Episodes[index]
+		assert.Equal(t, expected.Name, episode.Name)
+		assert.Equal(t, expected.Watched, episode.Watched)
+		assert.Equal(t, expected.WatchedDate, episode.WatchedDate)
+		assert.True(t, expected.CreatedAt.Equal(episode.CreatedAt))
+	}
+}
+
+func TestRestoreReplaceKeepsOtherUsers(t *testing.T) {
+	setUpListData(t)
+
+	bob := models.User{Username: "bob", PasswordHash: "hash", Role: models.UserRoleViewer}
+	assert.Nil(t, testutils.GetTestRepository().Users().Create(&bob))
+	bobRepo := testutils.GetTestRepository().ForUser(bob.Id)
+	pilot, err := bobRepo.Episodes().FindByKey(2, 1, 1)
+	assert.Nil(t, err)
+	pilot.Watched, pilot.WatchedDate = true, 20240301
+	assert.Nil(t, bobRepo.Episodes().SaveWatchState(&pilot))
+	brooklyn, err := bobRepo.Episodes().FindByKey(3, 1, 1)
+	assert.Nil(t, err)
+	brooklyn.Watched, brooklyn.WatchedDate = true, 20240302
+	assert.Nil(t, bobRepo.Episodes().SaveWatchState(&brooklyn))
+
+	// the backup of the test user leaves Brooklyn Nine-Nine out
+	doc := getBackup(t)
+	var tvShows []models.TvShow
+	for _, tvShow := range doc.TvShows {
+		if tvShow.TmdbId != 3 {
+			tvShows = append(tvShows, tvShow)
+		}
+	}
+	var episodes []models.Episode
+	for _, episode := range doc.Episodes {
+		if episode.TmdbId != 3 {
+			episodes = append(episodes, episode)
+		}
+	}
+	doc.TvShows, doc.Episodes = tvShows, episodes
+	assert.Equal(t, []string{"Abbott Elementary", "Castle", "The Rookie"}, tvShowNames(doc.TvShows))
+
+	w := postRestore(t, backup.ModeReplace, doc)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"mode":"replace","tv_shows":{"created":0,"updated":3,"deleted":1},"seasons":{"created":0,"updated":0},"episodes":{"created":0,"updated":5,"deleted":1}}`, w.Body.String())
+
+	// bob keeps his watch state and history of the episodes still there
+	pilot, err = bobRepo.Episodes().FindByKey(2, 1, 1)
+	assert.Nil(t, err)
+	assert.True(t, pilot.Watched)
+	assert.Equal(t, models.Date(20240301), pilot.WatchedDate)
+	events, err := bobRepo.WatchEvents().FindByEpisodeId(pilot.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+
+	testutils.CheckListAllTvShows(t, false, 3)
+	testutils.CheckListAllEpisodes(t, false, 5)
+}
+
+func TestRestoreMerge(t *testing.T) {
+	setUpListData(t)
+	season := models.Season{TmdbId: 1, Number: 1, Name: "Season 1", EpisodeCount: 10}
+	assert.Nil(t, testutils.GetTestRepository().Seasons().Create(&season))
+
+	doc := backup.Document{
+		Version: backup.Version,
+		TvShows: []models.TvShow{
+			{TmdbId: 1, Name: "Castle", GroupType: 2, Status: 2},
+			{TmdbId: 5, Name: "Bones", GroupType: 1, Status: 2},
+		},
+		Seasons: []models.Season{
+			{TmdbId: 1, Number: 1, Name: "Season One", EpisodeCount: 10},
+			{TmdbId: 5, Number: 1, Name: "Season 1", EpisodeCount: 22},
+		},
+		Episodes: []models.Episode{
+			{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20181016, Watched: true, WatchedDate: 20240201},
+			{TmdbId: 5, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20050913},
+		},
+	}
+
+	w := postRestore(t, backup.ModeMerge, doc)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"mode":"merge","tv_shows":{"created":1,"updated":1},"seasons":{"created":1,"updated":1},"episodes":{"created":1,"updated":1}}`, w.Body.String())
+
+	repo := testutils.GetTestUserRepository()
+	tvShow, err := repo.TvShows().FindByTmdbId(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, tvShow.Id)
+	assert.Equal(t, models.GroupTypePlanned, tvShow.GroupType)
+
+	seasons, err := repo.Seasons().FindAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(seasons))
+	assert.Equal(t, season.Id, seasons[0].Id)
+	assert.Equal(t, "Season One", seasons[0].Name)
+
+	episode, err := repo.Episodes().FindByKey(2, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, episode.Id)
+	assert.True(t, episode.Watched)
+	assert.Equal(t, models.Date(20240201), episode.WatchedDate)
+
+	// the rows missing from the document are kept
+	testutils.CheckListAllTvShows(t, false, 5)
+	testutils.CheckListAllEpisodes(t, false, 7)
+}
+
+func TestRestoreErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(mode string, doc interface{}, statusCode int, message string) {
+		w := postRestore(t, mode, doc)
+		assert.Equal(t, statusCode, w.Code)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	valid := backup.Document{Version: backup.Version, TvShows: []models.TvShow{{TmdbId: 5, Name: "Bones", GroupType: 1, Status: 2}}}
+
+	checkError("overwrite", valid, http.StatusBadRequest, "mode invalid, must be replace or merge")
+	checkError(backup.ModeMerge, backup.Document{Version: 3}, http.StatusUnprocessableEntity, "backup version 3 not supported, must be 1 to 2")
+	checkError(backup.ModeMerge, backup.Document{Version: backup.Version, Seasons: []models.Season{
+		{TmdbId: 2, Number: 1},
+		{TmdbId: 2, Number: 1},
+	}}, http.StatusUnprocessableEntity, "seasons[1]: season 2/1 repeated")
+	checkError(backup.ModeMerge, backup.Document{Version: backup.Version, Seasons: []models.Season{{TmdbId: 9, Number: 1}}},
+		http.StatusUnprocessableEntity, "seasons[0]: TvShow (TMDB ID 9) not found")
+	checkError(backup.ModeMerge, backup.Document{Version: backup.Version, TvShows: []models.TvShow{{TmdbId: 5, Name: "B", GroupType: 1, Status: 2}}},
+		http.StatusUnprocessableEntity, "tv_shows[0]: Name: less than min")
+	checkError(backup.ModeMerge, backup.Document{Version: backup.Version, Episodes: []models.Episode{
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot"},
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot"},
+	}}, http.StatusUnprocessableEntity, "episodes[1]: episode 2/1x1 repeated")
+
+	// a replace whose episodes miss their show is rolled back
+	checkError(backup.ModeReplace, backup.Document{Version: backup.Version, TvShows: valid.TvShows, Episodes: []models.Episode{
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot"},
+	}}, http.StatusUnprocessableEntity, "episodes[0]: TvShow (TMDB ID 2) not found")
+
+	testutils.CheckListAllTvShows(t, false, 4)
+	testutils.CheckListAllEpisodes(t, false, 6)
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postBatch(t *testing.T, handler gin.HandlerFunc, query string, body interface{}, report *controllers.BatchReport) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	url := "/batch"
+	r.POST(url, handler)
+
+	bodyJson, err := json.Marshal(body)
+	assert.Nil(t, err)
+	req, err := http.NewRequest(http.MethodPost, url+query, strings.NewReader(string(bodyJson)))
+	assert.Nil(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code == http.StatusMultiStatus && report != nil {
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), report))
+	}
+	return w
+}
+
+func batchStatuses(report controllers.BatchReport) []string {
+	statuses := []string{}
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestTvShowCreateBatchPartial(t *testing.T) {
+	setUpListData(t)
+
+	tvShows := []models.TvShow{
+		{TmdbId: 10, Name: "Bones", GroupType: 1, Status: 2},
+		{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2},
+		{TmdbId: 11, Name: "", GroupType: 1, Status: 2},
+		{TmdbId: 10, Name: "Bones", GroupType: 1, Status: 2},
+		{TmdbId: 12, Name: "The Rookie", GroupType: 1, Status: 1},
+		{TmdbId: 13, Name: "Elsbeth", GroupType: 1, Status: 1},
+	}
+
+	var report controllers.BatchReport
+	w := postBatch(t, controllers.TvShowCreateBatch, "?mode=partial", tvShows, &report)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 3, report.Skipped)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, []string{"created", "skipped", "error", "skipped", "skipped", "created"}, batchStatuses(report))
+
+	for index, result := range report.Results {
+		assert.Equal(t, index, result.Index)
+	}
+	assert.Equal(t, "TvShow Castle (TMDB ID 1) already exist", report.Results[1].Reason)
+	assert.Equal(t, "Name: less than min", report.Results[2].Reason)
+	assert.Equal(t, "TvShow Bones (TMDB ID 10) already exist", report.Results[3].Reason)
+	assert.Equal(t, "TvShow name The Rookie already exist", report.Results[4].Reason)
+	assert.Equal(t, "", report.Results[0].Reason)
+
+	tvShow, err := testutils.GetTestRepository().TvShows().FindByTmdbId(13)
+	assert.Nil(t, err)
+	assert.Equal(t, "Elsbeth", tvShow.Name)
+	testutils.CheckListAllTvShows(t, false, 6)
+}
+
+func TestEpisodeCreateBatchPartial(t *testing.T) {
+	setUpListData(t)
+
+	episodes := []models.Episode{
+		{TmdbId: 2, Season: 1, Episode: 3, Name: "The Good, the Bad and the Ugly", AirDate: 20181030},
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20181016},
+		{TmdbId: 99, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20200101},
+		{TmdbId: 2, Season: 1, Episode: 4, Name: "", AirDate: 20181106},
+		{TmdbId: 2, Season: 1, Episode: 3, Name: "The Good, the Bad and the Ugly", AirDate: 20181030},
+		{TmdbId: 4, Season: 1, Episode: 2, Name: "Light Bulb", AirDate: 20211214, Watched: true, WatchedDate: 20240105},
+	}
+
+	var report controllers.BatchReport
+	w := postBatch(t, controllers.EpisodeCreateBatch, "?mode=partial", episodes, &report)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 2, report.Errors)
+	assert.Equal(t, []string{"created", "skipped", "error", "error", "skipped", "created"}, batchStatuses(report))
+	assert.Equal(t, "episode 1x01 already exist for The Rookie", report.Results[1].Reason)
+	assert.Equal(t, "TvShow (TMDB ID 99) not found", report.Results[2].Reason)
+	assert.Equal(t, "episode 1x03 already exist for The Rookie", report.Results[4].Reason)
+
+	episode, err := testutils.GetTestUserRepository().Episodes().FindByKey(4, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "Light Bulb", episode.Name)
+	assert.True(t, episode.Watched)
+	assert.Equal(t, models.Date(20240105), episode.WatchedDate)
+}
+
+func TestCreateBatchPartialErrors(t *testing.T) {
+	setUpListData(t)
+
+	w := postBatch(t, controllers.TvShowCreateBatch, "?mode=all", []models.TvShow{}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"mode invalid, must be partial"}`, w.Body.String())
+
+	w = postBatch(t, controllers.EpisodeCreateBatch, "?mode=all", []models.Episode{}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// nothing valid is still a multi-status, with nothing created
+	var report controllers.BatchReport
+	w = postBatch(t, controllers.TvShowCreateBatch, "?mode=partial", []models.TvShow{{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2}}, &report)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, []string{"skipped"}, batchStatuses(report))
+
+	// the default stays all or nothing
+	w = postBatch(t, controllers.TvShowCreateBatch, "", []models.TvShow{
+		{TmdbId: 20, Name: "Bones", GroupType: 1, Status: 2},
+		{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	tvShow, err := testutils.GetTestRepository().TvShows().FindByTmdbId(20)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, tvShow.Id)
+}
+
+// racingRepository creates a row right after the handler reads the existing
+// ones, like another client posting the same batch at the same time.
+type racingRepository struct {
+	repository.Repository
+}
+
+type racingTvShows struct {
+	repository.TvShowRepository
+}
+
+type racingEpisodes struct {
+	repository.EpisodeRepository
+}
+
+func (r racingRepository) TvShows() repository.TvShowRepository {
+	return racingTvShows{r.Repository.TvShows()}
+}
+
+func (r racingRepository) Episodes() repository.EpisodeRepository {
+	return racingEpisodes{r.Repository.Episodes()}
+}
+
+func (r racingTvShows) FindAll() ([]models.TvShow, error) {
+	tvShows, err := r.TvShowRepository.FindAll()
+	if err == nil {
+		err = r.TvShowRepository.Create(&models.TvShow{TmdbId: 10, Name: "Bones", GroupType: 1, Status: 2})
+	}
+	return tvShows, err
+}
+
+func (r racingEpisodes) FindByTmdbId(tmdbId int) ([]models.Episode, error) {
+	episodes, err := r.EpisodeRepository.FindByTmdbId(tmdbId)
+	if err == nil {
+		err = r.EpisodeRepository.Create(&models.Episode{TmdbId: tmdbId, Season: 1, Episode: 3, Name: "The Good, the Bad and the Ugly", AirDate: 20181030})
+	}
+	return episodes, err
+}
+
+func TestCreateBatchPartialRace(t *testing.T) {
+	setUpListData(t)
+
+	request := func(url string, body interface{}) controllers.BatchReport {
+		r := testutils.SetUpTestRoutes(false)
+		r.Use(controllers.UseRepository(racingRepository{testutils.GetTestUserRepository()}))
+		r.POST("/tvshows/batch", controllers.TvShowCreateBatch)
+		r.POST("/episodes/batch", controllers.EpisodeCreateBatch)
+
+		bodyJson, err := json.Marshal(body)
+		assert.Nil(t, err)
+		var report controllers.BatchReport
+		w := testutils.Request(t, r, http.MethodPost, url+"?mode=partial", nil, string(bodyJson), nil)
+		assert.Equal(t, http.StatusMultiStatus, w.Code, url)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	// the rows created in the meantime are skipped, the others still created
+	report := request("/tvshows/batch", []models.TvShow{
+		{TmdbId: 10, Name: "Bones", GroupType: 1, Status: 2},
+		{TmdbId: 13, Name: "Elsbeth", GroupType: 1, Status: 1},
+	})
+	assert.Equal(t, []string{"skipped", "created"}, batchStatuses(report))
+	assert.Equal(t, "TvShow Bones (TMDB ID 10) already exist", report.Results[0].Reason)
+	testutils.CheckListAllTvShows(t, false, 6)
+
+	report = request("/episodes/batch", []models.Episode{
+		{TmdbId: 2, Season: 1, Episode: 3, Name: "The Good, the Bad and the Ugly", AirDate: 20181030},
+		{TmdbId: 2, Season: 1, Episode: 4, Name: "Fire Fighting", AirDate: 20181106},
+	})
+	assert.Equal(t, []string{"skipped", "created"}, batchStatuses(report))
+	assert.Equal(t, "episode 1x03 already exist for The Rookie", report.Results[0].Reason)
+}
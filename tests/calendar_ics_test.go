@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/ical"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// icsSummaries returns the SUMMARY of every event of the feed.
+func icsSummaries(body string) []string {
+	summaries := []string{}
+	for _, line := range strings.Split(body, "\r\n") {
+		if summary, ok := strings.CutPrefix(line, "SUMMARY:"); ok {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+func TestCalendarIcs(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpAppRoutes()
+	token := registerAndLogin(t, r, "/api/v1", "", "alice")
+	repo := testutils.GetTestRepository()
+
+	today := models.Today(time.UTC)
+	tomorrow := today.AddDays(1)
+	tvShows := []models.TvShow{
+		{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 1},
+		{TmdbId: 2, Name: "The Rookie", GroupType: 2, Status: 1},
+	}
+	assert.Nil(t, repo.TvShows().CreateMany(tvShows))
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 3, Episode: 4, Name: "Head Case", AirDate: 20101011},
+		{TmdbId: 1, Season: 3, Episode: 5, Name: "Anatomy of a Murder", Overview: "Castle, Beckett; and a nurse", AirDate: today},
+		{TmdbId: 2, Season: 1, Episode: 10, Name: "Caught Stealing", AirDate: tomorrow},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+
+	w := userRequest(t, r, http.MethodGet, "/api/v1/calendar.ics", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	assert.Equal(t, []string{"Castle 3x05 - Anatomy of a Murder", "The Rookie 1x10 - Caught Stealing"}, icsSummaries(body))
+	assert.Contains(t, body, "UID:episode-2@tvshows-backend-go\r\n")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:"+today.Time().Format("20060102")+"\r\n")
+	assert.Contains(t, body, "DTEND;VALUE=DATE:"+tomorrow.Time().Format("20060102")+"\r\n")
+	assert.Contains(t, body, `DESCRIPTION:Castle\, Beckett\; and a nurse`+"\r\n")
+
+	w = userRequest(t, r, http.MethodGet, "/api/v1/calendar.ics?tmdb_id=2", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"The Rookie 1x10 - Caught Stealing"}, icsSummaries(w.Body.String()))
+
+	w = userRequest(t, r, http.MethodGet, "/api/v1/calendar.ics?group=1", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Castle 3x05 - Anatomy of a Murder"}, icsSummaries(w.Body.String()))
+
+	w = userRequest(t, r, http.MethodGet, "/api/v1/calendar.ics?group=x", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"group invalid"}`, w.Body.String())
+}
+
+func TestCalendarToken(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpAppRoutes()
+	token := registerAndLogin(t, r, "/api/v1", "", "alice")
+
+	checkError := func(method string, url string, token string, statusCode int, message string) {
+		w := userRequest(t, r, method, url, token, nil)
+		assert.Equal(t, statusCode, w.Code, method+" "+url)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError(http.MethodGet, "/api/v1/calendar.ics", "", http.StatusUnauthorized, "authentication required")
+	checkError(http.MethodGet, "/api/v1/calendar.ics?token=tvc_unknown", "", http.StatusUnauthorized, "calendar token invalid")
+	checkError(http.MethodDelete, "/api/v1/users/me/calendar-token", token, http.StatusNotFound, "calendar token not found")
+
+	var created struct {
+		Token string `json:"token"`
+		Url   string `json:"url"`
+	}
+	w := userRequest(t, r, http.MethodPost, "/api/v1/users/me/calendar-token", token, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	unmarshal(t, w, &created)
+	assert.True(t, strings.HasPrefix(created.Token, "tvc_"))
+	assert.Equal(t, "/api/v1/calendar.ics?token="+created.Token, created.Url)
+
+	// calendar apps subscribe without headers
+	w = userRequest(t, r, http.MethodGet, created.Url, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{}, icsSummaries(w.Body.String()))
+
+	// the token is never shown again
+	w = userRequest(t, r, http.MethodGet, "/api/v1/users/me", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "calendar")
+
+	// it only opens the calendar feed
+	checkError(http.MethodGet, "/api/v1/tvshows?token="+created.Token, "", http.StatusUnauthorized, "authentication required")
+
+	// a new token revokes the previous one
+	var renewed struct {
+		Token string `json:"token"`
+	}
+	w = userRequest(t, r, http.MethodPost, "/api/v1/users/me/calendar-token", token, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	unmarshal(t, w, &renewed)
+	assert.NotEqual(t, created.Token, renewed.Token)
+	checkError(http.MethodGet, created.Url, "", http.StatusUnauthorized, "calendar token invalid")
+
+	w = userRequest(t, r, http.MethodDelete, "/api/v1/users/me/calendar-token", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"message":"calendar token revoked"}`, w.Body.String())
+	checkError(http.MethodGet, "/api/v1/calendar.ics?token="+renewed.Token, "", http.StatusUnauthorized, "calendar token invalid")
+}
+
+func TestIcalWrite(t *testing.T) {
+	calendar := ical.Calendar{
+		ProdId: "-//test//EN",
+		Events: []ical.Event{{
+			UID:     "episode-1@test",
+			Date:    time.Date(2024, 12, 31, 0, 0, 0, 0, time.Local),
+			Summary: strings.Repeat("á", 40),
+			Stamp:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, calendar.Write(&buffer))
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:episode-1@test",
+		"DTSTAMP:20240102T030405Z",
+		"DTSTART;VALUE=DATE:20241231",
+		"DTEND;VALUE=DATE:20250101",
+		// folded at 75 octets without splitting the 2 octets of á
+		"SUMMARY:" + strings.Repeat("á", 33),
+		" " + strings.Repeat("á", 7),
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")+"\r\n", buffer.String())
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func calendarDays(days []controllers.CalendarDay) map[models.Date][]string {
+	keys := make(map[models.Date][]string)
+	for _, day := range days {
+		for _, episode := range day.Episodes {
+			keys[day.Date] = append(keys[day.Date], episode.TvShowName+" "+episode.Name)
+		}
+	}
+	return keys
+}
+
+func TestCalendar(t *testing.T) {
+	setUpListData(t)
+
+	var days []controllers.CalendarDay
+	w := listRequest(t, controllers.Calendar, "?from=20090301&to=20181231", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Date{20090309, 20090316, 20130917, 20181016, 20181023}, []models.Date{days[0].Date, days[1].Date, days[2].Date, days[3].Date, days[4].Date})
+	assert.Equal(t, map[models.Date][]string{
+		20090309: {"Castle Flowers for Your Grave"},
+		20090316: {"Castle Nanny McDead"},
+		20130917: {"Brooklyn Nine-Nine Pilot"},
+		20181016: {"The Rookie Pilot"},
+		20181023: {"The Rookie Crime of the Century"},
+	}, calendarDays(days))
+	assert.Equal(t, 1, days[0].Episodes[0].TmdbId)
+	assert.True(t, days[0].Episodes[0].Watched)
+
+	w = listRequest(t, controllers.Calendar, "?from=20090301&to=20181231&unwatched=true", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[models.Date][]string{
+		20181016: {"The Rookie Pilot"},
+		20181023: {"The Rookie Crime of the Century"},
+	}, calendarDays(days))
+
+	w = listRequest(t, controllers.Calendar, "?from=20090301&to=20251231&status=2", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[models.Date][]string{
+		20090309: {"Castle Flowers for Your Grave"},
+		20090316: {"Castle Nanny McDead"},
+		20130917: {"Brooklyn Nine-Nine Pilot"},
+	}, calendarDays(days))
+
+	w = listRequest(t, controllers.Calendar, "?from=20200101&to=20201231", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestCalendarDefaultWindow(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+
+	today := models.Today(time.UTC)
+	inTwoWeeks := today.AddDays(14)
+	inTwoMonths := models.DateOf(today.Time().AddDate(0, 2, 0))
+	episodes := []models.Episode{
+		{TmdbId: 4, Season: 1, Episode: 2, Name: "Light Bulb", AirDate: today},
+		{TmdbId: 4, Season: 1, Episode: 3, Name: "Wishlist", AirDate: inTwoWeeks},
+		{TmdbId: 2, Season: 1, Episode: 3, Name: "The Good, the Bad and the Ugly", AirDate: inTwoWeeks},
+		{TmdbId: 4, Season: 1, Episode: 4, Name: "New Tech", AirDate: inTwoMonths},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+
+	var days []controllers.CalendarDay
+	w := listRequest(t, controllers.Calendar, "", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[models.Date][]string{
+		today:      {"Abbott Elementary Light Bulb"},
+		inTwoWeeks: {"The Rookie The Good, the Bad and the Ugly", "Abbott Elementary Wishlist"},
+	}, calendarDays(days))
+}
+
+func TestCalendarErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(query string, message string) {
+		w := listRequest(t, controllers.Calendar, query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("?from=2024", "from invalid, must be YYYY-MM-DD")
+	checkError("?to=20241301", "to invalid, must be YYYY-MM-DD")
+	checkError("?from=20240201&to=20240101", "to invalid, must not be before from")
+	checkError("?unwatched=maybe", "unwatched invalid")
+	checkError("?status=x", "status invalid")
+	checkError("?group=x", "group invalid")
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getCsv(t *testing.T, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.GET("/export", handler)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/export", nil)
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func postCsv(t *testing.T, handler gin.HandlerFunc, content string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if content != "" {
+		part, err := writer.CreateFormFile("file", "upload.csv")
+		assert.Nil(t, err)
+		_, err = part.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+
+	r := testutils.SetUpTestRoutes(true)
+	r.POST("/import", handler)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/import", body)
+	assert.Nil(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r.ServeHTTP(w, req)
+	// println(w.Body.String())
+	return w
+}
+
+func TestCsvExport(t *testing.T) {
+	setUpListData(t)
+
+	w := getCsv(t, controllers.TvShowExportCsv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, "id,tmdb_id,name,overview,group,status,created_at,updated_at", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "4,4,Abbott Elementary,,archived,returning,"))
+
+	w = getCsv(t, controllers.EpisodeExportCsv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines = strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, 7, len(lines))
+	assert.Equal(t, "id,tmdb_id,season,episode,name,overview,air_date,watched,watched_date,created_at,updated_at", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "1,1,1,1,Flowers for Your Grave,,2009-03-09,true,2024-01-01,"))
+}
+
+func TestCsvExportImportRoundTrip(t *testing.T) {
+	setUpListData(t)
+
+	tvShows := getCsv(t, controllers.TvShowExportCsv).Body.String()
+	episodes := getCsv(t, controllers.EpisodeExportCsv).Body.String()
+
+	// importing an export only updates the same rows
+	w := postCsv(t, controllers.TvShowImportCsv, tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"total":4,"created":0,"updated":4,"errors":[]}`, w.Body.String())
+
+	w = postCsv(t, controllers.EpisodeImportCsv, episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"total":6,"created":0,"updated":6,"errors":[]}`, w.Body.String())
+
+	// only updated_at moves
+	withoutUpdatedAt := func(content string) []string {
+		var lines []string
+		for _, line := range strings.Split(content, "\n") {
+			if index := strings.LastIndex(line, ","); index >= 0 {
+				line = line[:index]
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+	assert.Equal(t, withoutUpdatedAt(episodes), withoutUpdatedAt(getCsv(t, controllers.EpisodeExportCsv).Body.String()))
+}
+
+func TestCsvImportReport(t *testing.T) {
+	setUpListData(t)
+
+	tvShows := "tmdb_id,name,group,status\n" +
+		"5,Bones,1,2\n" +
+		"6,X,1,2\n" +
+		"7,Castle,1,2\n" +
+		"abc,Fringe,1,2\n" +
+		"2,The Rookie,2,1\n" +
+		"5,Bones,1,2\n" +
+		"8,\"Parks and\nRecreation\",1,2,extra\n"
+	w := postCsv(t, controllers.TvShowImportCsv, tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"total":7,"created":1,"updated":1,"errors":[`+
+		`{"line":3,"error":"Name: less than min"},`+
+		`{"line":4,"error":"TvShow name Castle already exist"},`+
+		`{"line":5,"error":"tmdb_id invalid"},`+
+		`{"line":7,"error":"TMDB ID 5 repeated"},`+
+		`{"line":8,"error":"expected 4 columns, got 5"}]}`, w.Body.String())
+	testutils.CheckListAllTvShows(t, false, 5)
+
+	episodes := "tmdb_id,season,episode,name,watched,watched_date\n" +
+		"5,1,1,Pilot,true,20240301\n" +
+		"2,1,1,Pilot,true,20240302\n" +
+		"9,1,1,Pilot,false,0\n" +
+		"5,1,2,The Man in the Fallout Shelter,maybe,0\n" +
+		"5,1,3,The Man in the Morgue,false,20241301\n"
+	w = postCsv(t, controllers.EpisodeImportCsv, episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"total":5,"created":1,"updated":1,"errors":[`+
+		`{"line":4,"error":"TvShow (TMDB ID 9) not found"},`+
+		`{"line":5,"error":"watched invalid"},`+
+		`{"line":6,"error":"watched_date invalid"}]}`, w.Body.String())
+	testutils.CheckListAllEpisodes(t, false, 7)
+
+	episode, err := testutils.GetTestUserRepository().Episodes().FindByKey(2, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Pilot", episode.Name)
+	assert.Equal(t, models.Date(20181016), episode.AirDate)
+	assert.True(t, episode.Watched)
+	assert.Equal(t, models.Date(20240302), episode.WatchedDate)
+}
+
+func TestCsvImportErrors(t *testing.T) {
+	checkError := func(handler gin.HandlerFunc, content string, message string) {
+		w := postCsv(t, handler, content)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError(controllers.TvShowImportCsv, "", "file required, send the csv as the multipart field file")
+	checkError(controllers.TvShowImportCsv, "tmdb_id,name,rating\n", "column rating unknown")
+	checkError(controllers.EpisodeImportCsv, "tmdb_id,season,name\n", "column episode required")
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateJson(t *testing.T) {
+	data, err := json.Marshal(models.Episode{AirDate: 20090309})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"air_date":"2009-03-09","watched":false,"watched_date":null`)
+
+	for _, body := range []string{
+		`{"air_date": "2009-03-09", "watched_date": null}`,
+		`{"air_date": 20090309, "watched_date": 0}`,
+		`{"air_date": "20090309"}`,
+	} {
+		decoded := models.Episode{WatchedDate: 20240101}
+		assert.Nil(t, json.Unmarshal([]byte(body), &decoded), body)
+		assert.Equal(t, models.NewDate(2009, time.March, 9), decoded.AirDate, body)
+		if strings.Contains(body, "watched_date") {
+			assert.True(t, decoded.WatchedDate.IsZero(), body)
+		}
+	}
+
+	var decoded models.Episode
+	assert.EqualError(t, json.Unmarshal([]byte(`{"air_date": "03/09/2009"}`), &decoded), "date invalid, must be YYYY-MM-DD")
+
+	// the old clients keep their numbers
+	data, err = models.MarshalLegacyJSON([]models.Episode{{AirDate: 20090309}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"air_date":20090309,"watched":false,"watched_date":0`)
+	data, err = models.MarshalLegacyJSON(map[string]interface{}{"date": models.Date(20090309), "count": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"count":1,"date":20090309}`, string(data))
+}
+
+func TestDateFormat(t *testing.T) {
+	setUpListData(t)
+
+	request := func(url string, headers map[string]string) *httptest.ResponseRecorder {
+		r := testutils.SetUpTestRoutes(true)
+		r.Use(controllers.UseDateFormat)
+		r.GET("/episodes/id/:id", controllers.EpisodeListById)
+		r.GET("/calendar", controllers.Calendar)
+		return testutils.Request(t, r, http.MethodGet, url, headers, "", nil)
+	}
+
+	// each request picks its format, ISO-8601 by default
+	w := request("/episodes/id/1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"air_date":"2009-03-09","watched":true,"watched_date":"2024-01-01"`)
+	w = request("/episodes/id/1", map[string]string{"X-Date-Format": "int"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"air_date":20090309,"watched":true,"watched_date":20240101`)
+	w = request("/episodes/id/3?date_format=int", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"air_date":20181016,"watched":false,"watched_date":0`)
+	w = request("/calendar?from=2009-03-01&to=2009-03-31&date_format=int", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"date":20090309`)
+	w = request("/episodes/id/1", map[string]string{"X-Date-Format": "iso"})
+	assert.Contains(t, w.Body.String(), `"air_date":"2009-03-09"`)
+
+	w = request("/episodes/id/1?date_format=unix", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"date format invalid, must be iso or int"}`, w.Body.String())
+}
+
+func TestDateMath(t *testing.T) {
+	date, err := models.ParseDate("2024-02-28")
+	assert.Nil(t, err)
+	assert.Equal(t, models.Date(20240228), date)
+	assert.Equal(t, models.Date(20240301), date.AddDays(2))
+	assert.Equal(t, models.Date(20231231), models.Date(20240101).AddDays(-1))
+	assert.Equal(t, "2024-02-28", date.String())
+
+	// the day is the one of the time zone, not the one of UTC
+	at := time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, models.Date(20240301), models.DateOf(at))
+	assert.Equal(t, models.Date(20240229), models.DateOf(at.In(time.FixedZone("UTC-5", -5*60*60))))
+
+	for _, s := range []string{"", "0"} {
+		date, err = models.ParseDate(s)
+		assert.Nil(t, err, s)
+		assert.True(t, date.IsZero(), s)
+	}
+	for _, s := range []string{"2024-02-30", "20241301", "2024"} {
+		_, err = models.ParseDate(s)
+		assert.EqualError(t, err, "date invalid, must be YYYY-MM-DD", s)
+	}
+}
+
+func TestDateQueryParams(t *testing.T) {
+	setUpListData(t)
+
+	var episodes []models.Episode
+	w := listRequest(t, controllers.EpisodeListAll, "?air_date_from=2018-10-16&air_date_to=2018-10-23", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{3, 4}, episodeIds(episodes))
+
+	w = listRequest(t, controllers.EpisodeListAll, "?air_date_from=20181016&air_date_to=20181023", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{3, 4}, episodeIds(episodes))
+
+	var days []controllers.CalendarDay
+	w = listRequest(t, controllers.Calendar, "?from=2009-03-01&to=2009-03-31", &days)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"date":"2009-03-09"`)
+	assert.Equal(t, 2, len(days))
+
+	w = listRequest(t, controllers.Calendar, "?from=2009-02-30", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"from invalid, must be YYYY-MM-DD"}`, w.Body.String())
+}
+
+func TestDateTimeZone(t *testing.T) {
+	setUpWatchedUntilData(t)
+
+	markWatched := func(timeZone string) *httptest.ResponseRecorder {
+		r := testutils.SetUpTestRoutes(true)
+		r.PUT("/episodes/watched/tvshow/:tmdbid", controllers.EpisodeEditMarkWatched)
+		return testutils.Request(t, r, http.MethodPut, "/episodes/watched/tvshow/1", map[string]string{"X-Time-Zone": timeZone}, `{"watched": true}`, nil)
+	}
+
+	// the watched date is today for the client, a day ahead of UTC or not
+	location, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.Nil(t, err)
+	w := markWatched("Pacific/Kiritimati")
+	assert.Equal(t, http.StatusOK, w.Code)
+	today := models.Today(location)
+	assert.Equal(t, []models.Date{20240101, today, today, today, 20240105, today}, watchedDates(t))
+
+	w = markWatched("Mars/Olympus_Mons")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"time zone invalid"}`, w.Body.String())
+}
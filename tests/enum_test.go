@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumJson(t *testing.T) {
+	tvShow := models.TvShow{GroupType: models.GroupTypePlanned, Status: models.TvShowStatusInProduction}
+	data, err := json.Marshal(tvShow)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"group":"planned","status":"in_production"`)
+
+	// values without a name keep their number
+	data, err = json.Marshal(models.TvShow{GroupType: 9})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"group":9,"status":0`)
+
+	for _, body := range []string{
+		`{"group": "archived", "status": "canceled"}`,
+		`{"group": "ARCHIVED", "status": "Canceled"}`,
+		`{"group": 3, "status": 3}`,
+		`{"group": "3", "status": "3"}`,
+	} {
+		var decoded models.TvShow
+		assert.Nil(t, json.Unmarshal([]byte(body), &decoded), body)
+		assert.Equal(t, models.GroupTypeArchived, decoded.GroupType, body)
+		assert.Equal(t, models.TvShowStatusCanceled, decoded.Status, body)
+	}
+
+	var decoded models.TvShow
+	assert.EqualError(t, json.Unmarshal([]byte(`{"group": "later"}`), &decoded), "group invalid, value must be watching, planned or archived")
+	assert.EqualError(t, json.Unmarshal([]byte(`{"status": true}`), &decoded), "status invalid, value must be returning, ended, canceled, in_production or pilot")
+}
+
+func TestEnumTvShowUpsert(t *testing.T) {
+	testutils.ResetTestRepository(t)
+
+	var tvShow models.TvShow
+	w := putUpsert(t, "/tvshows/tmdb/10", `{"name": "Bones", "group": "planned", "status": "ended"}`, &tvShow)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, models.GroupTypePlanned, tvShow.GroupType)
+	assert.Equal(t, models.TvShowStatusEnded, tvShow.Status)
+	assert.Contains(t, w.Body.String(), `"group":"planned","status":"ended"`)
+
+	// the legacy numbers are still accepted
+	w = putUpsert(t, "/tvshows/tmdb/10", `{"name": "Bones", "group": 1, "status": 3}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"group":"watching","status":"canceled"`)
+
+	w = putUpsert(t, "/tvshows/tmdb/10", `{"name": "Bones", "group": 7, "status": 3}`, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, `{"error":"GroupType: value must be watching, planned or archived"}`, w.Body.String())
+
+	w = putUpsert(t, "/tvshows/tmdb/10", `{"name": "Bones", "group": "watching", "status": "airing"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"status invalid, value must be returning, ended, canceled, in_production or pilot"}`, w.Body.String())
+}
+
+func TestEnumListFilters(t *testing.T) {
+	setUpListData(t)
+
+	var tvShows []models.TvShow
+	w := listRequest(t, controllers.TvShowListAll, "?group=watching&status=returning", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"The Rookie"}, tvShowNames(tvShows))
+
+	w = listRequest(t, controllers.TvShowListAll, "?group=1&status=1", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"The Rookie"}, tvShowNames(tvShows))
+
+	var episodes []models.Episode
+	w = listRequest(t, controllers.EpisodeListAll, "?group=planned", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{5}, episodeIds(episodes))
+
+	w = listRequest(t, controllers.TvShowListAll, "?group=later", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"group invalid"}`, w.Body.String())
+
+	w = listRequest(t, controllers.EpisodeListAll, "?status=airing", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"status invalid"}`, w.Body.String())
+}
+
+func TestEnumCsvImport(t *testing.T) {
+	setUpListData(t)
+
+	tvShows := "tmdb_id,name,group,status\n" +
+		"5,Bones,archived,ended\n" +
+		"6,Fringe,2,3\n" +
+		"7,Lost,later,ended\n"
+	w := postCsv(t, controllers.TvShowImportCsv, tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"total":3,"created":2,"updated":0,"errors":[{"line":4,"error":"group invalid"}]}`, w.Body.String())
+
+	tvShow, err := testutils.GetTestUserRepository().TvShows().FindByTmdbId(5)
+	assert.Nil(t, err)
+	assert.Equal(t, models.GroupTypeArchived, tvShow.GroupType)
+	assert.Equal(t, models.TvShowStatusEnded, tvShow.Status)
+}
+
+func TestMetaEnums(t *testing.T) {
+	r := setUpAppRoutes()
+
+	// no login needed
+	w := userRequest(t, r, http.MethodGet, "/api/v1/meta/enums", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"group":[`+
+		`{"value":1,"name":"watching"},{"value":2,"name":"planned"},{"value":3,"name":"archived"}],`+
+		`"status":[`+
+		`{"value":1,"name":"returning"},{"value":2,"name":"ended"},{"value":3,"name":"canceled"},`+
+		`{"value":4,"name":"in_production"},{"value":5,"name":"pilot"}]}`, w.Body.String())
+}
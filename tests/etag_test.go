@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func etagRequest(t *testing.T, method string, url string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.GET("/tvshows/:id", controllers.TvShowListById)
+	r.PUT("/tvshows/:id", controllers.TvShowEdit)
+	r.PATCH("/tvshows/:id", controllers.TvShowPatch)
+	r.DELETE("/tvshows/:id", controllers.TvShowDelete)
+	r.PUT("/tvshows/tmdb/:tmdbid", controllers.TvShowUpsert)
+	r.GET("/episodes/id/:id", controllers.EpisodeListById)
+	r.PATCH("/episodes/:id", controllers.EpisodePatch)
+	r.PUT("/episodes/watched/:id", controllers.EpisodeEditMarkWatched)
+	r.DELETE("/episodes/delete/:id", controllers.EpisodeDelete)
+	return testutils.Request(t, r, method, url, headers, body, nil)
+}
+
+func TestTvShowETag(t *testing.T) {
+	setUpListData(t)
+
+	w := etagRequest(t, http.MethodGet, "/tvshows/1", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{24}"$`, etag)
+
+	w = etagRequest(t, http.MethodGet, "/tvshows/1", map[string]string{"If-None-Match": `"other", ` + etag}, "")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "", w.Body.String())
+	w = etagRequest(t, http.MethodGet, "/tvshows/1", map[string]string{"If-None-Match": "W/" + etag}, "")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = etagRequest(t, http.MethodGet, "/tvshows/2", map[string]string{"If-None-Match": etag}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the phone edits first, the web client still holds the old tag
+	w = etagRequest(t, http.MethodPatch, "/tvshows/1", map[string]string{"If-Match": etag}, `{"status": 3}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	newEtag := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, newEtag)
+
+	stale := map[string]string{"If-Match": etag}
+	message := `{"error":"precondition failed, If-Match does not match the current TvShow"}`
+	w = etagRequest(t, http.MethodPut, "/tvshows/1", stale, `{"status": 1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, message, w.Body.String())
+	w = etagRequest(t, http.MethodPatch, "/tvshows/1", stale, `{"status": 1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = etagRequest(t, http.MethodDelete, "/tvshows/1", stale, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = etagRequest(t, http.MethodPut, "/tvshows/tmdb/1", stale, `{"name": "Castle", "group": 1, "status": 1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = etagRequest(t, http.MethodPut, "/tvshows/tmdb/10", map[string]string{"If-Match": "*"}, `{"name": "Bones", "group": 1, "status": 1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = etagRequest(t, http.MethodGet, "/tvshows/1", nil, "")
+	assert.Equal(t, newEtag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"status":"canceled"`)
+
+	w = etagRequest(t, http.MethodPut, "/tvshows/1", map[string]string{"If-Match": newEtag}, `{"status": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// requests without If-Match keep working as before
+	w = etagRequest(t, http.MethodDelete, "/tvshows/1", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEpisodeETag(t *testing.T) {
+	setUpListData(t)
+
+	w := etagRequest(t, http.MethodGet, "/episodes/id/3", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEqual(t, "", etag)
+
+	w = etagRequest(t, http.MethodGet, "/episodes/id/3", map[string]string{"If-None-Match": "*"}, "")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = etagRequest(t, http.MethodGet, "/episodes/id/99", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// marking it watched changes the representation of this user
+	w = etagRequest(t, http.MethodPut, "/episodes/watched/3", map[string]string{"If-Match": etag}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	watchedEtag := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, watchedEtag)
+
+	w = etagRequest(t, http.MethodGet, "/episodes/id/3", map[string]string{"If-None-Match": etag}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, watchedEtag, w.Header().Get("ETag"))
+
+	w = etagRequest(t, http.MethodPatch, "/episodes/3", map[string]string{"If-Match": etag}, `{"overview": "Pilot"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `{"error":"precondition failed, If-Match does not match the current Episode"}`, w.Body.String())
+	w = etagRequest(t, http.MethodDelete, "/episodes/delete/3", map[string]string{"If-Match": etag}, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = etagRequest(t, http.MethodDelete, "/episodes/delete/3", map[string]string{"If-Match": watchedEtag}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// interleavedRepository saves the row again right after the handler reads
+// it, like another client writing between the If-Match check and the write.
+type interleavedRepository struct {
+	repository.Repository
+}
+
+type interleavedTvShows struct {
+	repository.TvShowRepository
+}
+
+type interleavedEpisodes struct {
+	repository.EpisodeRepository
+}
+
+func (r interleavedRepository) TvShows() repository.TvShowRepository {
+	return interleavedTvShows{r.Repository.TvShows()}
+}
+
+func (r interleavedRepository) Episodes() repository.EpisodeRepository {
+	return interleavedEpisodes{r.Repository.Episodes()}
+}
+
+func (r interleavedTvShows) FindById(id int) (models.TvShow, error) {
+	return r.saveAgain(r.TvShowRepository.FindById(id))
+}
+
+func (r interleavedTvShows) FindByTmdbId(tmdbId int) (models.TvShow, error) {
+	return r.saveAgain(r.TvShowRepository.FindByTmdbId(tmdbId))
+}
+
+func (r interleavedTvShows) saveAgain(tvShow models.TvShow, err error) (models.TvShow, error) {
+	if err == nil && tvShow.Id > 0 {
+		other := tvShow
+		err = r.TvShowRepository.Save(&other)
+	}
+	return tvShow, err
+}
+
+func (r interleavedEpisodes) FindById(id int) (models.Episode, error) {
+	return r.saveAgain(r.EpisodeRepository.FindById(id))
+}
+
+func (r interleavedEpisodes) FindByKey(tmdbId, season, episode int) (models.Episode, error) {
+	return r.saveAgain(r.EpisodeRepository.FindByKey(tmdbId, season, episode))
+}
+
+func (r interleavedEpisodes) saveAgain(episode models.Episode, err error) (models.Episode, error) {
+	if err == nil && episode.Id > 0 {
+		other := episode
+		err = r.EpisodeRepository.Save(&other)
+	}
+	return episode, err
+}
+
+func TestETagInterleavedWrite(t *testing.T) {
+	setUpListData(t)
+
+	request := func(method string, url string, etag string, body string) *httptest.ResponseRecorder {
+		r := testutils.SetUpTestRoutes(false)
+		r.Use(controllers.UseRepository(interleavedRepository{testutils.GetTestUserRepository()}))
+		r.PUT("/tvshows/:id", controllers.TvShowEdit)
+		r.PATCH("/tvshows/:id", controllers.TvShowPatch)
+		r.DELETE("/tvshows/:id", controllers.TvShowDelete)
+		r.PUT("/tvshows/tmdb/:tmdbid", controllers.TvShowUpsert)
+		r.PUT("/episodes/:id", controllers.EpisodeEdit)
+		r.PATCH("/episodes/:id", controllers.EpisodePatch)
+		r.DELETE("/episodes/delete/:id", controllers.EpisodeDelete)
+		r.PUT("/episodes/tmdb/:tmdbid/:season/:episode", controllers.EpisodeUpsert)
+		return testutils.Request(t, r, method, url, map[string]string{"If-Match": etag}, body, nil)
+	}
+
+	// the tag matches when the handler reads the row, not when it writes it
+	for _, test := range []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodPut, "/tvshows/1", `{"status": 1}`},
+		{http.MethodPatch, "/tvshows/1", `{"status": 1}`},
+		{http.MethodPut, "/tvshows/tmdb/1", `{"name": "Castle", "group": 1, "status": 1}`},
+		{http.MethodDelete, "/tvshows/1", ""},
+		{http.MethodPut, "/episodes/3", `{"overview": "Pilot"}`},
+		{http.MethodPut, "/episodes/tmdb/2/1/1", `{"name": "Pilot", "overview": "Pilot"}`},
+		{http.MethodPatch, "/episodes/3", `{"overview": "Pilot"}`},
+		{http.MethodDelete, "/episodes/delete/3", ""},
+	} {
+		get := "/tvshows/1"
+		if strings.HasPrefix(test.url, "/episodes") {
+			get = "/episodes/id/3"
+		}
+		etag := etagRequest(t, http.MethodGet, get, nil, "").Header().Get("ETag")
+
+		w := request(test.method, test.url, etag, test.body)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, test.method+" "+test.url)
+		assert.Contains(t, w.Body.String(), "precondition failed", test.method+" "+test.url)
+	}
+
+	w := etagRequest(t, http.MethodGet, "/tvshows/1", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"status":"returning"`)
+	w = etagRequest(t, http.MethodGet, "/episodes/id/3", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"overview":"Pilot"`)
+}
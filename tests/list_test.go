@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setUpListData(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	repo := testutils.GetTestUserRepository()
+
+	tvShows := []models.TvShow{
+		{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2},
+		{TmdbId: 2, Name: "The Rookie", GroupType: 1, Status: 1},
+		{TmdbId: 3, Name: "Brooklyn Nine-Nine", GroupType: 2, Status: 2},
+		{TmdbId: 4, Name: "Abbott Elementary", GroupType: 3, Status: 1},
+	}
+	assert.Nil(t, repo.TvShows().CreateMany(tvShows))
+
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", AirDate: 20090309, Watched: true, WatchedDate: 20240101},
+		{TmdbId: 1, Season: 1, Episode: 2, Name: "Nanny McDead", AirDate: 20090316, Watched: true, WatchedDate: 20240102},
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20181016},
+		{TmdbId: 2, Season: 1, Episode: 2, Name: "Crime of the Century", AirDate: 20181023},
+		{TmdbId: 3, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20130917, Watched: true, WatchedDate: 20240103},
+		{TmdbId: 4, Season: 1, Episode: 1, Name: "Pilot", AirDate: 20211207},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+}
+
+func listRequest(t *testing.T, handler gin.HandlerFunc, query string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	url := "/list"
+	r.GET(url, handler)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, url+query, nil)
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+	// println(w.Body.String())
+
+	if w.Code == http.StatusOK && response != nil {
+		err = json.Unmarshal(w.Body.Bytes(), response)
+		assert.Nil(t, err)
+	}
+	return w
+}
+
+func tvShowNames(tvShows []models.TvShow) []string {
+	names := []string{}
+	for _, tvShow := range tvShows {
+		names = append(names, tvShow.Name)
+	}
+	return names
+}
+
+func episodeIds(episodes []models.Episode) []int {
+	ids := []int{}
+	for _, episode := range episodes {
+		ids = append(ids, episode.Id)
+	}
+	return ids
+}
+
+func TestTvShowListPagination(t *testing.T) {
+	setUpListData(t)
+
+	var tvShows []models.TvShow
+	w := listRequest(t, controllers.TvShowListAll, "", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, []string{"Abbott Elementary", "Brooklyn Nine-Nine", "Castle", "The Rookie"}, tvShowNames(tvShows))
+
+	w = listRequest(t, controllers.TvShowListAll, "?limit=2&offset=1&sort=-name", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, "2", w.Header().Get("X-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-Offset"))
+	assert.Equal(t, []string{"Castle", "Brooklyn Nine-Nine"}, tvShowNames(tvShows))
+
+	w = listRequest(t, controllers.TvShowListAll, "?offset=10", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, 0, len(tvShows))
+}
+
+func TestTvShowListFilters(t *testing.T) {
+	setUpListData(t)
+
+	checkNames := func(query string, total string, names []string) {
+		var tvShows []models.TvShow
+		w := listRequest(t, controllers.TvShowListAll, query, &tvShows)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, total, w.Header().Get("X-Total-Count"))
+		assert.Equal(t, names, tvShowNames(tvShows))
+	}
+
+	checkNames("?group=1", "2", []string{"Castle", "The Rookie"})
+	checkNames("?status=1&sort=-group", "2", []string{"Abbott Elementary", "The Rookie"})
+	checkNames("?name=rOO", "2", []string{"Brooklyn Nine-Nine", "The Rookie"})
+	checkNames("?watched=true", "2", []string{"Brooklyn Nine-Nine", "Castle"})
+	checkNames("?watched=false&limit=1", "2", []string{"Abbott Elementary"})
+	checkNames("?air_date_from=20130101&air_date_to=20191231", "2", []string{"Brooklyn Nine-Nine", "The Rookie"})
+}
+
+func TestEpisodeListFilters(t *testing.T) {
+	setUpListData(t)
+
+	checkIds := func(query string, total string, ids []int) {
+		var episodes []models.Episode
+		w := listRequest(t, controllers.EpisodeListAll, query, &episodes)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, total, w.Header().Get("X-Total-Count"))
+		assert.Equal(t, ids, episodeIds(episodes))
+	}
+
+	checkIds("", "6", []int{1, 2, 3, 4, 5, 6})
+	checkIds("?tmdb_id=2&season=1", "2", []int{3, 4})
+	checkIds("?watched=true&sort=-watched_date", "3", []int{5, 2, 1})
+	checkIds("?watched=false&sort=air_date&limit=2", "3", []int{3, 4})
+	checkIds("?air_date_from=20181001&air_date_to=20211231", "3", []int{3, 4, 6})
+	checkIds("?group=1&status=2", "2", []int{1, 2})
+	checkIds("?name=pilot&sort=-tmdb_id", "3", []int{6, 5, 3})
+}
+
+func TestListErrors(t *testing.T) {
+	checkError := func(handler gin.HandlerFunc, query string, message string) {
+		w := listRequest(t, handler, query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError(controllers.TvShowListAll, "?limit=abc", "limit invalid")
+	checkError(controllers.TvShowListAll, "?limit=5000", "limit invalid, must be between 0 and 1000")
+	checkError(controllers.TvShowListAll, "?offset=-1", "offset invalid")
+	checkError(controllers.TvShowListAll, "?sort=air_date", "sort invalid, field air_date not allowed")
+	checkError(controllers.EpisodeListAll, "?sort=-group", "sort invalid, field group not allowed")
+	checkError(controllers.EpisodeListAll, "?watched=maybe", "watched invalid")
+	checkError(controllers.EpisodeListAll, "?air_date_from=20250230", "air date invalid, must be YYYY-MM-DD")
+}
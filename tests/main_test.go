@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/feealc/tvshows-backend-go/controllers"
-	"github.com/feealc/tvshows-backend-go/generic"
 	"github.com/feealc/tvshows-backend-go/models"
 	"github.com/feealc/tvshows-backend-go/tests/testutils"
 	"github.com/stretchr/testify/assert"
@@ -186,9 +185,9 @@ func TestTvShowCreateErrorBind(t *testing.T) {
 		TmdbId:    123,
 		Name:      "Test",
 		Overview:  "This is about",
-		GroupType: "2",
+		GroupType: "later",
 	}
-	testutils.CheckResponseError(r, t, url, tvShowGroup, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field TvShow.group of type int")
+	testutils.CheckResponseError(r, t, url, tvShowGroup, http.StatusBadRequest, "group invalid, value must be watching, planned or archived")
 
 	// STATUS
 	type TvShowBindStatus struct {
@@ -203,9 +202,9 @@ func TestTvShowCreateErrorBind(t *testing.T) {
 		Name:      "Test",
 		Overview:  "This is about",
 		GroupType: 2,
-		Status:    "0",
+		Status:    "airing",
 	}
-	testutils.CheckResponseError(r, t, url, tvShowStatus, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field TvShow.status of type int")
+	testutils.CheckResponseError(r, t, url, tvShowStatus, http.StatusBadRequest, "status invalid, value must be returning, ended, canceled, in_production or pilot")
 }
 
 func TestTvShowCreateErrorValidate(t *testing.T) {
@@ -256,7 +255,7 @@ func TestTvShowCreateErrorValidate(t *testing.T) {
 		GroupType: 14,
 		Status:    1,
 	}
-	testutils.CheckResponseError(r, t, url, tvShowGroup, http.StatusUnprocessableEntity, "GroupType: value must be 1, 2 or 3")
+	testutils.CheckResponseError(r, t, url, tvShowGroup, http.StatusUnprocessableEntity, "GroupType: value must be watching, planned or archived")
 
 	// STATUS
 	type TvShowValidateStatus struct {
@@ -271,7 +270,7 @@ func TestTvShowCreateErrorValidate(t *testing.T) {
 		GroupType: 1,
 		Status:    0,
 	}
-	testutils.CheckResponseError(r, t, url, tvShowStatus, http.StatusUnprocessableEntity, "Status: value must be 1, 2, 3, 4 or 5")
+	testutils.CheckResponseError(r, t, url, tvShowStatus, http.StatusUnprocessableEntity, "Status: value must be returning, ended, canceled, in_production or pilot")
 
 	// TMDB ID / NAME / GROUP / STATUS
 	type TvShowValidateAll struct {
@@ -287,7 +286,7 @@ func TestTvShowCreateErrorValidate(t *testing.T) {
 		Status:    6,
 	}
 	// sometimes the order of these error messages change
-	// msg := "TmdbId: zero value, Name: less than min, GroupType: value must be 1, 2 or 3, Status: value must be 1, 2, 3, 4 or 5"
+	// msg := "TmdbId: zero value, Name: less than min, GroupType: value must be watching, planned or archived, Status: value must be returning, ended, canceled, in_production or pilot"
 	msg := ""
 	testutils.CheckResponseError(r, t, url, tvShowAll, http.StatusUnprocessableEntity, msg)
 }
@@ -402,9 +401,9 @@ func TestTvShowCreateBatchErrorBind(t *testing.T) {
 		TmdbId:    123,
 		Name:      "Test",
 		Overview:  "This is about",
-		GroupType: "2",
+		GroupType: "later",
 	}
-	testutils.CheckResponseError(r, t, url, []TvShowBindGroup{tvShowGroup}, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field TvShow.group of type int")
+	testutils.CheckResponseError(r, t, url, []TvShowBindGroup{tvShowGroup}, http.StatusBadRequest, "group invalid, value must be watching, planned or archived")
 
 	// STATUS
 	type TvShowBindStatus struct {
@@ -419,9 +418,9 @@ func TestTvShowCreateBatchErrorBind(t *testing.T) {
 		Name:      "Test",
 		Overview:  "This is about",
 		GroupType: 2,
-		Status:    "0",
+		Status:    "airing",
 	}
-	testutils.CheckResponseError(r, t, url, []TvShowBindStatus{tvShowStatus}, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field TvShow.status of type int")
+	testutils.CheckResponseError(r, t, url, []TvShowBindStatus{tvShowStatus}, http.StatusBadRequest, "status invalid, value must be returning, ended, canceled, in_production or pilot")
 }
 
 func TestTvShowCreateBatchErrorValidate(t *testing.T) {
@@ -472,7 +471,7 @@ func TestTvShowCreateBatchErrorValidate(t *testing.T) {
 		GroupType: 14,
 		Status:    1,
 	}
-	testutils.CheckResponseError(r, t, url, []TvShowValidateGroup{tvShowGroup}, http.StatusUnprocessableEntity, "GroupType: value must be 1, 2 or 3")
+	testutils.CheckResponseError(r, t, url, []TvShowValidateGroup{tvShowGroup}, http.StatusUnprocessableEntity, "GroupType: value must be watching, planned or archived")
 
 	// STATUS
 	type TvShowValidateStatus struct {
@@ -487,7 +486,7 @@ func TestTvShowCreateBatchErrorValidate(t *testing.T) {
 		GroupType: 1,
 		Status:    0,
 	}
-	testutils.CheckResponseError(r, t, url, []TvShowValidateStatus{tvShowStatus}, http.StatusUnprocessableEntity, "Status: value must be 1, 2, 3, 4 or 5")
+	testutils.CheckResponseError(r, t, url, []TvShowValidateStatus{tvShowStatus}, http.StatusUnprocessableEntity, "Status: value must be returning, ended, canceled, in_production or pilot")
 
 	// TMDB ID / NAME / GROUP / STATUS
 	type TvShowValidateAll struct {
@@ -503,7 +502,7 @@ func TestTvShowCreateBatchErrorValidate(t *testing.T) {
 		Status:    6,
 	}
 	// sometimes the order of these error messages change
-	// msg := "TmdbId: zero value, Name: less than min, GroupType: value must be 1, 2 or 3, Status: value must be 1, 2, 3, 4 or 5"
+	// msg := "TmdbId: zero value, Name: less than min, GroupType: value must be watching, planned or archived, Status: value must be returning, ended, canceled, in_production or pilot"
 	msg := ""
 	testutils.CheckResponseError(r, t, url, []TvShowValidateAll{tvShowAll}, http.StatusUnprocessableEntity, msg)
 }
@@ -704,9 +703,9 @@ func TestEpisodeCreateErrorBind(t *testing.T) {
 		Episode:  1,
 		Name:     "Test",
 		Overview: "About",
-		AirDate:  "20250101",
+		AirDate:  "01/01/2025",
 	}
-	testutils.CheckResponseError(r, t, url, episodeAirDate, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field Episode.air_date of type int")
+	testutils.CheckResponseError(r, t, url, episodeAirDate, http.StatusBadRequest, "date invalid, must be YYYY-MM-DD")
 
 	// WATCHED
 	type EpisodeBindWatched struct {
@@ -748,9 +747,9 @@ func TestEpisodeCreateErrorBind(t *testing.T) {
 		Overview:    "About",
 		AirDate:     20250101,
 		Watched:     false,
-		WatchedDate: "20241231",
+		WatchedDate: "12/31/2024",
 	}
-	testutils.CheckResponseError(r, t, url, episodeWatchedDate, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field Episode.watched_date of type int")
+	testutils.CheckResponseError(r, t, url, episodeWatchedDate, http.StatusBadRequest, "date invalid, must be YYYY-MM-DD")
 }
 
 func TestEpisodeCreateErrorValidade(t *testing.T) {
@@ -782,11 +781,11 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 	}
 	episodeSeason := EpisodeValidadeSeason{
 		TmdbId:  10,
-		Season:  0,
+		Season:  -1,
 		Episode: 1,
 		Name:    "Test",
 	}
-	testutils.CheckResponseError(r, t, url, episodeSeason, http.StatusUnprocessableEntity, "Season: zero value")
+	testutils.CheckResponseError(r, t, url, episodeSeason, http.StatusUnprocessableEntity, "Season: less than min")
 
 	// EPISODE
 	type EpisodeValidadeEpisode struct {
@@ -798,10 +797,10 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 	episodeEpisode := EpisodeValidadeEpisode{
 		TmdbId:  1,
 		Season:  1,
-		Episode: 0,
+		Episode: -1,
 		Name:    "Test",
 	}
-	testutils.CheckResponseError(r, t, url, episodeEpisode, http.StatusUnprocessableEntity, "Episode: zero value")
+	testutils.CheckResponseError(r, t, url, episodeEpisode, http.StatusUnprocessableEntity, "Episode: less than min")
 
 	// NAME
 	type EpisodeValidadeName struct {
@@ -833,7 +832,7 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 		Name:    "Test",
 		AirDate: 202501,
 	}
-	testutils.CheckResponseError(r, t, url, episodeAirDateLen, http.StatusUnprocessableEntity, "AirDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, episodeAirDateLen, http.StatusUnprocessableEntity, "AirDate: date must be YYYY-MM-DD")
 
 	// AIR DATE - value < 0
 	type EpisodeValidadeAirDateValueNegative struct {
@@ -850,7 +849,7 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 		Name:    "Test",
 		AirDate: -1,
 	}
-	testutils.CheckResponseError(r, t, url, episodeAirDateValueNegative, http.StatusUnprocessableEntity, "AirDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, episodeAirDateValueNegative, http.StatusUnprocessableEntity, "AirDate: date must be YYYY-MM-DD")
 
 	// AIR DATE - invalid date (invalid month)
 	type EpisodeValidadeAirDateInvalidDateInvalidMonth struct {
@@ -901,7 +900,7 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 		Name:        "Test",
 		WatchedDate: 2025102,
 	}
-	testutils.CheckResponseError(r, t, url, episodeWatchedDateLen, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, episodeWatchedDateLen, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYY-MM-DD")
 
 	// WATCHED DATE - value < 0
 	type EpisodeValidadeWatchedDateValueNegative struct {
@@ -918,7 +917,7 @@ func TestEpisodeCreateErrorValidade(t *testing.T) {
 		Name:        "Test",
 		WatchedDate: -2025,
 	}
-	testutils.CheckResponseError(r, t, url, episodeWatchedDateValueNegative, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, episodeWatchedDateValueNegative, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYY-MM-DD")
 
 	// WATCHED DATE - invalid date (invalid month)
 	type EpisodeValidadeWatchedDateInvalidDateInvalidMonth struct {
@@ -1125,9 +1124,9 @@ func TestEpisodeCreateBatchErrorBind(t *testing.T) {
 		Episode:  1,
 		Name:     "Test",
 		Overview: "About",
-		AirDate:  "20250101",
+		AirDate:  "01/01/2025",
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeBindAirDate{episodeAirDate}, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field Episode.air_date of type int")
+	testutils.CheckResponseError(r, t, url, []EpisodeBindAirDate{episodeAirDate}, http.StatusBadRequest, "date invalid, must be YYYY-MM-DD")
 
 	// WATCHED
 	type EpisodeBindWatched struct {
@@ -1169,9 +1168,9 @@ func TestEpisodeCreateBatchErrorBind(t *testing.T) {
 		Overview:    "About",
 		AirDate:     20250101,
 		Watched:     false,
-		WatchedDate: "20241231",
+		WatchedDate: "12/31/2024",
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeBindWatchedDate{episodeWatchedDate}, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field Episode.watched_date of type int")
+	testutils.CheckResponseError(r, t, url, []EpisodeBindWatchedDate{episodeWatchedDate}, http.StatusBadRequest, "date invalid, must be YYYY-MM-DD")
 }
 
 func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
@@ -1203,11 +1202,11 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 	}
 	episodeSeason := EpisodeValidadeSeason{
 		TmdbId:  10,
-		Season:  0,
+		Season:  -1,
 		Episode: 1,
 		Name:    "Test",
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeSeason{episodeSeason}, http.StatusUnprocessableEntity, "Season: zero value")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeSeason{episodeSeason}, http.StatusUnprocessableEntity, "Season: less than min")
 
 	// EPISODE
 	type EpisodeValidadeEpisode struct {
@@ -1219,10 +1218,10 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 	episodeEpisode := EpisodeValidadeEpisode{
 		TmdbId:  1,
 		Season:  1,
-		Episode: 0,
+		Episode: -1,
 		Name:    "Test",
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeEpisode{episodeEpisode}, http.StatusUnprocessableEntity, "Episode: zero value")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeEpisode{episodeEpisode}, http.StatusUnprocessableEntity, "Episode: less than min")
 
 	// NAME
 	type EpisodeValidadeName struct {
@@ -1254,7 +1253,7 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 		Name:    "Test",
 		AirDate: 202501,
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeAirDateLen{episodeAirDateLen}, http.StatusUnprocessableEntity, "AirDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeAirDateLen{episodeAirDateLen}, http.StatusUnprocessableEntity, "AirDate: date must be YYYY-MM-DD")
 
 	// AIR DATE - value < 0
 	type EpisodeValidadeAirDateValueNegative struct {
@@ -1271,7 +1270,7 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 		Name:    "Test",
 		AirDate: -1,
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeAirDateValueNegative{episodeAirDateValueNegative}, http.StatusUnprocessableEntity, "AirDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeAirDateValueNegative{episodeAirDateValueNegative}, http.StatusUnprocessableEntity, "AirDate: date must be YYYY-MM-DD")
 
 	// AIR DATE - invalid date (invalid month)
 	type EpisodeValidadeAirDateInvalidDateInvalidMonth struct {
@@ -1322,7 +1321,7 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 		Name:        "Test",
 		WatchedDate: 2025102,
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeWatchedDateLen{episodeWatchedDateLen}, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeWatchedDateLen{episodeWatchedDateLen}, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYY-MM-DD")
 
 	// WATCHED DATE - value < 0
 	type EpisodeValidadeWatchedDateValueNegative struct {
@@ -1339,7 +1338,7 @@ func TestEpisodeCreateBatchErrorValidate(t *testing.T) {
 		Name:        "Test",
 		WatchedDate: -2025,
 	}
-	testutils.CheckResponseError(r, t, url, []EpisodeValidadeWatchedDateValueNegative{episodeWatchedDateValueNegative}, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYYMMDD")
+	testutils.CheckResponseError(r, t, url, []EpisodeValidadeWatchedDateValueNegative{episodeWatchedDateValueNegative}, http.StatusUnprocessableEntity, "WatchedDate: date must be YYYY-MM-DD")
 
 	// WATCHED DATE - invalid date (invalid month)
 	type EpisodeValidadeWatchedDateInvalidDateInvalidMonth struct {
@@ -1465,7 +1464,7 @@ func TestEpisodeMarkAsWatched(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	episodeToWatch.Watched = true
-	episodeToWatch.WatchedDate = generic.GetCurrentDate()
+	episodeToWatch.WatchedDate = models.Today(time.UTC)
 	testutils.CheckEpisode(t, episodeWatched, episodeToWatch)
 
 	err = UpdateEpisodeTest(episodeWatched)
@@ -1496,7 +1495,7 @@ func TestEpisodeMarkSeasonAsWatched(t *testing.T) {
 		episode, err := GetEpisodeTest(ep.Id)
 		assert.Nil(t, err)
 		episode.Watched = true
-		episode.WatchedDate = generic.GetCurrentDate()
+		episode.WatchedDate = models.Today(time.UTC)
 		testutils.CheckEpisode(t, ep, episode)
 		err = UpdateEpisodeTest(episode)
 		assert.Nil(t, err)
@@ -1562,4 +1561,4 @@ func TestEpisodeSummaryErrors(t *testing.T) {
 // }
 
 // FUNCOES INTERNAS QUE CRIEI
-// generic.GetCurrentDate()
+// models.Today(time.UTC)
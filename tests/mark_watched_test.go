@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func putMarkWatched(t *testing.T, url string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.PUT("/episodes/watched/season/:tmdbid/:season", controllers.EpisodeEditMarkWatched)
+	r.PUT("/episodes/watched/tvshow/:tmdbid", controllers.EpisodeEditMarkWatched)
+	return testutils.Request(t, r, http.MethodPut, url, nil, body, response)
+}
+
+func TestMarkSeasonAndTvShowWatched(t *testing.T) {
+	setUpWatchedUntilData(t)
+	today := models.Today(time.UTC)
+
+	var episodes []models.Episode
+	w := putMarkWatched(t, "/episodes/watched/season/1/2?watched=false", "", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{4, 5, 6}, episodeIds(episodes))
+	for _, episode := range episodes {
+		assert.False(t, episode.Watched)
+	}
+	assert.Equal(t, []models.Date{20240101, 0, 0, 0, 0, 0}, watchedDates(t))
+
+	// the date of the last watch stays for the history
+	assert.Equal(t, models.Date(20240105), episodes[1].WatchedDate)
+
+	// the episode watched already keeps its date
+	w = putMarkWatched(t, "/episodes/watched/season/1/1", `{"watched": true}`, &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Date{20240101, today, today, 0, 0, 0}, watchedDates(t))
+	assert.Equal(t, models.Date(20240101), episodes[0].WatchedDate)
+
+	w = putMarkWatched(t, "/episodes/watched/tvshow/1", "", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 6, len(episodes))
+	assert.Equal(t, []models.Date{20240101, today, today, today, today, today}, watchedDates(t))
+
+	// marking them again records no rewatch
+	events, err := testutils.GetTestUserRepository().WatchEvents().FindByTmdbId(1)
+	assert.Nil(t, err)
+	w = putMarkWatched(t, "/episodes/watched/tvshow/1", "", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 6, len(episodes))
+	rewatched, err := testutils.GetTestUserRepository().WatchEvents().FindByTmdbId(1)
+	assert.Nil(t, err)
+	assert.Equal(t, len(events), len(rewatched))
+
+	w = putMarkWatched(t, "/episodes/watched/tvshow/1?watched=0", `{"watched": false}`, &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.Date{0, 0, 0, 0, 0, 0}, watchedDates(t))
+}
+
+func TestMarkWatchedErrors(t *testing.T) {
+	setUpWatchedUntilData(t)
+	assert.Nil(t, testutils.GetTestRepository().TvShows().Create(&models.TvShow{TmdbId: 2, Name: "The Rookie", GroupType: 1, Status: 1}))
+
+	checkError := func(url string, body string, statusCode int, message string) {
+		w := putMarkWatched(t, url, body, nil)
+		assert.Equal(t, statusCode, w.Code, url)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("/episodes/watched/tvshow/1?watched=maybe", "", http.StatusBadRequest, "watched invalid")
+	checkError("/episodes/watched/tvshow/1?watched=true", `{"watched": false}`, http.StatusBadRequest, "watched invalid, query and body disagree")
+	checkError("/episodes/watched/tvshow/1", `{"watched": "no"}`, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field .watched of type bool")
+	checkError("/episodes/watched/tvshow/99", "", http.StatusNotFound, "TvShow not found")
+	checkError("/episodes/watched/tvshow/2", "", http.StatusNotFound, "episodes not found for The Rookie")
+	checkError("/episodes/watched/season/1/5", "", http.StatusNotFound, "episodes not found for season 5")
+}
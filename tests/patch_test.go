@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/mergepatch"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func patchRequest(t *testing.T, url string, contentType string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.PATCH("/tvshows/:id", controllers.TvShowPatch)
+	r.PATCH("/episodes/:id", controllers.EpisodePatch)
+
+	var headers map[string]string
+	if contentType != "" {
+		headers = map[string]string{"Content-Type": contentType}
+	}
+	return testutils.Request(t, r, http.MethodPatch, url, headers, body, response)
+}
+
+func TestMergePatchRfcExamples(t *testing.T) {
+	// appendix A of RFC 7396
+	examples := []struct {
+		target, patch, result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, example := range examples {
+		var target, patch interface{}
+		assert.Nil(t, json.Unmarshal([]byte(example.target), &target))
+		assert.Nil(t, json.Unmarshal([]byte(example.patch), &patch))
+
+		result, err := json.Marshal(mergepatch.Merge(target, patch))
+		assert.Nil(t, err)
+		assert.Equal(t, example.result, string(result), example.patch)
+	}
+}
+
+func TestTvShowPatch(t *testing.T) {
+	setUpListData(t)
+	assert.Nil(t, testutils.GetTestRepository().TvShows().Save(&models.TvShow{Id: 1, TmdbId: 1, Name: "Castle", Overview: "Crime novelist", GroupType: 1, Status: 2}))
+
+	var tvShow models.TvShow
+	w := patchRequest(t, "/tvshows/1", mergepatch.ContentType, `{"status": 3}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckTvShow(t, tvShow, models.TvShow{Id: 1, TmdbId: 1, Name: "Castle", Overview: "Crime novelist", GroupType: 1, Status: 3})
+
+	// null clears the field, repeating an immutable field is fine
+	w = patchRequest(t, "/tvshows/1", "application/json; charset=utf-8", `{"overview": null, "tmdb_id": 1}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckTvShow(t, tvShow, models.TvShow{Id: 1, TmdbId: 1, Name: "Castle", GroupType: 1, Status: 3})
+
+	stored, err := testutils.GetTestRepository().TvShows().FindById(1)
+	assert.Nil(t, err)
+	testutils.CheckTvShow(t, stored, tvShow)
+}
+
+func TestEpisodePatch(t *testing.T) {
+	setUpListData(t)
+
+	var episode models.Episode
+	w := patchRequest(t, "/episodes/1", "", `{"overview": "Pilot", "watched_date": 0}`, &episode)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckEpisode(t, episode, models.Episode{Id: 1, TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", Overview: "Pilot", AirDate: 20090309, Watched: true})
+
+	w = patchRequest(t, "/episodes/1", mergepatch.ContentType, `{"watched": null}`, &episode)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored, err := testutils.GetTestUserRepository().Episodes().FindById(1)
+	assert.Nil(t, err)
+	testutils.CheckEpisode(t, stored, models.Episode{Id: 1, TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", Overview: "Pilot", AirDate: 20090309})
+}
+
+func TestPatchErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(url string, contentType string, body string, statusCode int, message string) {
+		w := patchRequest(t, url, contentType, body, nil)
+		assert.Equal(t, statusCode, w.Code, body)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("/tvshows/abc", "", `{}`, http.StatusBadRequest, "id invalid")
+	checkError("/tvshows/99", "", `{}`, http.StatusNotFound, "TvShow not found")
+	checkError("/tvshows/1", "text/plain", `{}`, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json")
+	checkError("/tvshows/1", "", `[{"name": "Castle"}]`, http.StatusBadRequest, "merge patch must be a json object")
+	checkError("/tvshows/1", "", `{"id": 2}`, http.StatusUnprocessableEntity, "id cannot be changed")
+	checkError("/tvshows/1", "", `{"tmdb_id": null}`, http.StatusUnprocessableEntity, "tmdb_id cannot be changed")
+	checkError("/tvshows/1", "", `{"created_at": "2020-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity, "created_at cannot be changed")
+	checkError("/tvshows/1", "", `{"name": null}`, http.StatusUnprocessableEntity, "Name: less than min")
+	checkError("/tvshows/1", "", `{"name": "The Rookie"}`, http.StatusBadRequest, "TvShow name The Rookie already exist")
+	checkError("/tvshows/1", "", `{"rating": 5}`, http.StatusBadRequest, `json: unknown field \"rating\"`)
+	checkError("/episodes/1", "", `{"tmdb_id": 2}`, http.StatusUnprocessableEntity, "tmdb_id cannot be changed")
+	checkError("/episodes/1", "", `{"air_date": 20091399}`, http.StatusUnprocessableEntity, `AirDate: parsing time \"20091399\": month out of range`)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/routes"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setUpAppRoutes serves the routes of the application with their policies.
+func setUpAppRoutes() *gin.Engine {
+	r := testutils.SetUpTestRoutes(false)
+	routes.SetUpRoutes(r, testutils.GetTestRepository(), tmdb.NewClient("", ""), testSigner)
+	return r
+}
+
+func TestRoles(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpAppRoutes()
+	adminToken := registerAndLogin(t, r, "/api/v1", "", "alice")
+	userToken := registerAndLogin(t, r, "/api/v1", adminToken, "bob")
+
+	var bob models.User
+	w := userRequest(t, r, http.MethodGet, "/api/v1/users/me", userToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	unmarshal(t, w, &bob)
+	assert.Equal(t, models.UserRoleViewer, bob.Role)
+
+	checkStatus := func(method string, url string, token string, body interface{}, statusCode int, message string) {
+		w := userRequest(t, r, method, "/api/v1"+url, token, body)
+		assert.Equal(t, statusCode, w.Code, method+" "+url)
+		if message != "" {
+			assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+		}
+	}
+
+	tvShow := models.TvShow{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2}
+
+	// health stays public, the rest needs a credential
+	checkStatus(http.MethodGet, "/health", "", nil, http.StatusOK, "")
+	checkStatus(http.MethodGet, "/tvshows", "", nil, http.StatusUnauthorized, "authentication required")
+
+	// strangers and viewers do not register users
+	newUser := controllers.Credentials{Username: "carol", Password: "carol-password"}
+	checkStatus(http.MethodPost, "/users/register", "", newUser, http.StatusForbidden, "registration closed, an admin creates the accounts")
+	checkStatus(http.MethodPost, "/users/register", userToken, newUser, http.StatusForbidden, "registration closed, an admin creates the accounts")
+
+	// viewers read
+	checkStatus(http.MethodGet, "/tvshows", userToken, nil, http.StatusOK, "")
+	checkStatus(http.MethodPost, "/tvshows/create", userToken, tvShow, http.StatusForbidden, "role editor required")
+	checkStatus(http.MethodDelete, "/truncate/all", userToken, nil, http.StatusForbidden, "role admin required")
+	checkStatus(http.MethodPut, "/users/1/role", userToken, controllers.UserRole{Role: models.UserRoleAdmin}, http.StatusForbidden, "role admin required")
+
+	// editors create and edit
+	roleUrl := "/users/" + strconv.Itoa(bob.Id) + "/role"
+	checkStatus(http.MethodPut, roleUrl, adminToken, controllers.UserRole{Role: "owner"}, http.StatusUnprocessableEntity, "Role: value must be admin, editor or viewer")
+	checkStatus(http.MethodPut, roleUrl, adminToken, controllers.UserRole{Role: models.UserRoleEditor}, http.StatusOK, "")
+	checkStatus(http.MethodPost, "/tvshows/create", userToken, tvShow, http.StatusCreated, "")
+	checkStatus(http.MethodDelete, "/tvshows/1", userToken, nil, http.StatusForbidden, "role admin required")
+	checkStatus(http.MethodDelete, "/tvshows/truncate", userToken, nil, http.StatusForbidden, "role admin required")
+	checkStatus(http.MethodDelete, "/episodes/truncate", userToken, nil, http.StatusForbidden, "role admin required")
+
+	// admins delete and truncate, but keep their own role
+	checkStatus(http.MethodPut, "/users/1/role", adminToken, controllers.UserRole{Role: models.UserRoleViewer}, http.StatusForbidden, "cannot change your own role")
+	checkStatus(http.MethodPut, "/users/99/role", adminToken, controllers.UserRole{Role: models.UserRoleViewer}, http.StatusNotFound, "User not found")
+	checkStatus(http.MethodDelete, "/tvshows/1", adminToken, nil, http.StatusOK, "")
+	checkStatus(http.MethodDelete, "/truncate/all", adminToken, nil, http.StatusOK, "")
+}
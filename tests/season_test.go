@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+	"github.com/feealc/tvshows-backend-go/tvsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func seasonRequest(t *testing.T, method string, url string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.GET("/tvshows/:id/seasons", controllers.SeasonListByTvShow)
+	r.GET("/tvshows/:id/seasons/:season", controllers.SeasonListByNumber)
+	r.POST("/tvshows/:id/seasons", controllers.SeasonCreate)
+	r.PUT("/tvshows/:id/seasons/:season", controllers.SeasonEdit)
+	r.DELETE("/tvshows/:id/seasons/:season", controllers.SeasonDelete)
+	r.GET("/episodes/summary/:id", controllers.EpisodeSummaryBySeason)
+	return testutils.Request(t, r, method, url, nil, body, response)
+}
+
+func seasonNumbers(seasons []models.Season) []int {
+	numbers := []int{}
+	for _, season := range seasons {
+		numbers = append(numbers, season.Number)
+	}
+	return numbers
+}
+
+func TestSeasonCrud(t *testing.T) {
+	setUpListData(t)
+
+	var season models.Season
+	w := seasonRequest(t, http.MethodPost, "/tvshows/1/seasons", `{"number": 2, "name": " Season 2 ", "air_date": 20090921, "episode_count": 24, "poster_path": "/castle2.jpg"}`, &season)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, models.Season{Id: 1, TmdbId: 1, Number: 2, Name: "Season 2", AirDate: 20090921, EpisodeCount: 24, PosterPath: "/castle2.jpg"},
+		models.Season{Id: season.Id, TmdbId: season.TmdbId, Number: season.Number, Name: season.Name, AirDate: season.AirDate, EpisodeCount: season.EpisodeCount, PosterPath: season.PosterPath})
+
+	// specials live in season 0
+	w = seasonRequest(t, http.MethodPost, "/tvshows/1/seasons", `{"tmdb_id": 1, "number": 0, "name": "Specials"}`, &season)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 0, season.Number)
+
+	var seasons []models.Season
+	w = seasonRequest(t, http.MethodGet, "/tvshows/1/seasons", "", &seasons)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{2, 0}, seasonNumbers(seasons))
+
+	w = seasonRequest(t, http.MethodPut, "/tvshows/1/seasons/2", `{"name": "Season Two", "episode_count": 24}`, &season)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Season Two", season.Name)
+	assert.Equal(t, 2, season.Number)
+	assert.Equal(t, models.Date(0), season.AirDate)
+
+	w = seasonRequest(t, http.MethodGet, "/tvshows/1/seasons/2", "", &season)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Season Two", season.Name)
+
+	w = seasonRequest(t, http.MethodDelete, "/tvshows/1/seasons/0", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"message":"Season deleted"}`, w.Body.String())
+	w = seasonRequest(t, http.MethodGet, "/tvshows/1/seasons", "", &seasons)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{2}, seasonNumbers(seasons))
+}
+
+func TestSeasonErrors(t *testing.T) {
+	setUpListData(t)
+	w := seasonRequest(t, http.MethodPost, "/tvshows/1/seasons", `{"number": 1}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	checkError := func(method string, url string, body string, statusCode int, message string) {
+		w := seasonRequest(t, method, url, body, nil)
+		assert.Equal(t, statusCode, w.Code, method+" "+url)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError(http.MethodGet, "/tvshows/99/seasons", "", http.StatusNotFound, "TvShow not found")
+	checkError(http.MethodGet, "/tvshows/1/seasons/x", "", http.StatusBadRequest, "season invalid")
+	checkError(http.MethodGet, "/tvshows/1/seasons/7", "", http.StatusNotFound, "Season not found")
+	checkError(http.MethodPost, "/tvshows/1/seasons", `{"number": 1}`, http.StatusBadRequest, "Season 1 of Castle already exist")
+	checkError(http.MethodPost, "/tvshows/1/seasons", `{"tmdb_id": 2, "number": 2}`, http.StatusBadRequest, "tmdb_id does not match the url")
+	checkError(http.MethodPost, "/tvshows/1/seasons", `{"number": -1}`, http.StatusUnprocessableEntity, "Number: less than min")
+	checkError(http.MethodPost, "/tvshows/1/seasons", `{"number": 2, "air_date": 2024}`, http.StatusUnprocessableEntity, "AirDate: date must be YYYY-MM-DD")
+	checkError(http.MethodPost, "/tvshows/1/seasons", `{"number": "2"}`, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field Season.number of type int")
+	checkError(http.MethodPut, "/tvshows/1/seasons/1", `{"number": 3}`, http.StatusBadRequest, "number does not match the url")
+	checkError(http.MethodDelete, "/tvshows/1/seasons/2", "", http.StatusNotFound, "Season not found")
+}
+
+func TestSeasonSummary(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+
+	for _, season := range []models.Season{
+		{TmdbId: 1, Number: 0, Name: "Specials", EpisodeCount: 3},
+		{TmdbId: 1, Number: 1, Name: "Season 1", EpisodeCount: 10},
+		{TmdbId: 1, Number: 2, Name: "Season 2", EpisodeCount: 24},
+	} {
+		assert.Nil(t, repo.Seasons().Create(&season))
+	}
+
+	w := seasonRequest(t, http.MethodGet, "/episodes/summary/1", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[`+
+		`{"season":1,"name":"Season 1","episode_count":10,"total_episodes":2,"total_episodes_watched":2},`+
+		`{"season":2,"name":"Season 2","episode_count":24,"total_episodes":0,"total_episodes_watched":0},`+
+		`{"season":0,"name":"Specials","episode_count":3,"total_episodes":0,"total_episodes_watched":0}]`, w.Body.String())
+
+	// shows without seasons or episodes
+	w = seasonRequest(t, http.MethodGet, "/episodes/summary/2", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"season":1,"total_episodes":2,"total_episodes_watched":0}]`, w.Body.String())
+	assert.Nil(t, repo.TvShows().Create(&models.TvShow{TmdbId: 5, Name: "Severance", GroupType: 1, Status: 1}))
+	w = seasonRequest(t, http.MethodGet, "/episodes/summary/5", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[]`, w.Body.String())
+}
+
+func TestSeasonImportAndSync(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	stub := newTmdbStubBreakingBad()
+	defer stub.Close()
+
+	w := importTvShow(t, stub, TMDBID_BREAKINGBAD)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Seasons []models.Season `json:"seasons"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []int{1, 2, 0}, seasonNumbers(response.Seasons))
+	assert.Equal(t, "Season 1", response.Seasons[0].Name)
+	assert.Equal(t, models.Date(20080120), response.Seasons[0].AirDate)
+	assert.Equal(t, 2, response.Seasons[0].EpisodeCount)
+
+	// TMDB announces season 3 before any of its episodes
+	stub.SetTvShow(
+		tmdb.TvShow{Id: TMDBID_BREAKINGBAD, Name: "Breaking Bad", Status: "Ended"},
+		tmdb.Season{SeasonNumber: 1, Name: "Season One", AirDate: "2008-01-20"},
+		tmdb.Season{SeasonNumber: 3, Name: "Season 3", PosterPath: "/bb3.jpg"},
+	)
+	repo := testutils.GetTestUserRepository()
+	tvShow, err := repo.TvShows().FindByTmdbId(TMDBID_BREAKINGBAD)
+	assert.Nil(t, err)
+	_, err = tvsync.SyncTvShow(repo, stub.Client(), tvShow)
+	assert.Nil(t, err)
+
+	seasons, err := repo.Seasons().FindByTmdbId(TMDBID_BREAKINGBAD)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2, 3, 0}, seasonNumbers(seasons))
+	assert.Equal(t, "Season One", seasons[0].Name)
+	assert.Equal(t, 0, seasons[0].EpisodeCount)
+	assert.Equal(t, "/bb3.jpg", seasons[2].PosterPath)
+
+	w = seasonRequest(t, http.MethodGet, "/episodes/summary/"+strconv.Itoa(tvShow.Id), "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `{"season":3,"name":"Season 3","total_episodes":0,"total_episodes_watched":0}`)
+
+	// deleting the show deletes its seasons
+	r := testutils.SetUpTestRoutes(true)
+	r.DELETE("/tvshows/:id", controllers.TvShowDelete)
+	req, err := http.NewRequest(http.MethodDelete, "/tvshows/"+strconv.Itoa(tvShow.Id), nil)
+	assert.Nil(t, err)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	seasons, err = repo.Seasons().FindByTmdbId(TMDBID_BREAKINGBAD)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(seasons))
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// setUpSpecialsData adds specials to Castle and Abbott Elementary and an
+// episode 0 to The Rookie.
+func setUpSpecialsData(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+
+	episodes := []models.Episode{
+		{TmdbId: 4, Season: 0, Episode: 1, Name: "Holiday Special", AirDate: 20221207},
+		{TmdbId: 2, Season: 1, Episode: 0, Name: "Prologue", AirDate: 20181009},
+		{TmdbId: 1, Season: 0, Episode: 1, Name: "Unbound", AirDate: 20100101},
+	}
+	for index := range episodes {
+		assert.Nil(t, models.ValidEpisode(&episodes[index]))
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+}
+
+func TestSpecialsEpisodeList(t *testing.T) {
+	setUpSpecialsData(t)
+
+	// specials come after the other seasons, episode 0 before episode 1
+	var episodes []models.Episode
+	w := listRequest(t, controllers.EpisodeListAll, "", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{1, 2, 9, 8, 3, 4, 5, 6, 7}, episodeIds(episodes))
+
+	w = listRequest(t, controllers.EpisodeListAll, "?tmdb_id=4&sort=season", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{6, 7}, episodeIds(episodes))
+
+	w = listRequest(t, controllers.EpisodeListAll, "?tmdb_id=4&sort=-season", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{7, 6}, episodeIds(episodes))
+
+	w = listRequest(t, controllers.EpisodeListAll, "?specials=false", &episodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{1, 2, 8, 3, 4, 5, 6}, episodeIds(episodes))
+	assert.Equal(t, "7", w.Header().Get("X-Total-Count"))
+
+	w = listRequest(t, controllers.EpisodeListAll, "?specials=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"specials invalid"}`, w.Body.String())
+}
+
+func TestSpecialsUnwatched(t *testing.T) {
+	setUpSpecialsData(t)
+
+	var tvShows []models.TvShow
+	w := listRequest(t, controllers.TvShowListAll, "?sort=tmdb_id", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{0, 1, 0, 1}, []int{tvShows[0].UnwatchedSeason, tvShows[0].UnwatchedEpisode, tvShows[0].UnwatchedCount, tvShows[3].UnwatchedCount})
+
+	w = listRequest(t, controllers.TvShowListAll, "?sort=tmdb_id&specials=false", &tvShows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{0, 0, 0, 0}, []int{tvShows[0].UnwatchedSeason, tvShows[0].UnwatchedEpisode, tvShows[0].UnwatchedCount, tvShows[3].UnwatchedCount})
+
+	type TvShowEpisodes struct {
+		TvShow   models.TvShow    `json:"tv_show"`
+		Episodes []models.Episode `json:"episodes"`
+	}
+	var response []TvShowEpisodes
+	w = listRequest(t, controllers.TvShowListAllUnwatchedEpisodes, "", &response)
+	assert.Equal(t, http.StatusOK, w.Code)
+	unwatched := []int{}
+	for _, row := range response {
+		unwatched = append(unwatched, episodeIds(row.Episodes)...)
+	}
+	assert.Equal(t, []int{6, 7, 9, 8, 3, 4}, unwatched)
+
+	w = listRequest(t, controllers.TvShowListAllUnwatchedEpisodes, "?specials=false", &response)
+	assert.Equal(t, http.StatusOK, w.Code)
+	unwatched = []int{}
+	for _, row := range response {
+		unwatched = append(unwatched, episodeIds(row.Episodes)...)
+	}
+	assert.Equal(t, []int{6, 8, 3, 4}, unwatched)
+
+	w = listRequest(t, controllers.TvShowListAllUnwatchedEpisodes, "?specials=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"specials invalid"}`, w.Body.String())
+}
+
+func TestSpecialsUpNext(t *testing.T) {
+	setUpSpecialsData(t)
+
+	var rows []controllers.UpNext
+	w := listRequest(t, controllers.UpNextList, "", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Castle Unbound", "Abbott Elementary Pilot", "The Rookie Prologue"}, upNextKeys(rows))
+
+	w = listRequest(t, controllers.UpNextList, "?specials=false", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Abbott Elementary Pilot", "The Rookie Prologue"}, upNextKeys(rows))
+
+	w = listRequest(t, controllers.UpNextList, "?specials=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"specials invalid"}`, w.Body.String())
+}
+
+func TestSpecialsSummary(t *testing.T) {
+	setUpSpecialsData(t)
+
+	w := seasonRequest(t, http.MethodGet, "/episodes/summary/1", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[`+
+		`{"season":1,"total_episodes":2,"total_episodes_watched":2},`+
+		`{"season":0,"total_episodes":1,"total_episodes_watched":0}]`, w.Body.String())
+
+	w = seasonRequest(t, http.MethodGet, "/episodes/summary/1?specials=false", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"season":1,"total_episodes":2,"total_episodes_watched":2}]`, w.Body.String())
+
+	w = seasonRequest(t, http.MethodGet, "/episodes/summary/1?specials=x", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"specials invalid"}`, w.Body.String())
+}
+
+func TestSpecialsWatchedUntil(t *testing.T) {
+	setUpSpecialsData(t)
+
+	// the special airs before the pilot but comes after the regular seasons
+	var tvShow models.TvShow
+	w := postWatchedUntil(t, "/tvshows/4/watched-until", `{"season": 1, "episode": 1}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{0, 1, 0}, []int{tvShow.UnwatchedSeason, tvShow.UnwatchedEpisode, tvShow.UnwatchedCount})
+
+	// episode 0 comes before episode 1
+	w = postWatchedUntil(t, "/tvshows/2/watched-until", `{"season": 1, "episode": 1}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{1, 2, 0}, []int{tvShow.UnwatchedSeason, tvShow.UnwatchedEpisode, tvShow.UnwatchedCount})
+}
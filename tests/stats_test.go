@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+
+	watch := func(tmdbId int, watchedDate models.Date) {
+		episode, err := repo.Episodes().FindByKey(tmdbId, 1, 1)
+		assert.Nil(t, err)
+		episode.Watched, episode.WatchedDate = true, watchedDate
+		assert.Nil(t, repo.Episodes().SaveWatchState(&episode))
+	}
+	watch(2, 20240215)
+	watch(4, 20230105)
+
+	var stats repository.Stats
+	w := listRequest(t, controllers.Stats, "", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, repository.Stats{
+		Watched: 5,
+		PerMonth: []repository.StatsPeriod{
+			{Period: "2023-01", Watched: 1},
+			{Period: "2024-01", Watched: 3},
+			{Period: "2024-02", Watched: 1},
+		},
+		PerYear: []repository.StatsPeriod{
+			{Period: "2023", Watched: 1},
+			{Period: "2024", Watched: 4},
+		},
+		PerTvShow: []repository.TvShowStats{
+			{TmdbId: 4, Name: "Abbott Elementary", Episodes: 1, Watched: 1, Completion: 100},
+			{TmdbId: 3, Name: "Brooklyn Nine-Nine", Episodes: 1, Watched: 1, Completion: 100},
+			{TmdbId: 1, Name: "Castle", Episodes: 2, Watched: 2, Completion: 100},
+			{TmdbId: 2, Name: "The Rookie", Episodes: 2, Watched: 1, Completion: 50},
+		},
+		ByGroup: []repository.StatsCount{
+			{Value: 1, Name: "watching", TvShows: 2, Watched: 3},
+			{Value: 2, Name: "planned", TvShows: 1, Watched: 1},
+			{Value: 3, Name: "archived", TvShows: 1, Watched: 1},
+		},
+		ByStatus: []repository.StatsCount{
+			{Value: 1, Name: "returning", TvShows: 2, Watched: 2},
+			{Value: 2, Name: "ended", TvShows: 2, Watched: 3},
+		},
+		LongestStreak: repository.Streak{Days: 3, Start: 20240101, End: 20240103},
+	}, stats)
+
+	// completion keeps counting every episode watched so far
+	w = listRequest(t, controllers.Stats, "?from=20240102&to=20240131", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(2), stats.Watched)
+	assert.Equal(t, []repository.StatsPeriod{{Period: "2024-01", Watched: 2}}, stats.PerMonth)
+	assert.Equal(t, []repository.StatsPeriod{{Period: "2024", Watched: 2}}, stats.PerYear)
+	assert.Equal(t, []repository.TvShowStats{
+		{TmdbId: 4, Name: "Abbott Elementary", Episodes: 1, Watched: 0, Completion: 100},
+		{TmdbId: 3, Name: "Brooklyn Nine-Nine", Episodes: 1, Watched: 1, Completion: 100},
+		{TmdbId: 1, Name: "Castle", Episodes: 2, Watched: 1, Completion: 100},
+		{TmdbId: 2, Name: "The Rookie", Episodes: 2, Watched: 0, Completion: 50},
+	}, stats.PerTvShow)
+	assert.Equal(t, repository.Streak{Days: 2, Start: 20240102, End: 20240103}, stats.LongestStreak)
+
+	// the most recent streak wins a tie
+	rookie, err := repo.Episodes().FindByKey(2, 1, 2)
+	assert.Nil(t, err)
+	rookie.Watched, rookie.WatchedDate = true, 20240216
+	assert.Nil(t, repo.Episodes().SaveWatchState(&rookie))
+	w = listRequest(t, controllers.Stats, "?from=20240102", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, repository.Streak{Days: 2, Start: 20240215, End: 20240216}, stats.LongestStreak)
+
+	// a rewatch counts again, an unmarked episode keeps its watches
+	castle, err := repo.Episodes().FindByKey(1, 1, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.WatchEvents().Create(&models.WatchEvent{EpisodeId: castle.Id, WatchedAt: time.Date(2024, time.February, 20, 23, 30, 0, 0, time.UTC)}))
+	brooklyn, err := repo.Episodes().FindByKey(3, 1, 1)
+	assert.Nil(t, err)
+	brooklyn.Watched = false
+	assert.Nil(t, repo.Episodes().SaveWatchState(&brooklyn))
+
+	w = listRequest(t, controllers.Stats, "", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(7), stats.Watched)
+	assert.Equal(t, []repository.StatsPeriod{{Period: "2023", Watched: 1}, {Period: "2024", Watched: 6}}, stats.PerYear)
+	assert.Equal(t, []repository.TvShowStats{
+		{TmdbId: 4, Name: "Abbott Elementary", Episodes: 1, Watched: 1, Completion: 100},
+		{TmdbId: 3, Name: "Brooklyn Nine-Nine", Episodes: 1, Watched: 1, Completion: 0},
+		{TmdbId: 1, Name: "Castle", Episodes: 2, Watched: 3, Completion: 100},
+		{TmdbId: 2, Name: "The Rookie", Episodes: 2, Watched: 2, Completion: 100},
+	}, stats.PerTvShow)
+
+	w = listRequest(t, controllers.Stats, "?from=20240201", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(3), stats.Watched)
+	assert.Equal(t, []repository.StatsPeriod{{Period: "2024-02", Watched: 3}}, stats.PerMonth)
+	assert.Equal(t, []repository.StatsCount{
+		{Value: 1, Name: "watching", TvShows: 2, Watched: 3},
+		{Value: 2, Name: "planned", TvShows: 1, Watched: 0},
+		{Value: 3, Name: "archived", TvShows: 1, Watched: 0},
+	}, stats.ByGroup)
+	assert.Equal(t, repository.Streak{Days: 2, Start: 20240215, End: 20240216}, stats.LongestStreak)
+
+	w = listRequest(t, controllers.Stats, "?from=20250101", &stats)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), stats.Watched)
+	assert.Equal(t, []repository.StatsPeriod{}, stats.PerMonth)
+	assert.Equal(t, repository.Streak{}, stats.LongestStreak)
+}
+
+func TestStatsErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(query string, message string) {
+		w := listRequest(t, controllers.Stats, query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("?from=2024", "from invalid, must be YYYY-MM-DD")
+	checkError("?to=x", "to invalid, must be YYYY-MM-DD")
+	checkError("?from=20240201&to=20240101", "to invalid, must not be before from")
+}
+
+// seedStats stores shows with rewatches, unmarked episodes and an episode
+// watched before watched dates were kept.
+func seedStats(t *testing.T, repo repository.Repository) {
+	assert.Nil(t, repo.TvShows().CreateMany([]models.TvShow{
+		{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2},
+		{TmdbId: 2, Name: "The Rookie", GroupType: 1, Status: 1},
+		{TmdbId: 3, Name: "Brooklyn Nine-Nine", GroupType: 2, Status: 2},
+	}))
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", Watched: true, WatchedDate: 20240101},
+		{TmdbId: 1, Season: 1, Episode: 2, Name: "Nanny McDead", Watched: true, WatchedDate: 20240102},
+		{TmdbId: 1, Season: 1, Episode: 3, Name: "Hedge Fund Homeboys", Watched: true},
+		{TmdbId: 2, Season: 1, Episode: 1, Name: "Pilot", Watched: true, WatchedDate: 20231231},
+		{TmdbId: 2, Season: 1, Episode: 2, Name: "Crime of the Century"},
+		{TmdbId: 3, Season: 1, Episode: 1, Name: "Pilot", Watched: true, WatchedDate: 20240103},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+
+	castle, err := repo.Episodes().FindByKey(1, 1, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.WatchEvents().Create(&models.WatchEvent{EpisodeId: castle.Id, WatchedAt: time.Date(2024, time.March, 1, 23, 30, 0, 0, time.UTC)}))
+	brooklyn, err := repo.Episodes().FindByKey(3, 1, 1)
+	assert.Nil(t, err)
+	brooklyn.Watched = false
+	assert.Nil(t, repo.Episodes().SaveWatchState(&brooklyn))
+}
+
+// TestStatsDatabase checks the aggregate queries of the gorm repository give
+// the stats of the memory one, which the other tests pin down.
+func TestStatsDatabase(t *testing.T) {
+	if os.Getenv("TEST_DATABASE") != "postgres" {
+		t.Skip("needs TEST_DATABASE=postgres")
+	}
+
+	testutils.ResetTestRepository(t)
+	database := testutils.GetTestUserRepository()
+	memory := repository.NewMemoryRepository().ForUser(testutils.GetTestUser().Id)
+	seedStats(t, database)
+	seedStats(t, memory)
+
+	for _, watched := range []repository.StatsRange{
+		{},
+		{To: 20240102},
+		{From: 20240101},
+		{From: 20240102, To: 20240301},
+	} {
+		expected, err := memory.Stats().Summary(watched)
+		assert.Nil(t, err)
+		stats, err := database.Stats().Summary(watched)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, stats, "%+v", watched)
+	}
+}
@@ -6,27 +6,120 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/feealc/tvshows-backend-go/auth"
 	"github.com/feealc/tvshows-backend-go/controllers"
 	"github.com/feealc/tvshows-backend-go/database"
 	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-func SetUpTestRoutes(connectDb bool) *gin.Engine {
-	if connectDb {
-		database.ConnectDataBase()
+const (
+	TEST_USER_NAME     = "tester"
+	TEST_USER_PASSWORD = "tester-password"
+)
+
+var (
+	testRepository repository.Repository
+	testUser       models.User
+)
+
+// GetTestRepository returns the repository shared by every test. It lives in
+// memory unless TEST_DATABASE=postgres, which connects using the DB_* env vars.
+func GetTestRepository() repository.Repository {
+	if testRepository == nil {
+		if os.Getenv("TEST_DATABASE") == "postgres" {
+			database.ConnectDataBase()
+			testRepository = repository.NewGormRepository(database.DB)
+		} else {
+			testRepository = repository.NewMemoryRepository()
+		}
 	}
+	return testRepository
+}
+
+// GetTestUser returns the admin account the test routes act as, creating it
+// the first time.
+func GetTestUser() models.User {
+	if testUser.Id == 0 {
+		repo := GetTestRepository()
+		user, err := repo.Users().FindByUsername(TEST_USER_NAME)
+		if err != nil {
+			panic(err)
+		}
+
+		if user.Id == 0 {
+			user.Username = TEST_USER_NAME
+			user.Role = models.UserRoleAdmin
+			if user.PasswordHash, err = auth.HashPassword(TEST_USER_PASSWORD); err != nil {
+				panic(err)
+			}
+			if err := repo.Users().Create(&user); err != nil {
+				panic(err)
+			}
+		}
+		testUser = user
+	}
+	return testUser
+}
+
+// GetTestUserRepository returns the shared repository bound to the watch
+// state of the test user.
+func GetTestUserRepository() repository.Repository {
+	return GetTestRepository().ForUser(GetTestUser().Id)
+}
+
+// ResetTestRepository drops and creates every table of the shared repository,
+// for test files that must not depend on the state left by other files.
+func ResetTestRepository(t *testing.T) {
+	repo := GetTestRepository()
+	assert.Nil(t, repo.TvShows().Truncate(true))
+	assert.Nil(t, repo.Episodes().Truncate(true))
+	assert.Nil(t, repo.Seasons().Truncate(true))
+	assert.Nil(t, repo.Users().Truncate(true))
+	assert.Nil(t, repo.ApiKeys().Truncate(true))
+	testUser = models.User{}
+}
+
+// SetUpTestRoutes returns an engine for the handlers under test. With
+// connectDb the requests use the shared repository as the test user.
+func SetUpTestRoutes(connectDb bool) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	// routes := gin.Default()
 	routes := gin.New()
 	routes.Use(gin.Recovery())
+	if connectDb {
+		routes.Use(controllers.UseRepository(GetTestRepository()))
+		routes.Use(func(c *gin.Context) {
+			controllers.SetCurrentUser(c, GetTestUser())
+			c.Next()
+		})
+	}
 	return routes
 }
 
+// Request serves one request through r with the given headers and decodes a
+// 200 or 201 body into response, unless response is nil.
+func Request(t *testing.T, r *gin.Engine, method string, url string, headers map[string]string, body string, response interface{}) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.Nil(t, err)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if (w.Code == http.StatusOK || w.Code == http.StatusCreated) && response != nil {
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), response))
+	}
+	return w
+}
+
 func CheckResponseError(r *gin.Engine, t *testing.T, url string, body interface{}, statusCode int, errorMessage string) {
 	tvShowJson, err := json.Marshal(body)
 	assert.Nil(t, err)
@@ -162,3 +255,15 @@ func CheckListAllEpisodes(t *testing.T, debug bool, totalExpected int) {
 	assert.Nil(t, err)
 	assert.Equal(t, totalExpected, total)
 }
+
+// CountingRepository wraps a repository and counts how many times handlers
+// reach for the episodes table, to catch N+1 queries.
+type CountingRepository struct {
+	repository.Repository
+	EpisodeQueries int
+}
+
+func (r *CountingRepository) Episodes() repository.EpisodeRepository {
+	r.EpisodeQueries++
+	return r.Repository.Episodes()
+}
@@ -0,0 +1,99 @@
+package testutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/feealc/tvshows-backend-go/tmdb"
+)
+
+const TMDB_STUB_API_KEY = "test-api-key"
+
+// TmdbStub is a local server answering the TMDB routes used by tmdb.Client.
+type TmdbStub struct {
+	Server  *httptest.Server
+	mu      sync.Mutex
+	tvShows map[int]tmdb.TvShow
+	seasons map[int]map[int]tmdb.Season
+}
+
+func NewTmdbStub() *TmdbStub {
+	stub := &TmdbStub{
+		tvShows: make(map[int]tmdb.TvShow),
+		seasons: make(map[int]map[int]tmdb.Season),
+	}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	return stub
+}
+
+func (s *TmdbStub) Close() {
+	s.Server.Close()
+}
+
+func (s *TmdbStub) Client() *tmdb.Client {
+	return tmdb.NewClient(s.Server.URL, TMDB_STUB_API_KEY)
+}
+
+// SetTvShow stores (or replaces) a show and its seasons. The season list of
+// the show details is built from the seasons given.
+func (s *TmdbStub) SetTvShow(tvShow tmdb.TvShow, seasons ...tmdb.Season) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tvShow.Seasons = nil
+	s.seasons[tvShow.Id] = make(map[int]tmdb.Season)
+	for _, season := range seasons {
+		tvShow.Seasons = append(tvShow.Seasons, tmdb.SeasonSummary{
+			SeasonNumber: season.SeasonNumber,
+			Name:         season.Name,
+			Overview:     season.Overview,
+			AirDate:      season.AirDate,
+			EpisodeCount: len(season.Episodes),
+			PosterPath:   season.PosterPath,
+		})
+		s.seasons[tvShow.Id][season.SeasonNumber] = season
+	}
+	s.tvShows[tvShow.Id] = tvShow
+}
+
+func (s *TmdbStub) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("api_key") != TMDB_STUB_API_KEY {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// /tv/{id} or /tv/{id}/season/{number}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "tv" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tmdbId, _ := strconv.Atoi(parts[1])
+	tvShow, ok := s.tvShows[tmdbId]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var body interface{} = tvShow
+	if len(parts) == 4 && parts[2] == "season" {
+		number, _ := strconv.Atoi(parts[3])
+		season, ok := s.seasons[tmdbId][number]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body = season
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+	"github.com/feealc/tvshows-backend-go/tvsync"
+	"github.com/stretchr/testify/assert"
+)
+
+var TMDBID_BREAKINGBAD int = 1396
+
+func newTmdbStubBreakingBad() *testutils.TmdbStub {
+	stub := testutils.NewTmdbStub()
+	stub.SetTvShow(
+		tmdb.TvShow{Id: TMDBID_BREAKINGBAD, Name: "Breaking Bad", Overview: "Walter White", Status: "Ended"},
+		tmdb.Season{SeasonNumber: 0, Name: "Specials", Episodes: []tmdb.Episode{
+			{SeasonNumber: 0, EpisodeNumber: 1, Name: "Good Cop Bad Cop", AirDate: "2009-02-17"},
+		}},
+		tmdb.Season{SeasonNumber: 1, Name: "Season 1", AirDate: "2008-01-20", Episodes: []tmdb.Episode{
+			{SeasonNumber: 1, EpisodeNumber: 1, Name: "Pilot", Overview: "Diagnosed", AirDate: "2008-01-20"},
+			{SeasonNumber: 1, EpisodeNumber: 2, Name: "Cat's in the Bag...", AirDate: "2008-01-27"},
+		}},
+		tmdb.Season{SeasonNumber: 2, Name: "Season 2", AirDate: "2009-03-08", Episodes: []tmdb.Episode{
+			{SeasonNumber: 2, EpisodeNumber: 1, Name: "Seven Thirty-Seven", AirDate: "2009-03-08"},
+		}},
+	)
+	return stub
+}
+
+func importTvShow(t *testing.T, stub *testutils.TmdbStub, tmdbId int) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.Use(controllers.UseTmdbClient(stub.Client()))
+	r.Use(controllers.UseDateFormat)
+	url := "/tvshows/import/:tmdbid"
+	r.POST(url, controllers.TvShowImport)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, strings.Replace(url, ":tmdbid", strconv.Itoa(tmdbId), 1)+"?group=2", nil)
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTvShowImport(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	stub := newTmdbStubBreakingBad()
+	defer stub.Close()
+
+	w := importTvShow(t, stub, TMDBID_BREAKINGBAD)
+	// println(w.Body.String())
+
+	type Response struct {
+		TvShow   models.TvShow    `json:"tv_show"`
+		Episodes []models.Episode `json:"episodes"`
+	}
+	var resp Response
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"air_date":"2008-01-20"`)
+	testutils.CheckTvShow(t, resp.TvShow, models.TvShow{
+		Id:        1,
+		TmdbId:    TMDBID_BREAKINGBAD,
+		Name:      "Breaking Bad",
+		Overview:  "Walter White",
+		GroupType: 2,
+		Status:    models.TvShowStatusEnded,
+	})
+
+	// specials come after the other seasons
+	assert.Equal(t, 4, len(resp.Episodes))
+	testutils.CheckEpisode(t, resp.Episodes[0], models.Episode{Id: 1, TmdbId: TMDBID_BREAKINGBAD, Season: 1, Episode: 1, Name: "Pilot", Overview: "Diagnosed", AirDate: 20080120})
+	testutils.CheckEpisode(t, resp.Episodes[1], models.Episode{Id: 2, TmdbId: TMDBID_BREAKINGBAD, Season: 1, Episode: 2, Name: "Cat's in the Bag...", AirDate: 20080127})
+	testutils.CheckEpisode(t, resp.Episodes[2], models.Episode{Id: 3, TmdbId: TMDBID_BREAKINGBAD, Season: 2, Episode: 1, Name: "Seven Thirty-Seven", AirDate: 20090308})
+	testutils.CheckEpisode(t, resp.Episodes[3], models.Episode{Id: 4, TmdbId: TMDBID_BREAKINGBAD, Season: 0, Episode: 1, Name: "Good Cop Bad Cop", AirDate: 20090217})
+
+	testutils.CheckListAllTvShows(t, DEBUG, 1)
+	testutils.CheckListAllEpisodes(t, DEBUG, 4)
+}
+
+func TestTvShowImportErrors(t *testing.T) {
+	stub := newTmdbStubBreakingBad()
+	defer stub.Close()
+
+	checkError := func(tmdbId int, statusCode int, message string) {
+		w := importTvShow(t, stub, tmdbId)
+		assert.Equal(t, statusCode, w.Code)
+		assert.Equal(t, fmt.Sprintf(`{"error":"%s"}`, message), w.Body.String())
+	}
+
+	// already imported
+	checkError(TMDBID_BREAKINGBAD, http.StatusBadRequest, fmt.Sprintf("TvShow Breaking Bad (TMDB ID %d) already exist", TMDBID_BREAKINGBAD))
+
+	// unknown on TMDB
+	checkError(999999, http.StatusNotFound, "TvShow not found on TMDB")
+
+	// another show with the same name
+	stub.SetTvShow(tmdb.TvShow{Id: 1397, Name: "Breaking Bad", Status: "Ended"})
+	checkError(1397, http.StatusBadRequest, "TvShow name Breaking Bad already exist")
+
+	// TMDB refusing the request
+	stub.SetTvShow(tmdb.TvShow{Id: 1399, Name: "Game of Thrones"})
+	stub.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	checkError(1399, http.StatusBadGateway, "TMDB request /tv/1399 failed with status 503")
+
+	testutils.CheckListAllTvShows(t, DEBUG, 1)
+	testutils.CheckListAllEpisodes(t, DEBUG, 4)
+}
+
+func TestTvShowSync(t *testing.T) {
+	stub := newTmdbStubBreakingBad()
+	defer stub.Close()
+
+	tmdbIdSuccession := 76331
+	stub.SetTvShow(
+		tmdb.TvShow{Id: tmdbIdSuccession, Name: "Succession", Status: "Returning Series"},
+		tmdb.Season{SeasonNumber: 1, Episodes: []tmdb.Episode{
+			{SeasonNumber: 1, EpisodeNumber: 1, Name: "Celebration", AirDate: "2018-06-03"},
+		}},
+	)
+	w := importTvShow(t, stub, tmdbIdSuccession)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// watch the imported episode
+	repo := testutils.GetTestUserRepository()
+	tvShow, err := repo.TvShows().FindByTmdbId(tmdbIdSuccession)
+	assert.Nil(t, err)
+	episode, err := repo.Episodes().FindByKey(tmdbIdSuccession, 1, 1)
+	assert.Nil(t, err)
+	episode.Watched = true
+	episode.WatchedDate = 20240101
+	assert.Nil(t, repo.Episodes().Save(&episode))
+
+	// TMDB renames the episode, announces a new one and ends the show
+	stub.SetTvShow(
+		tmdb.TvShow{Id: tmdbIdSuccession, Name: "Succession", Status: "Ended"},
+		tmdb.Season{SeasonNumber: 1, Episodes: []tmdb.Episode{
+			{SeasonNumber: 1, EpisodeNumber: 1, Name: "Celebration (Pilot)", AirDate: "2018-06-03"},
+			{SeasonNumber: 1, EpisodeNumber: 2, Name: "The New Deal", AirDate: "2018-06-10"},
+		}},
+	)
+
+	r := testutils.SetUpTestRoutes(true)
+	r.Use(controllers.UseTmdbClient(stub.Client()))
+	r.Use(controllers.UseDateFormat)
+	url := "/tvshows/:id/sync"
+	r.POST(url, controllers.TvShowSync)
+	w = httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, strings.Replace(url, ":id", strconv.Itoa(tvShow.Id), 1)+"?date_format=int", nil)
+	assert.Nil(t, err)
+	r.ServeHTTP(w, req)
+	// println(w.Body.String())
+
+	var report tvsync.Report
+	err = json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"air_date":20180610`)
+	assert.Equal(t, tvShow.Id, report.TvShowId)
+	assert.True(t, report.StatusChanged)
+	assert.Equal(t, models.TvShowStatusEnded, report.Status)
+	assert.Equal(t, 1, len(report.Added))
+	testutils.CheckEpisode(t, report.Added[0], models.Episode{Id: 6, TmdbId: tmdbIdSuccession, Season: 1, Episode: 2, Name: "The New Deal", AirDate: 20180610})
+	assert.Equal(t, 1, len(report.Updated))
+	assert.Equal(t, []string{"name"}, report.Updated[0].Fields)
+	testutils.CheckEpisode(t, report.Updated[0].Episode, models.Episode{Id: episode.Id, TmdbId: tmdbIdSuccession, Season: 1, Episode: 1, Name: "Celebration (Pilot)", AirDate: 20180603, Watched: true, WatchedDate: 20240101})
+
+	// ended shows are left out of the scheduled sync
+	reports, err := tvsync.SyncAll(repo, stub.Client())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(reports))
+}
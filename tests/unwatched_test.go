@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedUnwatched creates total shows with 3 episodes each. Every third show is
+// fully watched, the others only have their first episode watched.
+func seedUnwatched(repo repository.Repository, total int) error {
+	var tvShows []models.TvShow
+	var episodes []models.Episode
+
+	for i := 1; i <= total; i++ {
+		tvShows = append(tvShows, models.TvShow{TmdbId: i, Name: fmt.Sprintf("Show %04d", i), GroupType: 1, Status: 1})
+		for ep := 1; ep <= 3; ep++ {
+			watched := ep == 1 || i%3 == 0
+			episodes = append(episodes, models.Episode{TmdbId: i, Season: 1, Episode: ep, Name: fmt.Sprintf("Episode %d", ep), Watched: watched})
+		}
+	}
+
+	if err := repo.TvShows().CreateMany(tvShows); err != nil {
+		return err
+	}
+	return repo.Episodes().CreateMany(episodes)
+}
+
+func countingRequest(repo *testutils.CountingRepository, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(false)
+	r.Use(controllers.UseRepository(repo))
+	url := "/tvshows"
+	r.GET(url, handler)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTvShowListAllUnwatched(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	repo := &testutils.CountingRepository{Repository: testutils.GetTestUserRepository()}
+	assert.Nil(t, seedUnwatched(repo.Repository, 30))
+
+	w := countingRequest(repo, controllers.TvShowListAll)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, repo.EpisodeQueries)
+
+	var tvShows []models.TvShow
+	err := json.Unmarshal(w.Body.Bytes(), &tvShows)
+	assert.Nil(t, err)
+	assert.Equal(t, 30, len(tvShows))
+	// Show 0001 has 2 unwatched episodes, Show 0003 is fully watched
+	testutils.CheckTvShow(t, tvShows[0], models.TvShow{Id: 1, TmdbId: 1, Name: "Show 0001", GroupType: 1, Status: 1, UnwatchedSeason: 1, UnwatchedEpisode: 2, UnwatchedCount: 1})
+	testutils.CheckTvShow(t, tvShows[2], models.TvShow{Id: 3, TmdbId: 3, Name: "Show 0003", GroupType: 1, Status: 1})
+}
+
+func TestTvShowListAllUnwatchedEpisodes(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	repo := &testutils.CountingRepository{Repository: testutils.GetTestUserRepository()}
+	assert.Nil(t, seedUnwatched(repo.Repository, 30))
+
+	w := countingRequest(repo, controllers.TvShowListAllUnwatchedEpisodes)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, repo.EpisodeQueries)
+
+	type TvShowEpisodes struct {
+		TvShow   models.TvShow    `json:"tv_show"`
+		Episodes []models.Episode `json:"episodes"`
+	}
+	var response []TvShowEpisodes
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, 30, len(response))
+	assert.Equal(t, "Show 0001", response[0].TvShow.Name)
+	assert.Equal(t, 2, len(response[0].Episodes))
+	assert.Equal(t, 2, response[0].Episodes[0].Episode)
+	assert.Equal(t, 3, response[0].Episodes[1].Episode)
+	assert.Equal(t, "Show 0003", response[2].TvShow.Name)
+	assert.NotNil(t, response[2].Episodes)
+	assert.Equal(t, 0, len(response[2].Episodes))
+}
+
+func benchmarkTvShowList(b *testing.B, handler gin.HandlerFunc) {
+	repo := &testutils.CountingRepository{Repository: repository.NewMemoryRepository().ForUser(1)}
+	if err := seedUnwatched(repo.Repository, 300); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if w := countingRequest(repo, handler); w.Code != http.StatusOK {
+			b.Fatalf("status %d", w.Code)
+		}
+	}
+
+	if queries := repo.EpisodeQueries / b.N; queries != 1 {
+		b.Fatalf("%d episode queries per request, want 1", queries)
+	}
+}
+
+func BenchmarkTvShowListAll(b *testing.B) {
+	benchmarkTvShowList(b, controllers.TvShowListAll)
+}
+
+func BenchmarkTvShowListAllUnwatchedEpisodes(b *testing.B) {
+	benchmarkTvShowList(b, controllers.TvShowListAllUnwatchedEpisodes)
+}
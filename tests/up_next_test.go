@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func upNextKeys(rows []controllers.UpNext) []string {
+	keys := []string{}
+	for _, row := range rows {
+		keys = append(keys, row.TvShow.Name+" "+row.Episode.Name)
+	}
+	return keys
+}
+
+func TestUpNextList(t *testing.T) {
+	setUpListData(t)
+	repo := testutils.GetTestUserRepository()
+
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 1, Episode: 3, Name: "Hedge Fund Homeboys", AirDate: 20090323},
+		{TmdbId: 1, Season: 1, Episode: 4, Name: "Hell Hath No Fury", AirDate: 20991231},
+		{TmdbId: 3, Season: 1, Episode: 2, Name: "The Tagger"},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+
+	var rows []controllers.UpNext
+	w := listRequest(t, controllers.UpNextList, "", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Castle Hedge Fund Homeboys", "Abbott Elementary Pilot", "The Rookie Pilot"}, upNextKeys(rows))
+	assert.Equal(t, []models.Date{20240102, 0, 0}, []models.Date{rows[0].LastWatchedDate, rows[1].LastWatchedDate, rows[2].LastWatchedDate})
+	assert.Equal(t, 1, rows[0].TvShow.TmdbId)
+	assert.False(t, rows[0].Episode.Watched)
+
+	// watching the rookie moves it to the top, on its next episode
+	pilot, err := repo.Episodes().FindByKey(2, 1, 1)
+	assert.Nil(t, err)
+	pilot.Watched, pilot.WatchedDate = true, 20250101
+	assert.Nil(t, repo.Episodes().SaveWatchState(&pilot))
+
+	w = listRequest(t, controllers.UpNextList, "", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"The Rookie Crime of the Century", "Castle Hedge Fund Homeboys", "Abbott Elementary Pilot"}, upNextKeys(rows))
+
+	w = listRequest(t, controllers.UpNextList, "?group=1", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"The Rookie Crime of the Century", "Castle Hedge Fund Homeboys"}, upNextKeys(rows))
+
+	w = listRequest(t, controllers.UpNextList, "?group=2", &rows)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{}, upNextKeys(rows))
+	assert.Equal(t, "[]", w.Body.String())
+
+	w = listRequest(t, controllers.UpNextList, "?group=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":"group invalid"}`, w.Body.String())
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func putUpsert(t *testing.T, url string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.PUT("/tvshows/tmdb/:tmdbid", controllers.TvShowUpsert)
+	r.PUT("/episodes/tmdb/:tmdbid/:season/:episode", controllers.EpisodeUpsert)
+	return testutils.Request(t, r, http.MethodPut, url, nil, body, response)
+}
+
+func TestTvShowUpsert(t *testing.T) {
+	setUpListData(t)
+
+	var created, updated models.TvShow
+	body := `{"name": "Bones", "overview": "Forensic", "group": 1, "status": 2}`
+	w := putUpsert(t, "/tvshows/tmdb/10", body, &created)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 10, created.TmdbId)
+	assert.Equal(t, 5, created.Id)
+
+	// running it again changes nothing but the update time
+	w = putUpsert(t, "/tvshows/tmdb/10", body, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckTvShow(t, updated, created)
+	assert.True(t, updated.CreatedAt.Equal(created.CreatedAt))
+
+	w = putUpsert(t, "/tvshows/tmdb/1", `{"tmdb_id": 1, "name": "Castle", "overview": "Crime novelist", "group": 2, "status": 2}`, &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, updated.Id)
+	assert.Equal(t, "Crime novelist", updated.Overview)
+	assert.Equal(t, models.GroupTypePlanned, updated.GroupType)
+
+	tvShow, err := testutils.GetTestRepository().TvShows().FindByTmdbId(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Crime novelist", tvShow.Overview)
+	testutils.CheckListAllTvShows(t, false, 5)
+}
+
+func TestEpisodeUpsert(t *testing.T) {
+	setUpListData(t)
+
+	var episode models.Episode
+	w := putUpsert(t, "/episodes/tmdb/1/1/3", `{"name": "Hedge Fund Homeboys", "air_date": 20090323}`, &episode)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	testutils.CheckEpisode(t, episode, models.Episode{Id: 7, TmdbId: 1, Season: 1, Episode: 3, Name: "Hedge Fund Homeboys", AirDate: 20090323})
+
+	// episode 1x01 is watched, an upsert without the watched fields keeps them
+	w = putUpsert(t, "/episodes/tmdb/1/1/1", `{"name": "Flowers for Your Grave", "overview": "Pilot", "air_date": 20090309}`, &episode)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckEpisode(t, episode, models.Episode{Id: 1, TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", Overview: "Pilot", AirDate: 20090309, Watched: true, WatchedDate: 20240101})
+
+	w = putUpsert(t, "/episodes/tmdb/1/1/1", `{"name": "Flowers for Your Grave", "air_date": 20090309, "watched_date": 20240201}`, &episode)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, episode.Watched)
+	assert.Equal(t, models.Date(20240201), episode.WatchedDate)
+
+	w = putUpsert(t, "/episodes/tmdb/1/1/1", `{"name": "Flowers for Your Grave", "air_date": 20090309, "watched": false, "watched_date": 0}`, &episode)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored, err := testutils.GetTestUserRepository().Episodes().FindByKey(1, 1, 1)
+	assert.Nil(t, err)
+	testutils.CheckEpisode(t, stored, models.Episode{Id: 1, TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", AirDate: 20090309})
+	testutils.CheckListAllEpisodes(t, false, 7)
+}
+
+func TestUpsertErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(url string, body string, statusCode int, message string) {
+		w := putUpsert(t, url, body, nil)
+		assert.Equal(t, statusCode, w.Code, url)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("/tvshows/tmdb/abc", `{}`, http.StatusBadRequest, "tmdbId invalid")
+	checkError("/tvshows/tmdb/10", `{"tmdb_id": 11, "name": "Bones", "group": 1, "status": 2}`, http.StatusBadRequest, "tmdb_id does not match the url")
+	checkError("/tvshows/tmdb/10", `{"name": "B", "group": 1, "status": 2}`, http.StatusUnprocessableEntity, "Name: less than min")
+	checkError("/tvshows/tmdb/10", `{"name": "Castle", "group": 1, "status": 2}`, http.StatusBadRequest, "TvShow name Castle already exist")
+	checkError("/episodes/tmdb/1/1/x", `{}`, http.StatusBadRequest, "episode invalid")
+	checkError("/episodes/tmdb/1/1/3", `{"season": 2, "name": "Pilot"}`, http.StatusBadRequest, "season does not match the url")
+	checkError("/episodes/tmdb/99/1/1", `{"name": "Pilot"}`, http.StatusNotFound, "TvShow not found")
+}
@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/auth"
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSigner = auth.NewSigner([]byte("test-secret"), time.Hour)
+
+// setUpUserRoutes wires the user routes and a few watch state routes behind
+// the real authentication, instead of the test user of SetUpTestRoutes.
+func setUpUserRoutes() *gin.Engine {
+	r := testutils.SetUpTestRoutes(false)
+	r.Use(controllers.UseRepository(testutils.GetTestRepository()))
+	r.Use(controllers.UseSigner(testSigner))
+	r.POST("/users/register", controllers.AuthenticateRegistration, controllers.UserRegister)
+	r.POST("/users/login", controllers.UserLogin)
+
+	private := r.Group("", controllers.Authenticate)
+	private.GET("/users/me", controllers.UserMe)
+	private.GET("/apikeys", controllers.ApiKeyListAll)
+	private.POST("/apikeys", controllers.ApiKeyCreate)
+	private.DELETE("/apikeys/:id", controllers.ApiKeyDelete)
+	private.GET("/episodes", controllers.EpisodeListAll)
+	private.PUT("/episodes/watched/:id", controllers.EpisodeEditMarkWatched)
+	private.GET("/episodes/summary/:id", controllers.EpisodeSummaryBySeason)
+	return r
+}
+
+func userRequest(t *testing.T, r *gin.Engine, method string, url string, token string, body interface{}) *httptest.ResponseRecorder {
+	req := newUserRequest(t, method, url, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	// println(w.Body.String())
+	return w
+}
+
+func newUserRequest(t *testing.T, method string, url string, body interface{}) *http.Request {
+	reader := strings.NewReader("")
+	if body != nil {
+		bodyJson, err := json.Marshal(body)
+		assert.Nil(t, err)
+		reader = strings.NewReader(string(bodyJson))
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	assert.Nil(t, err)
+	return req
+}
+
+func unmarshal(t *testing.T, w *httptest.ResponseRecorder, response interface{}) {
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), response))
+}
+
+// registerAndLogin creates the user through the routes under prefix and
+// returns a login token. adminToken registers the users after the first one.
+func registerAndLogin(t *testing.T, r *gin.Engine, prefix string, adminToken string, username string) string {
+	credentials := controllers.Credentials{Username: username, Password: username + "-password"}
+	w := userRequest(t, r, http.MethodPost, prefix+"/users/register", adminToken, credentials)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = userRequest(t, r, http.MethodPost, prefix+"/users/login", "", credentials)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Token     string      `json:"token"`
+		TokenType string      `json:"token_type"`
+		User      models.User `json:"user"`
+	}
+	unmarshal(t, w, &response)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, username, response.User.Username)
+	return response.Token
+}
+
+func TestUserRegisterAndLogin(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpUserRoutes()
+
+	w := userRequest(t, r, http.MethodPost, "/users/register", "", controllers.Credentials{Username: " Alice ", Password: "alice-password"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	var user models.User
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(t, "alice", user.Username)
+	// the first account administers the others
+	assert.Equal(t, models.UserRoleAdmin, user.Role)
+
+	stored, err := testutils.GetTestRepository().Users().FindByUsername("alice")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "alice-password", stored.PasswordHash)
+	assert.True(t, auth.CheckPassword(stored.PasswordHash, "alice-password"))
+
+	w = userRequest(t, r, http.MethodPost, "/users/login", "", controllers.Credentials{Username: "alice", Password: "alice-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &login))
+	userId, err := testSigner.Verify(login.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, userId)
+
+	w = userRequest(t, r, http.MethodGet, "/users/me", login.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(t, "alice", user.Username)
+}
+
+func TestUserRegisterFirstAdminOnce(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpUserRoutes()
+
+	// simultaneous first registrations make a single admin, the others find
+	// the registration closed
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for index, username := range []string{"alice", "bob", "carol", "dave"} {
+		wg.Add(1)
+		go func(index int, username string) {
+			defer wg.Done()
+			codes[index] = userRequest(t, r, http.MethodPost, "/users/register", "", controllers.Credentials{Username: username, Password: username + "-password"}).Code
+		}(index, username)
+	}
+	wg.Wait()
+
+	sort.Ints(codes)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}, codes)
+	count, err := testutils.GetTestRepository().Users().Count()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestUserErrors(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpUserRoutes()
+
+	checkError := func(method string, url string, token string, body interface{}, statusCode int, message string) {
+		w := userRequest(t, r, method, url, token, body)
+		assert.Equal(t, statusCode, w.Code)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError(http.MethodPost, "/users/register", "", controllers.Credentials{Username: "bob", Password: "short"}, http.StatusUnprocessableEntity, "password invalid, must have at least 8 characters")
+	checkError(http.MethodPost, "/users/register", "", controllers.Credentials{Username: "bo", Password: "bob-password"}, http.StatusUnprocessableEntity, "Username: less than min")
+
+	credentials := controllers.Credentials{Username: "bob", Password: "bob-password"}
+	assert.Equal(t, http.StatusCreated, userRequest(t, r, http.MethodPost, "/users/register", "", credentials).Code)
+	checkError(http.MethodPost, "/users/register", "", credentials, http.StatusBadRequest, "User bob already exist")
+
+	// after the first user only an admin registers the others
+	carol := controllers.Credentials{Username: "carol", Password: "carol-password"}
+	checkError(http.MethodPost, "/users/register", "", carol, http.StatusForbidden, "registration closed, an admin creates the accounts")
+	checkError(http.MethodPost, "/users/register", "not-a-token", carol, http.StatusUnauthorized, "token invalid")
+
+	checkError(http.MethodPost, "/users/login", "", controllers.Credentials{Username: "bob", Password: "wrong-password"}, http.StatusUnauthorized, "username or password invalid")
+	checkError(http.MethodPost, "/users/login", "", controllers.Credentials{Username: "nobody", Password: "bob-password"}, http.StatusUnauthorized, "username or password invalid")
+
+	checkError(http.MethodGet, "/users/me", "", nil, http.StatusUnauthorized, "authentication required")
+	checkError(http.MethodGet, "/users/me", "not-a-token", nil, http.StatusUnauthorized, "token invalid")
+
+	expired := &auth.Signer{Secret: testSigner.Secret, TTL: -time.Hour}
+	token, _, err := expired.Sign(1)
+	assert.Nil(t, err)
+	checkError(http.MethodGet, "/users/me", token, nil, http.StatusUnauthorized, "token expired")
+
+	forged, _, err := auth.NewSigner([]byte("other-secret"), time.Hour).Sign(1)
+	assert.Nil(t, err)
+	checkError(http.MethodGet, "/users/me", forged, nil, http.StatusUnauthorized, "token invalid")
+}
+
+func TestUserWatchStateIsolation(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpUserRoutes()
+	aliceToken := registerAndLogin(t, r, "", "", "alice")
+	bobToken := registerAndLogin(t, r, "", aliceToken, "bob")
+
+	repo := testutils.GetTestRepository()
+	tvShow := models.TvShow{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2}
+	assert.Nil(t, repo.TvShows().Create(&tvShow))
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave"},
+		{TmdbId: 1, Season: 1, Episode: 2, Name: "Nanny McDead"},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+
+	w := userRequest(t, r, http.MethodPut, "/episodes/watched/"+strconv.Itoa(episodes[0].Id), aliceToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	watchedIds := func(token string) []int {
+		var watched []models.Episode
+		w := userRequest(t, r, http.MethodGet, "/episodes?watched=true", token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &watched))
+		return episodeIds(watched)
+	}
+	assert.Equal(t, []int{episodes[0].Id}, watchedIds(aliceToken))
+	assert.Equal(t, []int{}, watchedIds(bobToken))
+
+	summary := func(token string) string {
+		w := userRequest(t, r, http.MethodGet, "/episodes/summary/"+strconv.Itoa(tvShow.Id), token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	assert.Equal(t, `[{"season":1,"total_episodes":2,"total_episodes_watched":1}]`, summary(aliceToken))
+	assert.Equal(t, `[{"season":1,"total_episodes":2,"total_episodes_watched":0}]`, summary(bobToken))
+
+	// deleting the episode removes the watch state along with it
+	assert.Nil(t, repo.Episodes().Delete(episodes[0].Id))
+	assert.Equal(t, []int{}, watchedIds(aliceToken))
+}
+
+func TestApiKeys(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	r := setUpUserRoutes()
+	aliceToken := registerAndLogin(t, r, "", "", "alice")
+	bobToken := registerAndLogin(t, r, "", aliceToken, "bob")
+
+	w := userRequest(t, r, http.MethodPost, "/apikeys", aliceToken, models.ApiKey{Name: " backup script "})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "key_hash")
+
+	var created struct {
+		ApiKey models.ApiKey `json:"api_key"`
+		Key    string        `json:"key"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "backup script", created.ApiKey.Name)
+	assert.True(t, strings.HasPrefix(created.Key, created.ApiKey.Prefix))
+	assert.True(t, auth.IsApiKey(created.Key))
+
+	stored, err := testutils.GetTestRepository().ApiKeys().FindByHash(auth.HashApiKey(created.Key))
+	assert.Nil(t, err)
+	assert.Equal(t, created.ApiKey.Id, stored.Id)
+	assert.NotContains(t, stored.KeyHash, created.Key)
+
+	// the key works in both headers
+	me := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	req := newUserRequest(t, http.MethodGet, "/users/me", nil)
+	req.Header.Set("X-API-Key", created.Key)
+	w = me(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+
+	w = userRequest(t, r, http.MethodGet, "/users/me", created.Key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+
+	var apiKeys []models.ApiKey
+	w = userRequest(t, r, http.MethodGet, "/apikeys", aliceToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &apiKeys))
+	assert.Equal(t, 1, len(apiKeys))
+	assert.Equal(t, created.ApiKey.Prefix, apiKeys[0].Prefix)
+
+	w = userRequest(t, r, http.MethodGet, "/apikeys", bobToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+
+	// only the owner can revoke a key
+	deleteUrl := "/apikeys/" + strconv.Itoa(created.ApiKey.Id)
+	w = userRequest(t, r, http.MethodDelete, deleteUrl, bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"error":"ApiKey not found"}`, w.Body.String())
+
+	w = userRequest(t, r, http.MethodDelete, deleteUrl, created.Key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = userRequest(t, r, http.MethodGet, "/users/me", created.Key, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `{"error":"api key invalid"}`, w.Body.String())
+
+	w = userRequest(t, r, http.MethodPost, "/apikeys", aliceToken, models.ApiKey{Name: " "})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
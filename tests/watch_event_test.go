@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func watchEventRequest(t *testing.T, method string, url string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.GET("/episodes/id/:id/events", controllers.WatchEventListByEpisode)
+	r.POST("/episodes/id/:id/events", controllers.WatchEventCreateForEpisode)
+	r.DELETE("/episodes/id/:id/events/:eventid", controllers.WatchEventDeleteForEpisode)
+	r.GET("/tvshows/:id/events", controllers.WatchEventListByTvShow)
+	r.POST("/tvshows/:id/events", controllers.WatchEventCreateForTvShow)
+	r.DELETE("/tvshows/:id/events/:eventid", controllers.WatchEventDeleteForTvShow)
+	r.PUT("/episodes/watched/:id", controllers.EpisodeEditMarkWatched)
+	return testutils.Request(t, r, method, url, nil, body, response)
+}
+
+func watchState(t *testing.T, id int) (bool, models.Date) {
+	episode, err := testutils.GetTestUserRepository().Episodes().FindById(id)
+	assert.Nil(t, err)
+	return episode.Watched, episode.WatchedDate
+}
+
+func watchEventIds(events []models.WatchEvent) []int {
+	ids := []int{}
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	return ids
+}
+
+func TestWatchEventRewatch(t *testing.T) {
+	setUpListData(t)
+	today := models.Today(time.UTC)
+
+	// the watched episodes saved by the setup have their first event
+	var events []models.WatchEvent
+	w := watchEventRequest(t, http.MethodGet, "/episodes/id/1/events", "", &events)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, models.Date(20240101), models.DateOf(events[0].WatchedAt.UTC()))
+
+	var first, second models.WatchEvent
+	w = watchEventRequest(t, http.MethodPost, "/episodes/id/3/events", "", &first)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 3, first.EpisodeId)
+	assert.Equal(t, 2, first.TmdbId)
+	assert.Equal(t, today, models.DateOf(first.WatchedAt.UTC()))
+	watched, watchedDate := watchState(t, 3)
+	assert.True(t, watched)
+	assert.Equal(t, today, watchedDate)
+
+	// an older watch goes to the history without moving the watched date
+	w = watchEventRequest(t, http.MethodPost, "/episodes/id/3/events", `{"watched_at": "2024-02-01T12:00:00Z", "note": "first time"}`, &second)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "first time", second.Note)
+	_, watchedDate = watchState(t, 3)
+	assert.Equal(t, today, watchedDate)
+
+	w = watchEventRequest(t, http.MethodGet, "/episodes/id/3/events", "", &events)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{first.Id, second.Id}, watchEventIds(events))
+
+	// unmarking keeps the history and the date of the last watch
+	w = watchEventRequest(t, http.MethodPut, "/episodes/watched/3", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	watched, watchedDate = watchState(t, 3)
+	assert.False(t, watched)
+	assert.Equal(t, today, watchedDate)
+	w = watchEventRequest(t, http.MethodGet, "/episodes/id/3/events", "", &events)
+	assert.Equal(t, 2, len(events))
+
+	// deleting the latest event moves the date back, but never marks watched
+	w = watchEventRequest(t, http.MethodDelete, "/episodes/id/3/events/"+strconv.Itoa(first.Id), "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"message":"WatchEvent deleted"}`, w.Body.String())
+	watched, watchedDate = watchState(t, 3)
+	assert.False(t, watched)
+	assert.Equal(t, models.Date(20240201), watchedDate)
+
+	// marking it again records the rewatch
+	w = watchEventRequest(t, http.MethodPut, "/episodes/watched/3", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = watchEventRequest(t, http.MethodGet, "/episodes/id/3/events", "", &events)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, today, models.DateOf(events[0].WatchedAt.UTC()))
+
+	for _, event := range events {
+		w = watchEventRequest(t, http.MethodDelete, "/episodes/id/3/events/"+strconv.Itoa(event.Id), "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	watched, watchedDate = watchState(t, 3)
+	assert.False(t, watched)
+	assert.Equal(t, models.Date(0), watchedDate)
+}
+
+func TestWatchEventByTvShow(t *testing.T) {
+	setUpListData(t)
+
+	var event models.WatchEvent
+	w := watchEventRequest(t, http.MethodPost, "/tvshows/2/events", `{"season": 1, "episode": 2, "watched_at": "2024-03-01T12:00:00Z"}`, &event)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 4, event.EpisodeId)
+	watched, watchedDate := watchState(t, 4)
+	assert.True(t, watched)
+	assert.Equal(t, models.Date(20240301), watchedDate)
+
+	w = watchEventRequest(t, http.MethodPost, "/tvshows/2/events", `{"season": 1, "episode": 1, "watched_at": "2024-02-01T12:00:00Z"}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var events []models.WatchEvent
+	w = watchEventRequest(t, http.MethodGet, "/tvshows/2/events", "", &events)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, []int{2, 1}, []int{events[0].Episode, events[1].Episode})
+
+	// the event belongs to another show
+	w = watchEventRequest(t, http.MethodDelete, "/tvshows/1/events/"+strconv.Itoa(event.Id), "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"error":"WatchEvent not found"}`, w.Body.String())
+	w = watchEventRequest(t, http.MethodDelete, "/tvshows/2/events/"+strconv.Itoa(event.Id), "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// deleting an episode takes its history along
+	assert.Nil(t, testutils.GetTestRepository().Episodes().Delete(3))
+	w = watchEventRequest(t, http.MethodGet, "/tvshows/2/events", "", &events)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestWatchEventErrors(t *testing.T) {
+	setUpListData(t)
+
+	checkError := func(method string, url string, body string, statusCode int, message string) {
+		w := watchEventRequest(t, method, url, body, nil)
+		assert.Equal(t, statusCode, w.Code, url)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	checkError(http.MethodGet, "/episodes/id/abc/events", "", http.StatusBadRequest, "id invalid")
+	checkError(http.MethodGet, "/episodes/id/99/events", "", http.StatusNotFound, "Episode not found")
+	checkError(http.MethodPost, "/episodes/id/3/events", `{"watched_at": "`+future+`"}`, http.StatusUnprocessableEntity, "WatchedAt: cannot be in the future")
+	checkError(http.MethodPost, "/episodes/id/3/events", `{"note": "`+strings.Repeat("a", 501)+`"}`, http.StatusUnprocessableEntity, "Note: greater than max")
+	checkError(http.MethodPost, "/tvshows/99/events", `{}`, http.StatusNotFound, "TvShow not found")
+	checkError(http.MethodPost, "/tvshows/2/events", `{"season": 9, "episode": 1}`, http.StatusNotFound, "Episode not found")
+	checkError(http.MethodDelete, "/episodes/id/3/events/x", "", http.StatusBadRequest, "event id invalid")
+	checkError(http.MethodDelete, "/episodes/id/3/events/99", "", http.StatusNotFound, "WatchEvent not found")
+
+	recorded, err := testutils.GetTestRepository().WatchStates().BackfillEvents()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), recorded)
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/controllers"
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/tests/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setUpWatchedUntilData(t *testing.T) {
+	testutils.ResetTestRepository(t)
+	repo := testutils.GetTestUserRepository()
+
+	assert.Nil(t, repo.TvShows().Create(&models.TvShow{TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2}))
+	episodes := []models.Episode{
+		{TmdbId: 1, Season: 1, Episode: 1, Name: "Flowers for Your Grave", Watched: true, WatchedDate: 20240101},
+		{TmdbId: 1, Season: 1, Episode: 2, Name: "Nanny McDead"},
+		{TmdbId: 1, Season: 1, Episode: 3, Name: "Hedge Fund Homeboys"},
+		{TmdbId: 1, Season: 2, Episode: 1, Name: "Deep in Death"},
+		{TmdbId: 1, Season: 2, Episode: 2, Name: "The Double Down", Watched: true, WatchedDate: 20240105},
+		{TmdbId: 1, Season: 2, Episode: 3, Name: "Inventing the Girl"},
+	}
+	assert.Nil(t, repo.Episodes().CreateMany(episodes))
+}
+
+func postWatchedUntil(t *testing.T, url string, body string, response interface{}) *httptest.ResponseRecorder {
+	r := testutils.SetUpTestRoutes(true)
+	r.POST("/tvshows/:id/watched-until", controllers.TvShowWatchedUntil)
+	return testutils.Request(t, r, http.MethodPost, url, nil, body, response)
+}
+
+func watchedDates(t *testing.T) []models.Date {
+	episodes, err := testutils.GetTestUserRepository().Episodes().FindByTmdbId(1)
+	assert.Nil(t, err)
+
+	dates := []models.Date{}
+	for _, episode := range episodes {
+		if !episode.Watched {
+			dates = append(dates, 0)
+			continue
+		}
+		dates = append(dates, episode.WatchedDate)
+	}
+	return dates
+}
+
+func TestTvShowWatchedUntil(t *testing.T) {
+	setUpWatchedUntilData(t)
+	today := models.Today(time.UTC)
+
+	var tvShow models.TvShow
+	w := postWatchedUntil(t, "/tvshows/1/watched-until", `{"season": 2, "episode": 1}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckTvShow(t, tvShow, models.TvShow{Id: 1, TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2, UnwatchedSeason: 2, UnwatchedEpisode: 3})
+	assert.Equal(t, []models.Date{20240101, today, today, today, 20240105, 0}, watchedDates(t))
+
+	// going back unmarks the later episodes only when asked
+	w = postWatchedUntil(t, "/tvshows/1/watched-until", `{"season": 1, "episode": 2}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, tvShow.UnwatchedSeason)
+	assert.Equal(t, 3, tvShow.UnwatchedEpisode)
+	assert.Equal(t, []models.Date{20240101, today, today, today, 20240105, 0}, watchedDates(t))
+
+	w = postWatchedUntil(t, "/tvshows/1/watched-until", `{"season": 1, "episode": 2, "unwatch_later": true}`, &tvShow)
+	assert.Equal(t, http.StatusOK, w.Code)
+	testutils.CheckTvShow(t, tvShow, models.TvShow{Id: 1, TmdbId: 1, Name: "Castle", GroupType: 1, Status: 2, UnwatchedSeason: 1, UnwatchedEpisode: 3, UnwatchedCount: 3})
+	assert.Equal(t, []models.Date{20240101, today, 0, 0, 0, 0}, watchedDates(t))
+}
+
+func TestTvShowWatchedUntilErrors(t *testing.T) {
+	setUpWatchedUntilData(t)
+
+	checkError := func(url string, body string, statusCode int, message string) {
+		w := postWatchedUntil(t, url, body, nil)
+		assert.Equal(t, statusCode, w.Code, body)
+		assert.Equal(t, `{"error":"`+message+`"}`, w.Body.String())
+	}
+
+	checkError("/tvshows/abc/watched-until", `{}`, http.StatusBadRequest, "id invalid")
+	checkError("/tvshows/99/watched-until", `{"season": 1, "episode": 1}`, http.StatusNotFound, "TvShow not found")
+	checkError("/tvshows/1/watched-until", `{"season": "1"}`, http.StatusBadRequest, "json: cannot unmarshal string into Go struct field WatchedUntil.season of type int")
+	checkError("/tvshows/1/watched-until", `{"season": 3, "episode": 1}`, http.StatusNotFound, "Episode not found")
+	assert.Equal(t, []models.Date{20240101, 0, 0, 0, 20240105, 0}, watchedDates(t))
+}
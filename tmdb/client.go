@@ -0,0 +1,105 @@
+package tmdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	kDEFAULT_BASE_URL = "https://api.themoviedb.org/3"
+	kDEFAULT_TIMEOUT  = 15 * time.Second
+)
+
+var ErrNotFound = errors.New("not found on TMDB")
+
+// Client talks to the TMDB v3 API. BaseURL can point to any server that
+// answers the same routes, like an httptest stub.
+type Client struct {
+	BaseURL    string
+	ApiKey     string
+	HTTPClient *http.Client
+}
+
+func NewClient(baseURL, apiKey string) *Client {
+	if baseURL == "" {
+		baseURL = kDEFAULT_BASE_URL
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		ApiKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: kDEFAULT_TIMEOUT},
+	}
+}
+
+// NewClientFromEnv builds a client from TMDB_BASE_URL and TMDB_API_KEY.
+func NewClientFromEnv() *Client {
+	return NewClient(os.Getenv("TMDB_BASE_URL"), os.Getenv("TMDB_API_KEY"))
+}
+
+func (c *Client) GetTvShow(tmdbId int) (TvShow, error) {
+	var tvShow TvShow
+	err := c.get(fmt.Sprintf("/tv/%d", tmdbId), &tvShow)
+	return tvShow, err
+}
+
+func (c *Client) GetSeason(tmdbId, season int) (Season, error) {
+	var s Season
+	err := c.get(fmt.Sprintf("/tv/%d/season/%d", tmdbId, season), &s)
+	return s, err
+}
+
+// GetTvShowWithSeasons fetches the show details and then every season listed
+// in them, in the same order.
+func (c *Client) GetTvShowWithSeasons(tmdbId int) (TvShow, []Season, error) {
+	tvShow, err := c.GetTvShow(tmdbId)
+	if err != nil {
+		return TvShow{}, nil, err
+	}
+
+	var seasons []Season
+	for _, summary := range tvShow.Seasons {
+		season, err := c.GetSeason(tmdbId, summary.SeasonNumber)
+		if err != nil {
+			return TvShow{}, nil, err
+		}
+		seasons = append(seasons, season)
+	}
+
+	return tvShow, seasons, nil
+}
+
+func (c *Client) get(path string, target interface{}) error {
+	query := url.Values{}
+	if c.ApiKey != "" {
+		query.Set("api_key", c.ApiKey)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TMDB request %s failed with status %d", path, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,158 @@
+package tmdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/models"
+)
+
+const kNAME_MAX_LENGTH = 80
+
+type TvShow struct {
+	Id       int             `json:"id"`
+	Name     string          `json:"name"`
+	Overview string          `json:"overview"`
+	Status   string          `json:"status"`
+	Seasons  []SeasonSummary `json:"seasons"`
+}
+
+type SeasonSummary struct {
+	SeasonNumber int    `json:"season_number"`
+	Name         string `json:"name"`
+	Overview     string `json:"overview"`
+	AirDate      string `json:"air_date"`
+	EpisodeCount int    `json:"episode_count"`
+	PosterPath   string `json:"poster_path"`
+}
+
+type Season struct {
+	SeasonNumber int       `json:"season_number"`
+	Name         string    `json:"name"`
+	Overview     string    `json:"overview"`
+	AirDate      string    `json:"air_date"`
+	PosterPath   string    `json:"poster_path"`
+	Episodes     []Episode `json:"episodes"`
+}
+
+type Episode struct {
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	Name          string `json:"name"`
+	Overview      string `json:"overview"`
+	AirDate       string `json:"air_date"`
+}
+
+// ToModel converts the TMDB details into a TvShow in the given group.
+func (t TvShow) ToModel(groupType models.GroupType) models.TvShow {
+	return models.TvShow{
+		TmdbId:    t.Id,
+		Name:      clipName(t.Name),
+		Overview:  t.Overview,
+		GroupType: groupType,
+		Status:    ParseStatus(t.Status),
+	}
+}
+
+func (e Episode) ToModel(tmdbId int) models.Episode {
+	return models.Episode{
+		TmdbId:   tmdbId,
+		Season:   e.SeasonNumber,
+		Episode:  e.EpisodeNumber,
+		Name:     clipName(e.Name),
+		Overview: e.Overview,
+		AirDate:  ParseDate(e.AirDate),
+	}
+}
+
+// ToModel converts the season listed in the show details into a Season.
+func (s SeasonSummary) ToModel(tmdbId int) models.Season {
+	return models.Season{
+		TmdbId:       tmdbId,
+		Number:       s.SeasonNumber,
+		Name:         clipName(s.Name),
+		Overview:     s.Overview,
+		AirDate:      ParseDate(s.AirDate),
+		EpisodeCount: s.EpisodeCount,
+		PosterPath:   s.PosterPath,
+	}
+}
+
+// ToSeasons converts and validates the seasons listed in the show details.
+// The specials of season 0 are included after the other seasons.
+func ToSeasons(tmdbId int, summaries []SeasonSummary) ([]models.Season, error) {
+	var seasons, specials []models.Season
+
+	for _, summary := range summaries {
+		season := summary.ToModel(tmdbId)
+		if err := models.ValidSeason(&season); err != nil {
+			return nil, fmt.Errorf("season %d: %s", season.Number, err.Error())
+		}
+		if season.Number == 0 {
+			specials = append(specials, season)
+		} else {
+			seasons = append(seasons, season)
+		}
+	}
+
+	return append(seasons, specials...), nil
+}
+
+// ToEpisodes converts and validates the episodes of every season. The
+// specials of season 0 are included after the other seasons.
+func ToEpisodes(tmdbId int, seasons []Season) ([]models.Episode, error) {
+	var episodes, specials []models.Episode
+
+	for _, season := range seasons {
+		for _, ep := range season.Episodes {
+			episode := ep.ToModel(tmdbId)
+			if err := models.ValidEpisode(&episode); err != nil {
+				return nil, fmt.Errorf("episode %dx%02d: %s", episode.Season, episode.Episode, err.Error())
+			}
+			if episode.Season == 0 {
+				specials = append(specials, episode)
+			} else {
+				episodes = append(episodes, episode)
+			}
+		}
+	}
+
+	return append(episodes, specials...), nil
+}
+
+// ParseStatus maps the TMDB status text to a TvShow status.
+func ParseStatus(status string) models.TvShowStatus {
+	switch strings.ToLower(status) {
+	case "ended":
+		return models.TvShowStatusEnded
+	case "canceled", "cancelled":
+		return models.TvShowStatusCanceled
+	case "in production", "planned":
+		return models.TvShowStatusInProduction
+	case "pilot":
+		return models.TvShowStatusPilot
+	default:
+		return models.TvShowStatusReturning
+	}
+}
+
+// ParseDate converts a TMDB date (YYYY-MM-DD) to a models.Date, or 0 when the
+// date is empty or invalid.
+func ParseDate(date string) models.Date {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0
+	}
+
+	return models.DateOf(t)
+}
+
+func clipName(name string) string {
+	name = strings.TrimSpace(name)
+	runes := []rune(name)
+	if len(runes) > kNAME_MAX_LENGTH {
+		return strings.TrimSpace(string(runes[:kNAME_MAX_LENGTH]))
+	}
+	return name
+}
@@ -0,0 +1,200 @@
+package tvsync
+
+import (
+	"fmt"
+
+	"github.com/feealc/tvshows-backend-go/models"
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+)
+
+type EpisodeChange struct {
+	Episode models.Episode `json:"episode"`
+	Fields  []string       `json:"fields"`
+}
+
+// Report describes what a sync changed for one show.
+type Report struct {
+	TvShowId      int                 `json:"tv_show_id"`
+	TmdbId        int                 `json:"tmdb_id"`
+	Name          string              `json:"name"`
+	StatusChanged bool                `json:"status_changed"`
+	Status        models.TvShowStatus `json:"status"`
+	Added         []models.Episode    `json:"added"`
+	Updated       []EpisodeChange     `json:"updated"`
+	Error         string              `json:"error,omitempty"`
+}
+
+// IsRunning tells if the show can still get new episodes.
+func IsRunning(tvShow models.TvShow) bool {
+	return tvShow.Status == models.TvShowStatusReturning || tvShow.Status == models.TvShowStatusInProduction
+}
+
+// SyncTvShow pulls the show from TMDB, inserts the episodes missing locally and
+// updates name, overview and air date of the existing ones. Watched and
+// WatchedDate are never touched and local episodes missing on TMDB are kept.
+// The seasons are brought up to date the same way, without a report.
+func SyncTvShow(repo repository.Repository, client *tmdb.Client, tvShow models.TvShow) (Report, error) {
+	report := Report{
+		TvShowId: tvShow.Id,
+		TmdbId:   tvShow.TmdbId,
+		Name:     tvShow.Name,
+		Status:   tvShow.Status,
+		Added:    []models.Episode{},
+		Updated:  []EpisodeChange{},
+	}
+
+	remoteTvShow, remoteSeasons, err := client.GetTvShowWithSeasons(tvShow.TmdbId)
+	if err != nil {
+		return report, err
+	}
+
+	seasons, err := tmdb.ToSeasons(tvShow.TmdbId, remoteTvShow.Seasons)
+	if err != nil {
+		return report, err
+	}
+
+	remoteEpisodes, err := tmdb.ToEpisodes(tvShow.TmdbId, remoteSeasons)
+	if err != nil {
+		return report, err
+	}
+
+	err = repo.Transaction(func(tx repository.Repository) error {
+		if err := syncSeasons(tx, tvShow.TmdbId, seasons); err != nil {
+			return err
+		}
+
+		localEpisodes, err := tx.Episodes().FindByTmdbId(tvShow.TmdbId)
+		if err != nil {
+			return err
+		}
+
+		local := make(map[string]models.Episode)
+		for _, episode := range localEpisodes {
+			local[episodeKey(episode)] = episode
+		}
+
+		var toCreate, toSave []models.Episode
+		var changes [][]string
+		for _, remote := range remoteEpisodes {
+			episode, ok := local[episodeKey(remote)]
+			if !ok {
+				toCreate = append(toCreate, remote)
+				continue
+			}
+
+			if fields := mergeEpisode(&episode, remote); len(fields) > 0 {
+				toSave = append(toSave, episode)
+				changes = append(changes, fields)
+			}
+		}
+
+		if err := tx.Episodes().CreateMany(toCreate); err != nil {
+			return err
+		}
+
+		if err := tx.Episodes().SaveMany(toSave); err != nil {
+			return err
+		}
+
+		if status := tmdb.ParseStatus(remoteTvShow.Status); status != tvShow.Status {
+			tvShow.Status = status
+			if err := tx.TvShows().Save(&tvShow); err != nil {
+				return err
+			}
+			report.StatusChanged = true
+			report.Status = status
+		}
+
+		report.Added = append(report.Added, toCreate...)
+		for index, episode := range toSave {
+			report.Updated = append(report.Updated, EpisodeChange{Episode: episode, Fields: changes[index]})
+		}
+		return nil
+	})
+
+	return report, err
+}
+
+// SyncAll syncs every running show. A failure on one show is recorded in its
+// report and does not stop the others.
+func SyncAll(repo repository.Repository, client *tmdb.Client) ([]Report, error) {
+	tvShows, err := repo.TvShows().FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []Report{}
+	for _, tvShow := range tvShows {
+		if !IsRunning(tvShow) {
+			continue
+		}
+
+		report, err := SyncTvShow(repo, client, tvShow)
+		if err != nil {
+			report.Error = err.Error()
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func episodeKey(episode models.Episode) string {
+	return fmt.Sprintf("%dx%d", episode.Season, episode.Episode)
+}
+
+// mergeEpisode copies the TMDB metadata into episode and returns the json
+// names of the fields that changed.
+func mergeEpisode(episode *models.Episode, remote models.Episode) []string {
+	var fields []string
+
+	if episode.Name != remote.Name {
+		episode.Name = remote.Name
+		fields = append(fields, "name")
+	}
+
+	if episode.Overview != remote.Overview {
+		episode.Overview = remote.Overview
+		fields = append(fields, "overview")
+	}
+
+	if episode.AirDate != remote.AirDate {
+		episode.AirDate = remote.AirDate
+		fields = append(fields, "air_date")
+	}
+
+	return fields
+}
+
+// syncSeasons creates the seasons missing locally and saves the ones whose
+// TMDB data changed. Local seasons missing on TMDB are kept.
+func syncSeasons(tx repository.Repository, tmdbId int, remoteSeasons []models.Season) error {
+	localSeasons, err := tx.Seasons().FindByTmdbId(tmdbId)
+	if err != nil {
+		return err
+	}
+
+	local := make(map[int]models.Season)
+	for _, season := range localSeasons {
+		local[season.Number] = season
+	}
+
+	for _, remote := range remoteSeasons {
+		season, ok := local[remote.Number]
+		if !ok {
+			if err := tx.Seasons().Create(&remote); err != nil {
+				return err
+			}
+			continue
+		}
+
+		remote.Id, remote.CreatedAt, remote.UpdatedAt = season.Id, season.CreatedAt, season.UpdatedAt
+		if remote != season {
+			if err := tx.Seasons().Save(&remote); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tvsync
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/feealc/tvshows-backend-go/repository"
+	"github.com/feealc/tvshows-backend-go/tmdb"
+)
+
+// IntervalFromEnv reads SYNC_INTERVAL (e.g. "6h"). Zero means the worker is
+// disabled.
+func IntervalFromEnv() time.Duration {
+	value := os.Getenv("SYNC_INTERVAL")
+	if value == "" {
+		return 0
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("SYNC_INTERVAL [%s] invalid, sync worker disabled", value)
+		return 0
+	}
+
+	return interval
+}
+
+// StartWorker runs SyncAll every interval in background until stop is called.
+func StartWorker(repo repository.Repository, client *tmdb.Client, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				runSync(repo, client)
+			}
+		}
+	}()
+
+	log.Printf("Sync worker started, interval [%s]", interval)
+	return func() {
+		close(done)
+	}
+}
+
+func runSync(repo repository.Repository, client *tmdb.Client) {
+	reports, err := SyncAll(repo, client)
+	if err != nil {
+		log.Printf("Sync failed: %s", err.Error())
+		return
+	}
+
+	for _, report := range reports {
+		if report.Error != "" {
+			log.Printf("Sync %s (TMDB ID %d) failed: %s", report.Name, report.TmdbId, report.Error)
+			continue
+		}
+		log.Printf("Sync %s (TMDB ID %d): %d added, %d updated", report.Name, report.TmdbId, len(report.Added), len(report.Updated))
+	}
+}